@@ -0,0 +1,125 @@
+// Package algo implements graph algorithms over any goneo database.
+//
+// Importing the package registers its algorithms as procedures callable from
+// gcy, e.g.
+//
+//	call algo.pageRank() yield node, score return node, score
+package algo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+)
+
+// Scores maps node ids to the score an algorithm assigned them.
+type Scores map[int]float64
+
+// Ranking returns the node ids ordered by descending score, ties ordered by
+// id.
+func (s Scores) Ranking() []int {
+	ids := make([]int, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if s[ids[i]] != s[ids[j]] {
+			return s[ids[i]] > s[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// Write stores the scores as property of their nodes.
+func (s Scores) Write(db DatabaseService, prop string) error {
+	for id, score := range s {
+		n, err := db.GetNode(id)
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", prop, err)
+		}
+		n.SetProperty(prop, strconv.FormatFloat(score, 'g', -1, 64))
+	}
+	return nil
+}
+
+// adjacency is a snapshot of the relations between the nodes of a database,
+// taken through the sgi.Graph adapter. Nodes are indexed by their id.
+type adjacency struct {
+	out, in [][]int
+}
+
+func newAdjacency(db DatabaseService) *adjacency {
+	g := goneo.NewGraph(db)
+
+	a := &adjacency{out: make([][]int, g.Order()), in: make([][]int, g.Order())}
+	for n := range a.out {
+		a.out[n] = g.Successors(n)
+		a.in[n] = g.Predecessors(n)
+	}
+	return a
+}
+
+func (a *adjacency) order() int { return len(a.out) }
+
+// weights returns the weights of the relations to the successors of every
+// node, read from a property. Relations without a numeric value and all
+// relations if prop is empty weigh 1, negative weights count as 0.
+func (a *adjacency) weights(db DatabaseService, prop string) [][]float64 {
+	weight := PropertyWeight(prop, 1)
+
+	weights := make([][]float64, a.order())
+	for i := range weights {
+		weights[i] = make([]float64, len(a.out[i]))
+
+		var rels []Relation
+		if prop != "" {
+			// The successors are in the order of the outgoing relations
+			node, _ := db.GetNode(i)
+			rels = node.Relations(Outgoing)
+		}
+		for k := range a.out[i] {
+			weights[i][k] = 1
+			if rels != nil {
+				weights[i][k] = math.Max(weight(rels[k]), 0)
+			}
+		}
+	}
+	return weights
+}
+
+// neighbours returns the distinct nodes related to n in the direction.
+func (a *adjacency) neighbours(n int, dir Direction) []int {
+	var related []int
+	switch dir {
+	case Outgoing:
+		related = a.out[n]
+	case Incoming:
+		related = a.in[n]
+	default:
+		related = append(append(related, a.out[n]...), a.in[n]...)
+	}
+
+	seen := make(map[int]bool, len(related))
+	distinct := make([]int, 0, len(related))
+	for _, o := range related {
+		if !seen[o] {
+			seen[o] = true
+			distinct = append(distinct, o)
+		}
+	}
+	return distinct
+}
+
+// scores converts a vector indexed by node id.
+func scores(vector []float64) Scores {
+	s := make(Scores, len(vector))
+	for id, v := range vector {
+		s[id] = v
+	}
+	return s
+}
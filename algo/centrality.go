@@ -0,0 +1,261 @@
+package algo
+
+import (
+	"math"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// PageRankOptions configures PageRank, zero values select the defaults.
+type PageRankOptions struct {
+	// DampingFactor is the probability of following a relation instead of
+	// jumping, 0.85 by default.
+	DampingFactor float64
+	// Iterations is the maximum number of iterations, 20 by default.
+	Iterations int
+	// Tolerance stops iterating once the ranks change less, 1e-7 by default.
+	Tolerance float64
+	// WeightProperty weighs relations by a property, relations without a
+	// numeric value weigh 1. All relations weigh the same if empty.
+	WeightProperty string
+	// Sources personalizes the ranks, jumps only lead to the sources.
+	// Sources not in the database are skipped, jumps lead to any node if
+	// none are left.
+	Sources []Node
+}
+
+// PageRank ranks nodes by the probability of reaching them by randomly
+// following outgoing relations. The ranks sum up to 1.
+func PageRank(db DatabaseService, opts PageRankOptions) Scores {
+	if opts.DampingFactor == 0 {
+		opts.DampingFactor = 0.85
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = 20
+	}
+	if opts.Tolerance == 0 {
+		opts.Tolerance = 1e-7
+	}
+
+	a := newAdjacency(db)
+	n := a.order()
+	if n == 0 {
+		return Scores{}
+	}
+
+	weights := a.weights(db, opts.WeightProperty)
+	totals := make([]float64, n)
+	for i := range weights {
+		for _, w := range weights[i] {
+			totals[i] += w
+		}
+	}
+
+	sources := make([]int, 0, len(opts.Sources))
+	for _, s := range opts.Sources {
+		if s.Id() >= 0 && s.Id() < n {
+			sources = append(sources, s.Id())
+		}
+	}
+
+	jump := make([]float64, n)
+	if len(sources) == 0 {
+		for i := range jump {
+			jump[i] = 1 / float64(n)
+		}
+	} else {
+		for _, s := range sources {
+			jump[s] += 1 / float64(len(sources))
+		}
+	}
+
+	rank := append([]float64(nil), jump...)
+	for it := 0; it < opts.Iterations; it++ {
+		next := make([]float64, n)
+
+		// Ranks of nodes without relations to follow are spread by jumping
+		dangling := 0.0
+		for i := range rank {
+			if totals[i] == 0 {
+				dangling += rank[i]
+				continue
+			}
+			for k, o := range a.out[i] {
+				next[o] += opts.DampingFactor * rank[i] * weights[i][k] / totals[i]
+			}
+		}
+		for i := range next {
+			next[i] += (1 - opts.DampingFactor + opts.DampingFactor*dangling) * jump[i]
+		}
+
+		change := 0.0
+		for i := range next {
+			change += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		if change < opts.Tolerance {
+			break
+		}
+	}
+
+	return scores(rank)
+}
+
+// DegreeCentrality scores nodes by the number of their relations in the
+// direction.
+func DegreeCentrality(db DatabaseService, dir Direction) Scores {
+	a := newAdjacency(db)
+
+	degree := make([]float64, a.order())
+	for i := range degree {
+		if dir != Incoming {
+			degree[i] += float64(len(a.out[i]))
+		}
+		if dir != Outgoing {
+			degree[i] += float64(len(a.in[i]))
+		}
+	}
+	return scores(degree)
+}
+
+// ClosenessCentrality scores nodes by their distance to the nodes reachable
+// following relations in the direction. Scores are scaled by the share of
+// nodes reachable, following Wasserman and Faust, so nodes of small
+// components do not score high.
+func ClosenessCentrality(db DatabaseService, dir Direction) Scores {
+	a := newAdjacency(db)
+	n := a.order()
+
+	closeness := make([]float64, n)
+	for i := range closeness {
+		dist := a.distances(i, dir)
+
+		reached, sum := 0, 0
+		for _, d := range dist {
+			if d > 0 {
+				reached, sum = reached+1, sum+d
+			}
+		}
+		if sum > 0 {
+			closeness[i] = float64(reached) / float64(sum) * float64(reached) / float64(n-1)
+		}
+	}
+	return scores(closeness)
+}
+
+// distances returns the number of relations from the start to every node,
+// -1 for unreachable nodes.
+func (a *adjacency) distances(start int, dir Direction) []int {
+	dist := make([]int, a.order())
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[start] = 0
+
+	for queue := []int{start}; len(queue) > 0; queue = queue[1:] {
+		for _, o := range a.neighbours(queue[0], dir) {
+			if dist[o] < 0 {
+				dist[o] = dist[queue[0]] + 1
+				queue = append(queue, o)
+			}
+		}
+	}
+	return dist
+}
+
+// BetweennessCentrality scores nodes by the number of shortest paths between
+// other nodes passing them, using Brandes' algorithm. Following relations in
+// both directions, paths are counted once instead of once per direction.
+func BetweennessCentrality(db DatabaseService, dir Direction) Scores {
+	a := newAdjacency(db)
+	n := a.order()
+
+	betweenness := make([]float64, n)
+	for s := 0; s < n; s++ {
+		stack := make([]int, 0, n)
+		preds := make([][]int, n)
+		paths := make([]float64, n)
+		dist := make([]int, n)
+		for i := range dist {
+			dist[i] = -1
+		}
+		paths[s], dist[s] = 1, 0
+
+		for queue := []int{s}; len(queue) > 0; queue = queue[1:] {
+			v := queue[0]
+			stack = append(stack, v)
+			for _, w := range a.neighbours(v, dir) {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					paths[w] += paths[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+
+		dependency := make([]float64, n)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				dependency[v] += paths[v] / paths[w] * (1 + dependency[w])
+			}
+			if w != s {
+				betweenness[w] += dependency[w]
+			}
+		}
+	}
+
+	if dir == Both {
+		for i := range betweenness {
+			betweenness[i] /= 2
+		}
+	}
+	return scores(betweenness)
+}
+
+// EigenvectorCentrality scores nodes by the scores of the nodes related to
+// them, following relations in the direction towards the scored node. The
+// scores are normalized to a unit vector.
+func EigenvectorCentrality(db DatabaseService, dir Direction, iterations int) Scores {
+	a := newAdjacency(db)
+	n := a.order()
+	if n == 0 {
+		return Scores{}
+	}
+
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 1 / math.Sqrt(float64(n))
+	}
+
+	for it := 0; it < iterations; it++ {
+		// Iterating on A+I converges on bipartite graphs as well
+		next := append([]float64(nil), x...)
+		for i := range next {
+			for _, o := range a.neighbours(i, dir.Reverse()) {
+				next[i] += x[o]
+			}
+		}
+
+		norm := 0.0
+		for _, v := range next {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+
+		change := 0.0
+		for i := range next {
+			next[i] /= norm
+			change += math.Abs(next[i] - x[i])
+		}
+		x = next
+		if change < 1e-9 {
+			break
+		}
+	}
+
+	return scores(x)
+}
@@ -0,0 +1,198 @@
+package algo
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/db/mem"
+)
+
+// newGraph creates a database with nodes related as given by pairs of
+// indices.
+func newGraph(nodes int, rels ...[2]int) (DatabaseService, []Node) {
+	db, _ := mem.NewDb("test", nil)
+
+	ns := make([]Node, nodes)
+	for i := range ns {
+		ns[i] = db.NewNode()
+	}
+	for _, rel := range rels {
+		ns[rel[0]].RelateTo(ns[rel[1]], "LINKS")
+	}
+	return db, ns
+}
+
+func TestPageRank(t *testing.T) {
+	db, _ := newGraph(5, [2]int{1, 0}, [2]int{2, 0}, [2]int{3, 0}, [2]int{4, 0}, [2]int{0, 1})
+
+	ranks := PageRank(db, PageRankOptions{})
+	if top := ranks.Ranking()[0]; top != 0 {
+		t.Error("Should rank the center highest, got ", top)
+	}
+	if ranks[1] <= ranks[2] {
+		t.Errorf("Should rank the node linked by the center higher, got %v", ranks)
+	}
+
+	sum := 0.0
+	for _, r := range ranks {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Error("Ranks should sum up to 1, got ", sum)
+	}
+}
+
+func TestWeightedPageRank(t *testing.T) {
+	db, ns := newGraph(3)
+	ns[0].RelateTo(ns[1], "LINKS").SetProperty("weight", 9)
+	ns[0].RelateTo(ns[2], "LINKS").SetProperty("weight", 1)
+
+	ranks := PageRank(db, PageRankOptions{WeightProperty: "weight"})
+	if ranks[1] <= ranks[2] {
+		t.Errorf("Should prefer heavier relations, got %v", ranks)
+	}
+
+	ranks = PageRank(db, PageRankOptions{})
+	if math.Abs(ranks[1]-ranks[2]) > 1e-9 {
+		t.Errorf("Should weigh relations the same, got %v", ranks)
+	}
+}
+
+func TestPersonalizedPageRank(t *testing.T) {
+	db, ns := newGraph(4, [2]int{0, 1}, [2]int{1, 0}, [2]int{2, 3}, [2]int{3, 2})
+
+	ranks := PageRank(db, PageRankOptions{Sources: []Node{ns[2]}})
+	if ranks[0] != 0 || ranks[1] != 0 {
+		t.Errorf("Should not reach nodes unrelated to the source, got %v", ranks)
+	}
+	if ranks[2] <= ranks[3] {
+		t.Errorf("Should rank the source highest, got %v", ranks)
+	}
+
+	// a node of a larger graph is no source in this one
+	_, others := newGraph(6)
+	if skipped := PageRank(db, PageRankOptions{Sources: []Node{ns[2], others[5]}}); !reflect.DeepEqual(skipped, ranks) {
+		t.Errorf("Should skip sources not in the graph, got %v", skipped)
+	}
+	if skipped := PageRank(db, PageRankOptions{Sources: []Node{others[5]}}); !reflect.DeepEqual(skipped, PageRank(db, PageRankOptions{})) {
+		t.Errorf("Should jump to any node without sources in the graph, got %v", skipped)
+	}
+}
+
+func TestDegreeCentrality(t *testing.T) {
+	db, _ := newGraph(3, [2]int{0, 1}, [2]int{0, 2}, [2]int{1, 2})
+
+	for dir, expected := range map[Direction][]float64{
+		Outgoing: {2, 1, 0},
+		Incoming: {0, 1, 2},
+		Both:     {2, 2, 2},
+	} {
+		degrees := DegreeCentrality(db, dir)
+		for id, d := range expected {
+			if degrees[id] != d {
+				t.Errorf("%s: expected degree %v of %d, got %v", dir, d, id, degrees[id])
+			}
+		}
+	}
+}
+
+func TestClosenessCentrality(t *testing.T) {
+	// a line and a separate node
+	db, _ := newGraph(4, [2]int{0, 1}, [2]int{1, 2})
+
+	closeness := ClosenessCentrality(db, Both)
+	if math.Abs(closeness[1]-2.0/3) > 1e-9 {
+		t.Error("Middle node should be closest, got ", closeness[1])
+	}
+	if math.Abs(closeness[0]-4.0/9) > 1e-9 || closeness[0] != closeness[2] {
+		t.Errorf("Ends should be equally close, got %v", closeness)
+	}
+	if closeness[3] != 0 {
+		t.Error("Unrelated node should not be close, got ", closeness[3])
+	}
+
+	closeness = ClosenessCentrality(db, Outgoing)
+	if closeness[2] != 0 || closeness[0] <= closeness[1] {
+		t.Errorf("Should only follow outgoing relations, got %v", closeness)
+	}
+}
+
+func TestBetweennessCentrality(t *testing.T) {
+	// two triangles joined by node 2
+	db, _ := newGraph(5, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 2})
+
+	betweenness := BetweennessCentrality(db, Both)
+	if betweenness[2] != 4 {
+		t.Error("Joining node should lie on 4 shortest paths, got ", betweenness[2])
+	}
+	if betweenness[0] != 0 {
+		t.Error("Outer nodes should lie on no shortest paths, got ", betweenness[0])
+	}
+
+	betweenness = BetweennessCentrality(db, Outgoing)
+	if betweenness[0] != 3 || betweenness[2] != 10 {
+		t.Errorf("Should follow relations in their direction, got %v", betweenness)
+	}
+}
+
+func TestEigenvectorCentrality(t *testing.T) {
+	db, _ := newGraph(5, [2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}, [2]int{3, 4})
+
+	centrality := EigenvectorCentrality(db, Both, 100)
+	if top := centrality.Ranking()[0]; top != 0 {
+		t.Errorf("Should rank the hub highest, got %v", centrality)
+	}
+	if centrality[3] <= centrality[1] {
+		t.Errorf("Should rank nodes with more neighbours higher, got %v", centrality)
+	}
+
+	norm := 0.0
+	for _, c := range centrality {
+		norm += c * c
+	}
+	if math.Abs(norm-1) > 1e-6 {
+		t.Error("Should normalize the scores, got ", norm)
+	}
+}
+
+func TestWriteScores(t *testing.T) {
+	db, ns := newGraph(2, [2]int{0, 1})
+
+	if err := DegreeCentrality(db, Outgoing).Write(db, "degree"); err != nil {
+		t.Fatal(err)
+	}
+	if ns[0].Properties()["degree"] != "1" || ns[1].Properties()["degree"] != "0" {
+		t.Errorf("Should write the scores, got %v and %v", ns[0], ns[1])
+	}
+}
+
+func TestCallPageRank(t *testing.T) {
+	db, ns := newGraph(3, [2]int{1, 0}, [2]int{2, 0})
+
+	table, err := goneo.Evaluate(db, `call algo.pageRank(0.85, 20, "", "rank") yield node, score as s return node, s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 3 {
+		t.Fatal("Should rank all nodes, got ", table.Len())
+	}
+	if table.Get(0, "node") != ns[0] || table.Get(0, "s").(float64) <= table.Get(1, "s").(float64) {
+		t.Errorf("Should yield the highest rank first, got %v", table)
+	}
+	if !ns[0].HasProperty("rank") {
+		t.Error("Should write the ranks, got ", ns[0])
+	}
+
+	table, err = goneo.Evaluate(db, `start n=node(1) call algo.personalizedPageRank(n) yield node, score return node, score`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < table.Len(); i++ {
+		if table.Get(i, "node") == ns[2] && table.Get(i, "score") != 0.0 {
+			t.Error("Should not reach nodes unrelated to the source, got ", table.Get(i, "score"))
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package algo
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// Partition maps node ids to the component or community they belong to. A
+// community is identified by the smallest id of its members.
+type Partition map[int]int
+
+// Groups returns the member ids of every community, ordered by their
+// smallest member.
+func (p Partition) Groups() [][]int {
+	members := make(map[int][]int)
+	for id, c := range p {
+		members[c] = append(members[c], id)
+	}
+
+	groups := make([][]int, 0, len(members))
+	for _, m := range members {
+		sort.Ints(m)
+		groups = append(groups, m)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// Write stores the communities as property of their nodes.
+func (p Partition) Write(db DatabaseService, prop string) error {
+	for id, c := range p {
+		n, err := db.GetNode(id)
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", prop, err)
+		}
+		n.SetProperty(prop, strconv.Itoa(c))
+	}
+	return nil
+}
+
+// partition identifies the communities of a labelling by their smallest
+// member.
+func partition(labels []int) Partition {
+	smallest := make(map[int]int)
+	for id, l := range labels {
+		if s, ok := smallest[l]; !ok || id < s {
+			smallest[l] = id
+		}
+	}
+
+	p := make(Partition, len(labels))
+	for id, l := range labels {
+		p[id] = smallest[l]
+	}
+	return p
+}
+
+// WeaklyConnectedComponents partitions the nodes into sets connected by
+// relations in any direction.
+func WeaklyConnectedComponents(db DatabaseService) Partition {
+	a := newAdjacency(db)
+
+	parent := make([]int, a.order())
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i := range a.out {
+		for _, o := range a.out[i] {
+			parent[find(o)] = find(i)
+		}
+	}
+
+	labels := make([]int, a.order())
+	for i := range labels {
+		labels[i] = find(i)
+	}
+	return partition(labels)
+}
+
+// StronglyConnectedComponents partitions the nodes into sets in which every
+// node can reach every other following the direction of relations, using
+// Tarjan's algorithm.
+func StronglyConnectedComponents(db DatabaseService) Partition {
+	return newAdjacency(db).stronglyConnected()
+}
+
+func (a *adjacency) stronglyConnected() Partition {
+	n := a.order()
+
+	index, low := make([]int, n), make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+	onStack := make([]bool, n)
+	stack := make([]int, 0)
+	labels := make([]int, n)
+	counter := 0
+
+	visit := func(v int) {
+		index[v], low[v] = counter, counter
+		counter += 1
+		stack = append(stack, v)
+		onStack[v] = true
+	}
+
+	// Iterating instead of recursing keeps long chains off the call stack
+	type frame struct{ node, next int }
+	for root := 0; root < n; root++ {
+		if index[root] >= 0 {
+			continue
+		}
+
+		visit(root)
+		calls := []frame{{root, 0}}
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			v := f.node
+
+			if f.next < len(a.out[v]) {
+				w := a.out[v][f.next]
+				f.next += 1
+
+				if index[w] < 0 {
+					visit(w)
+					calls = append(calls, frame{w, 0})
+				} else if onStack[w] {
+					low[v] = min(low[v], index[w])
+				}
+				continue
+			}
+
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				p := calls[len(calls)-1].node
+				low[p] = min(low[p], low[v])
+			}
+
+			if low[v] == index[v] {
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					labels[w] = v
+					if w == v {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return partition(labels)
+}
+
+// LabelPropagation detects communities by letting every node adopt the label
+// most common among its neighbours until labels are stable, or for at most
+// the given number of iterations. Nodes are visited in random order and ties
+// are broken randomly, results are reproducible for the same seed.
+func LabelPropagation(db DatabaseService, iterations int, seed int64) Partition {
+	a := newAdjacency(db)
+	rnd := rand.New(rand.NewSource(seed))
+
+	labels := make([]int, a.order())
+	for i := range labels {
+		labels[i] = i
+	}
+
+	for it := 0; it < iterations; it++ {
+		changed := false
+		for _, i := range rnd.Perm(len(labels)) {
+			counts := make(map[int]int)
+			for _, o := range a.neighbours(i, Both) {
+				if o != i {
+					counts[labels[o]] += 1
+				}
+			}
+
+			most := 0
+			for _, c := range counts {
+				most = max(most, c)
+			}
+			// Keep the label unless another one is more common
+			if counts[labels[i]] == most {
+				continue
+			}
+
+			candidates := make([]int, 0)
+			for l, c := range counts {
+				if c == most {
+					candidates = append(candidates, l)
+				}
+			}
+			sort.Ints(candidates)
+
+			labels[i], changed = candidates[rnd.Intn(len(candidates))], true
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return partition(labels)
+}
+
+// TriangleCount counts the triangles every node is part of, ignoring the
+// direction of relations, and the triangles of the whole graph.
+func TriangleCount(db DatabaseService) (Scores, int) {
+	a := newAdjacency(db)
+	neighbours := a.undirected()
+
+	triangles := make([]float64, a.order())
+	total := 0
+	for u := range neighbours {
+		for v := range neighbours[u] {
+			if v <= u {
+				continue
+			}
+			for w := range neighbours[v] {
+				if w > v && neighbours[u][w] {
+					triangles[u] += 1
+					triangles[v] += 1
+					triangles[w] += 1
+					total += 1
+				}
+			}
+		}
+	}
+	return scores(triangles), total
+}
+
+// ClusteringCoefficient scores nodes by how many of their neighbours are
+// related to each other, ignoring the direction of relations.
+func ClusteringCoefficient(db DatabaseService) Scores {
+	triangles, _ := TriangleCount(db)
+	neighbours := newAdjacency(db).undirected()
+
+	coefficients := make(Scores, len(neighbours))
+	for u := range neighbours {
+		coefficients[u] = 0
+		if d := float64(len(neighbours[u])); d > 1 {
+			coefficients[u] = 2 * triangles[u] / (d * (d - 1))
+		}
+	}
+	return coefficients
+}
+
+// undirected returns the distinct neighbours of every node, without the node
+// itself.
+func (a *adjacency) undirected() []map[int]bool {
+	neighbours := make([]map[int]bool, a.order())
+	for u := range neighbours {
+		neighbours[u] = make(map[int]bool)
+		for _, v := range a.neighbours(u, Both) {
+			if v != u {
+				neighbours[u][v] = true
+			}
+		}
+	}
+	return neighbours
+}
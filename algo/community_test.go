@@ -0,0 +1,131 @@
+package algo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BuJo/goneo"
+)
+
+// cliques relates nodes 0-3 and 4-7 with each other, joined by a single
+// relation between 3 and 4.
+var cliques = [][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3},
+	{4, 5}, {4, 6}, {4, 7}, {5, 6}, {5, 7}, {6, 7},
+	{3, 4},
+}
+
+func TestWeaklyConnectedComponents(t *testing.T) {
+	db, _ := newGraph(6, [2]int{0, 1}, [2]int{2, 1}, [2]int{3, 4}, [2]int{4, 3})
+
+	groups := WeaklyConnectedComponents(db).Groups()
+	if expected := [][]int{{0, 1, 2}, {3, 4}, {5}}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected components %v, got %v", expected, groups)
+	}
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	db, _ := newGraph(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 3})
+
+	components := StronglyConnectedComponents(db)
+	if expected := [][]int{{0, 1, 2}, {3, 4}, {5}}; !reflect.DeepEqual(components.Groups(), expected) {
+		t.Errorf("Expected components %v, got %v", expected, components.Groups())
+	}
+	if components[4] != 3 {
+		t.Error("Components should be identified by their smallest member, got ", components[4])
+	}
+}
+
+func TestLabelPropagation(t *testing.T) {
+	db, _ := newGraph(8, cliques...)
+
+	groups := LabelPropagation(db, 10, 1).Groups()
+	if expected := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected communities %v, got %v", expected, groups)
+	}
+
+	for seed := int64(0); seed < 10; seed++ {
+		if a, b := LabelPropagation(db, 10, seed), LabelPropagation(db, 10, seed); !reflect.DeepEqual(a, b) {
+			t.Errorf("Seed %d should reproduce communities, got %v and %v", seed, a.Groups(), b.Groups())
+		}
+	}
+}
+
+func TestLouvain(t *testing.T) {
+	db, _ := newGraph(8, cliques...)
+
+	communities := Louvain(db, "")
+	if expected := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}}; !reflect.DeepEqual(communities.Groups(), expected) {
+		t.Errorf("Expected communities %v, got %v", expected, communities.Groups())
+	}
+
+	if q := Modularity(db, communities, ""); q < 0.4 {
+		t.Error("Communities should be modular, got ", q)
+	}
+	if q := Modularity(db, WeaklyConnectedComponents(db), ""); q != 0 {
+		t.Error("A single community should not be modular, got ", q)
+	}
+}
+
+func TestWeightedLouvain(t *testing.T) {
+	// a ring whose heavy relations pair up nodes
+	db, ns := newGraph(6)
+	for i := range ns {
+		weight := 1
+		if i%2 == 0 {
+			weight = 10
+		}
+		ns[i].RelateTo(ns[(i+1)%len(ns)], "LINKS").SetProperty("weight", weight)
+	}
+
+	groups := Louvain(db, "weight").Groups()
+	if expected := [][]int{{0, 1}, {2, 3}, {4, 5}}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected communities %v, got %v", expected, groups)
+	}
+}
+
+func TestTriangleCount(t *testing.T) {
+	db, _ := newGraph(9, append(cliques, [2]int{7, 8})...)
+
+	triangles, total := TriangleCount(db)
+	if total != 8 {
+		t.Error("Expected 8 triangles, got ", total)
+	}
+	if triangles[0] != 3 || triangles[8] != 0 {
+		t.Errorf("Expected 3 triangles on each clique node, got %v", triangles)
+	}
+
+	coefficients := ClusteringCoefficient(db)
+	if coefficients[0] != 1 || coefficients[8] != 0 {
+		t.Errorf("Expected clique nodes to be clustered, got %v", coefficients)
+	}
+	if coefficients[3] != 0.5 {
+		t.Error("Expected half of the neighbours of a joining node to be related, got ", coefficients[3])
+	}
+}
+
+func TestCallCommunities(t *testing.T) {
+	db, ns := newGraph(8, cliques...)
+
+	table, err := goneo.Evaluate(db, `call algo.louvain("", "community") yield node, community as c return node, c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 8 {
+		t.Fatal("Should yield every node, got ", table.Len())
+	}
+	if table.Get(5, "node") != ns[5] || table.Get(5, "c") != 4 {
+		t.Errorf("Should yield the community of every node, got %v", table)
+	}
+	if ns[7].Properties()["community"] != "4" {
+		t.Error("Should write the communities, got ", ns[7])
+	}
+
+	table, err = goneo.Evaluate(db, `call algo.wcc() yield community return count(community) as c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Get(0, "c") != 8 {
+		t.Error("Should stream all nodes, got ", table.Get(0, "c"))
+	}
+}
@@ -0,0 +1,210 @@
+package algo
+
+import (
+	"container/heap"
+	"fmt"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// CycleError is returned when relations expected to be acyclic form cycles.
+type CycleError struct {
+	// Cycles found, one per set of nodes reaching each other.
+	Cycles []Path
+}
+
+func (e *CycleError) Error() string {
+	if len(e.Cycles) == 0 {
+		return "relations form a cycle"
+	}
+	return fmt.Sprintf("relations form %d cycle(s), e.g. %s", len(e.Cycles), e.Cycles[0])
+}
+
+// typedRelations returns the outgoing relations of the type of every node,
+// of all types if relType is empty.
+func typedRelations(db DatabaseService, relType string) [][]Relation {
+	opts := PathOptions{}
+	if relType != "" {
+		opts.Types = []string{relType}
+	}
+
+	nodes := db.GetAllNodes()
+	rels := make([][]Relation, len(nodes))
+	for _, n := range nodes {
+		for _, rel := range n.Relations(Outgoing) {
+			if opts.Follows(rel) {
+				rels[n.Id()] = append(rels[n.Id()], rel)
+			}
+		}
+	}
+	return rels
+}
+
+// TopologicalSort orders the nodes so every relation of the type leads from
+// an earlier to a later node, using Kahn's algorithm. Of the nodes which may
+// come next the one with the smallest id is chosen, so the order is
+// deterministic. Relations of all types are ordered if relType is empty.
+// If the relations form cycles a *CycleError is returned.
+func TopologicalSort(db DatabaseService, relType string) ([]Node, error) {
+	rels := typedRelations(db, relType)
+	nodes := db.GetAllNodes()
+
+	incoming := make([]int, len(rels))
+	for _, out := range rels {
+		for _, rel := range out {
+			incoming[rel.End().Id()] += 1
+		}
+	}
+
+	ready := &idQueue{}
+	for id, in := range incoming {
+		if in == 0 {
+			heap.Push(ready, id)
+		}
+	}
+
+	sorted := make([]Node, 0, len(nodes))
+	for ready.Len() > 0 {
+		id := heap.Pop(ready).(int)
+		sorted = append(sorted, nodes[id])
+
+		for _, rel := range rels[id] {
+			end := rel.End().Id()
+			if incoming[end] -= 1; incoming[end] == 0 {
+				heap.Push(ready, end)
+			}
+		}
+	}
+
+	if len(sorted) < len(nodes) {
+		return nil, &CycleError{FindCycles(db, relType)}
+	}
+	return sorted, nil
+}
+
+// FindCycles returns a cycle for every set of nodes reaching each other by
+// relations of the type, and for every relation of the type leading back to
+// its start. Each cycle is a shortest one starting and ending at the
+// smallest node of its set.
+func FindCycles(db DatabaseService, relType string) []Path {
+	rels := typedRelations(db, relType)
+	nodes := db.GetAllNodes()
+	components := stronglyConnected(rels)
+
+	cycles := make([]Path, 0)
+	for _, members := range components {
+		start := members[0]
+		within := make(map[int]bool, len(members))
+		for _, m := range members {
+			within[m] = true
+		}
+
+		// Breadth first back to the start, staying within the component
+		prev := make(map[int]Relation)
+		var closing Relation
+		for queue := []int{start}; len(queue) > 0 && closing == nil; queue = queue[1:] {
+			for _, rel := range rels[queue[0]] {
+				end := rel.End().Id()
+				if end == start {
+					closing = rel
+					break
+				}
+				if _, seen := prev[end]; !seen && within[end] {
+					prev[end] = rel
+					queue = append(queue, end)
+				}
+			}
+		}
+		if closing == nil {
+			continue
+		}
+
+		route := []Relation{closing}
+		for n := closing.Start().Id(); n != start; n = prev[n].Start().Id() {
+			route = append(route, prev[n])
+		}
+		builder := NewPathBuilder(nodes[start])
+		for i := len(route) - 1; i >= 0; i-- {
+			builder = builder.Append(route[i])
+		}
+		cycles = append(cycles, builder.Build())
+	}
+	return cycles
+}
+
+// stronglyConnected returns the members of every strongly connected
+// component, ordered by their smallest member.
+func stronglyConnected(rels [][]Relation) [][]int {
+	a := &adjacency{out: make([][]int, len(rels)), in: make([][]int, len(rels))}
+	for id, out := range rels {
+		for _, rel := range out {
+			a.out[id] = append(a.out[id], rel.End().Id())
+		}
+	}
+	return a.stronglyConnected().Groups()
+}
+
+// TransitiveReduction returns the fewest relations of the type connecting
+// the same nodes as all of them, leaving out relations which are implied by
+// longer paths and parallel relations. Relations are ordered by their start
+// node. If the relations form cycles a *CycleError is returned.
+func TransitiveReduction(db DatabaseService, relType string) ([]Relation, error) {
+	order, err := TopologicalSort(db, relType)
+	if err != nil {
+		return nil, err
+	}
+	rels := typedRelations(db, relType)
+
+	// Nodes reachable from every node, collected from the last node back
+	reachable := make([]map[int]bool, len(rels))
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i].Id()
+		reachable[id] = make(map[int]bool)
+		for _, rel := range rels[id] {
+			end := rel.End().Id()
+			reachable[id][end] = true
+			for r := range reachable[end] {
+				reachable[id][r] = true
+			}
+		}
+	}
+
+	reduction := make([]Relation, 0)
+	for id, out := range rels {
+		kept := make(map[int]bool)
+		for _, rel := range out {
+			end := rel.End().Id()
+			if kept[end] || reachedOtherwise(rels[id], reachable, end) {
+				continue
+			}
+			kept[end] = true
+			reduction = append(reduction, rel)
+		}
+	}
+	return reduction, nil
+}
+
+// reachedOtherwise reports whether end is reachable through another of the
+// relations.
+func reachedOtherwise(out []Relation, reachable []map[int]bool, end int) bool {
+	for _, rel := range out {
+		if other := rel.End().Id(); other != end && reachable[other][end] {
+			return true
+		}
+	}
+	return false
+}
+
+// idQueue is a priority queue of node ids, smallest first.
+type idQueue []int
+
+func (q idQueue) Len() int            { return len(q) }
+func (q idQueue) Less(i, j int) bool  { return q[i] < q[j] }
+func (q idQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *idQueue) Push(x interface{}) { *q = append(*q, x.(int)) }
+func (q *idQueue) Pop() interface{} {
+	old := *q
+	id := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return id
+}
@@ -0,0 +1,122 @@
+package algo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+)
+
+// newDependencies creates nodes depending on each other by DEPENDS_ON, and
+// unrelated cyclic LINKS.
+func newDependencies() (DatabaseService, []Node) {
+	db, ns := newGraph(5)
+	for _, dep := range [][2]int{{3, 1}, {1, 0}, {3, 0}, {2, 0}, {4, 2}, {4, 0}} {
+		ns[dep[0]].RelateTo(ns[dep[1]], "DEPENDS_ON")
+	}
+	ns[0].RelateTo(ns[4], "LINKS")
+	return db, ns
+}
+
+func ids(nodes []Node) []int {
+	ids := make([]int, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.Id())
+	}
+	return ids
+}
+
+func TestTopologicalSort(t *testing.T) {
+	db, _ := newDependencies()
+
+	sorted, err := TopologicalSort(db, "DEPENDS_ON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := ids(sorted), []int{3, 1, 4, 2, 0}; len(got) != len(expected) {
+		t.Fatalf("Expected order %v, got %v", expected, got)
+	} else {
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("Expected order %v, got %v", expected, got)
+			}
+		}
+	}
+
+	_, err = TopologicalSort(db, "")
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) || len(cycleErr.Cycles) != 1 {
+		t.Fatal("Should report the cycle, got ", err)
+	}
+	if cycle := cycleErr.Cycles[0]; len(cycle.Relations()) != 2 || cycle.Nodes()[0].Id() != 0 || cycle.Nodes()[2].Id() != 0 {
+		t.Error("Should find the shortest cycle, got ", cycle)
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	db, ns := newGraph(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}, [2]int{4, 4})
+
+	if cycles := FindCycles(db, "LINKS"); len(cycles) != 2 {
+		t.Fatal("Should find the cycle and the loop, got ", cycles)
+	} else {
+		if len(cycles[0].Relations()) != 3 {
+			t.Error("Should find the triangle, got ", cycles[0])
+		}
+		if cycles[1].Nodes()[0] != ns[4] || len(cycles[1].Relations()) != 1 {
+			t.Error("Should find the loop, got ", cycles[1])
+		}
+	}
+
+	if cycles := FindCycles(db, "DEPENDS_ON"); len(cycles) != 0 {
+		t.Error("Should only follow the type, got ", cycles)
+	}
+}
+
+func TestTransitiveReduction(t *testing.T) {
+	db, ns := newDependencies()
+	ns[3].RelateTo(ns[1], "DEPENDS_ON")
+
+	reduction, err := TransitiveReduction(db, "DEPENDS_ON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reduction) != 4 {
+		t.Fatal("Should leave out implied and parallel relations, got ", reduction)
+	}
+	for _, rel := range reduction {
+		if rel.Start() == ns[3] && rel.End() == ns[0] || rel.Start() == ns[4] && rel.End() == ns[0] {
+			t.Error("Should leave out implied relation, got ", rel)
+		}
+	}
+
+	if _, err := TransitiveReduction(db, ""); err == nil {
+		t.Error("Should not reduce cycles")
+	}
+}
+
+func TestCallTopologicalSort(t *testing.T) {
+	db, ns := newDependencies()
+
+	table, err := goneo.Evaluate(db, `call algo.topologicalSort("DEPENDS_ON") yield node, position return node, position`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 5 || table.Get(0, "node") != ns[3] || table.Get(4, "position") != 4 {
+		t.Errorf("Should yield the nodes in order, got %v", table)
+	}
+
+	_, err = goneo.Evaluate(db, `call algo.topologicalSort() yield node return node`)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Error("Should fail on cycles, got ", err)
+	}
+
+	table, err = goneo.Evaluate(db, `call algo.findCycles("LINKS") yield path return path`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 0 {
+		t.Errorf("Should not find cycles of a single type, got %v", table)
+	}
+}
@@ -0,0 +1,204 @@
+package algo
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// ErrNegativeCapacity is returned by flow algorithms when a relation has a
+// negative capacity.
+var ErrNegativeCapacity = errors.New("negative relation capacity")
+
+// FlowOptions configures flow algorithms, zero values select the defaults.
+type FlowOptions struct {
+	// CapacityProperty holds the capacity of a relation, "capacity" by
+	// default.
+	CapacityProperty string
+	// DefaultCapacity of relations without a numeric capacity.
+	DefaultCapacity float64
+	// Types of relations to follow, all types if empty.
+	Types []string
+}
+
+// Flow is a maximum flow from a source to a sink following the direction of
+// relations.
+type Flow struct {
+	// Value of the flow, the summed flow leaving the source.
+	Value float64
+	// Relations carrying flow, ordered by id.
+	Relations []RelationFlow
+	// Cut are the relations of a minimum cut, saturated relations whose
+	// removal separates the sink from the source. Their capacities sum up to
+	// the value of the flow.
+	Cut []Relation
+	// SourceSide are the nodes still reachable from the source on the cut.
+	SourceSide []Node
+}
+
+// RelationFlow is the flow through a relation.
+type RelationFlow struct {
+	Relation Relation
+	Flow     float64
+}
+
+// flowEdge is an edge of the residual graph, edges come in pairs of an edge
+// and its reverse.
+type flowEdge struct {
+	to       int
+	capacity float64
+	flow     float64
+	rel      Relation
+}
+
+// flowNetwork is the residual graph of the nodes reachable from the source.
+type flowNetwork struct {
+	nodes   []Node
+	indices map[int]int
+	edges   []flowEdge
+	out     [][]int
+
+	level []int
+	next  []int
+}
+
+// MaxFlow computes a maximum flow from source to sink and a minimum cut
+// using Dinic's algorithm.
+func MaxFlow(source, sink Node, opts FlowOptions) (*Flow, error) {
+	if opts.CapacityProperty == "" {
+		opts.CapacityProperty = "capacity"
+	}
+
+	net, err := newFlowNetwork(source, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	flow := &Flow{}
+	t, ok := net.indices[sink.Id()]
+	if ok && source.Id() != sink.Id() {
+		for net.levels(0, t) {
+			net.next = make([]int, len(net.nodes))
+			for pushed := net.push(0, t, math.Inf(1)); pushed > 0; pushed = net.push(0, t, math.Inf(1)) {
+				flow.Value += pushed
+			}
+		}
+	}
+
+	for i := 0; i < len(net.edges); i += 2 {
+		if e := net.edges[i]; e.flow > 0 {
+			flow.Relations = append(flow.Relations, RelationFlow{e.rel, e.flow})
+		}
+	}
+	sort.Slice(flow.Relations, func(i, j int) bool { return flow.Relations[i].Relation.Id() < flow.Relations[j].Relation.Id() })
+
+	// The cut separates the nodes reachable in the residual graph
+	net.levels(0, -1)
+	for i, n := range net.nodes {
+		if net.level[i] >= 0 {
+			flow.SourceSide = append(flow.SourceSide, n)
+		}
+	}
+	for i := 0; i < len(net.edges); i += 2 {
+		from := net.edges[i+1].to
+		if e := net.edges[i]; net.level[from] >= 0 && net.level[e.to] < 0 {
+			flow.Cut = append(flow.Cut, e.rel)
+		}
+	}
+	sort.Slice(flow.Cut, func(i, j int) bool { return flow.Cut[i].Id() < flow.Cut[j].Id() })
+
+	return flow, nil
+}
+
+// newFlowNetwork collects the relations reachable from the source, the
+// source becomes the first node.
+func newFlowNetwork(source Node, opts FlowOptions) (*flowNetwork, error) {
+	capacity := PropertyWeight(opts.CapacityProperty, opts.DefaultCapacity)
+	follow := PathOptions{Types: opts.Types}
+
+	net := &flowNetwork{indices: make(map[int]int)}
+	add := func(n Node) int {
+		if i, ok := net.indices[n.Id()]; ok {
+			return i
+		}
+		net.indices[n.Id()] = len(net.nodes)
+		net.nodes = append(net.nodes, n)
+		net.out = append(net.out, nil)
+		return len(net.nodes) - 1
+	}
+
+	add(source)
+	for i := 0; i < len(net.nodes); i++ {
+		for _, rel := range net.nodes[i].Relations(Outgoing) {
+			if !follow.Follows(rel) {
+				continue
+			}
+			c := capacity(rel)
+			if c < 0 || math.IsNaN(c) {
+				return nil, ErrNegativeCapacity
+			}
+
+			to := add(rel.End())
+			net.out[i] = append(net.out[i], len(net.edges))
+			net.edges = append(net.edges, flowEdge{to: to, capacity: c, rel: rel})
+			net.out[to] = append(net.out[to], len(net.edges))
+			net.edges = append(net.edges, flowEdge{to: i, rel: rel})
+		}
+	}
+
+	return net, nil
+}
+
+// residual is the capacity left on an edge, a tiny remainder counts as none.
+func (e *flowEdge) residual() float64 {
+	if r := e.capacity - e.flow; r > 1e-12 {
+		return r
+	}
+	return 0
+}
+
+// levels numbers the nodes by their distance from s in the residual graph
+// and reports whether t is reachable.
+func (net *flowNetwork) levels(s, t int) bool {
+	net.level = make([]int, len(net.nodes))
+	for i := range net.level {
+		net.level[i] = -1
+	}
+	net.level[s] = 0
+
+	for queue := []int{s}; len(queue) > 0; queue = queue[1:] {
+		for _, e := range net.out[queue[0]] {
+			edge := &net.edges[e]
+			if net.level[edge.to] < 0 && edge.residual() > 0 {
+				net.level[edge.to] = net.level[queue[0]] + 1
+				queue = append(queue, edge.to)
+			}
+		}
+	}
+	return t >= 0 && net.level[t] >= 0
+}
+
+// push sends flow along a path of increasing levels from n to t, returning
+// the flow sent.
+func (net *flowNetwork) push(n, t int, limit float64) float64 {
+	if n == t {
+		return limit
+	}
+
+	for ; net.next[n] < len(net.out[n]); net.next[n]++ {
+		e := net.out[n][net.next[n]]
+		edge := &net.edges[e]
+		if net.level[edge.to] != net.level[n]+1 || edge.residual() == 0 {
+			continue
+		}
+
+		if pushed := net.push(edge.to, t, math.Min(limit, edge.residual())); pushed > 0 {
+			edge.flow += pushed
+			net.edges[e^1].flow -= pushed
+			return pushed
+		}
+	}
+	return 0
+}
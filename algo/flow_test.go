@@ -0,0 +1,117 @@
+package algo
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+)
+
+// newNetwork creates the flow network of Cormen et al. with source 0 and
+// sink 5.
+func newNetwork() (DatabaseService, []Node) {
+	db, ns := newGraph(6)
+	for _, pipe := range [][3]int{{0, 1, 16}, {0, 2, 13}, {1, 3, 12}, {2, 1, 4}, {2, 4, 14}, {3, 2, 9}, {3, 5, 20}, {4, 3, 7}, {4, 5, 4}} {
+		ns[pipe[0]].RelateTo(ns[pipe[1]], "PIPE").SetProperty("capacity", pipe[2])
+	}
+	return db, ns
+}
+
+func TestMaxFlow(t *testing.T) {
+	_, ns := newNetwork()
+
+	flow, err := MaxFlow(ns[0], ns[5], FlowOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Value != 23 {
+		t.Fatal("Expected a flow of 23, got ", flow.Value)
+	}
+
+	// Flow is conserved in every node but source and sink
+	balance := make(map[int]float64)
+	for _, f := range flow.Relations {
+		if f.Flow > float64(f.Relation.Property("capacity").(int))+1e-9 {
+			t.Error("Expected flow within capacity, got ", f)
+		}
+		balance[f.Relation.Start().Id()] -= f.Flow
+		balance[f.Relation.End().Id()] += f.Flow
+	}
+	for id, b := range balance {
+		if id != 0 && id != 5 && math.Abs(b) > 1e-9 {
+			t.Errorf("Expected flow to be conserved in %d, got %v", id, b)
+		}
+	}
+	if balance[5] != 23 {
+		t.Error("Expected the flow to reach the sink, got ", balance[5])
+	}
+
+	cut := 0.0
+	for _, rel := range flow.Cut {
+		cut += float64(rel.Property("capacity").(int))
+	}
+	if len(flow.Cut) != 3 || cut != flow.Value {
+		t.Error("Expected the cut to limit the flow, got ", flow.Cut)
+	}
+	if len(flow.SourceSide) != 4 {
+		t.Error("Expected the sink separated from the source, got ", flow.SourceSide)
+	}
+}
+
+func TestMaxFlowOptions(t *testing.T) {
+	_, ns := newNetwork()
+	ns[0].RelateTo(ns[5], "SHORTCUT")
+
+	flow, err := MaxFlow(ns[0], ns[5], FlowOptions{DefaultCapacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Value != 25 {
+		t.Error("Expected relations without capacity to carry the default, got ", flow.Value)
+	}
+
+	flow, err = MaxFlow(ns[0], ns[5], FlowOptions{DefaultCapacity: 2, Types: []string{"PIPE"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Value != 23 {
+		t.Error("Expected only pipes to carry flow, got ", flow.Value)
+	}
+
+	flow, err = MaxFlow(ns[5], ns[0], FlowOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flow.Value != 0 || len(flow.Cut) != 0 {
+		t.Error("Expected no flow against the direction of relations, got ", flow.Value)
+	}
+
+	ns[1].RelateTo(ns[2], "PIPE").SetProperty("capacity", -1)
+	if _, err := MaxFlow(ns[0], ns[5], FlowOptions{}); !errors.Is(err, ErrNegativeCapacity) {
+		t.Error("Expected negative capacities to be rejected, got ", err)
+	}
+}
+
+func TestCallMaxFlow(t *testing.T) {
+	db, ns := newNetwork()
+	ns[0].SetProperty("name", "source")
+	ns[5].SetProperty("name", "sink")
+
+	table, err := goneo.Evaluate(db, `match (s {name: "source"}), (t {name: "sink"}) call algo.minCut(s, t) yield value, relation return value, relation`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 3 || table.Get(0, "value") != 23.0 {
+		t.Errorf("Expected the minimum cut, got %v", table)
+	}
+
+	table, err = goneo.Evaluate(db, `match (s {name: "source"}), (t {name: "sink"}) call algo.maxFlow(s, t, "capacity", "PIPE") yield flow return flow`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() == 0 {
+		t.Error("Expected relations carrying flow")
+	}
+}
@@ -0,0 +1,170 @@
+package algo
+
+import . "github.com/BuJo/goneo/db"
+
+// weightedGraph is an undirected graph with symmetric weights, self loops
+// hold the weight within an aggregated community.
+type weightedGraph []map[int]float64
+
+// newWeightedGraph reads the relations of the database ignoring their
+// direction. Self loops are ignored.
+func newWeightedGraph(db DatabaseService, weightProperty string) weightedGraph {
+	a := newAdjacency(db)
+	weights := a.weights(db, weightProperty)
+
+	g := make(weightedGraph, a.order())
+	for i := range g {
+		g[i] = make(map[int]float64)
+	}
+	for i := range a.out {
+		for k, o := range a.out[i] {
+			if o != i {
+				g[i][o] += weights[i][k]
+				g[o][i] += weights[i][k]
+			}
+		}
+	}
+	return g
+}
+
+// degrees returns the summed weights of every node and of all nodes.
+func (g weightedGraph) degrees() ([]float64, float64) {
+	degrees := make([]float64, len(g))
+	total := 0.0
+	for i := range g {
+		for _, w := range g[i] {
+			degrees[i] += w
+		}
+		total += degrees[i]
+	}
+	return degrees, total
+}
+
+// Modularity measures how much denser the communities are related within
+// than expected at random, ignoring the direction of relations.
+func Modularity(db DatabaseService, p Partition, weightProperty string) float64 {
+	g := newWeightedGraph(db, weightProperty)
+	degrees, total := g.degrees()
+	if total == 0 {
+		return 0
+	}
+
+	internal := make(map[int]float64)
+	degree := make(map[int]float64)
+	for i := range g {
+		degree[p[i]] += degrees[i]
+		for j, w := range g[i] {
+			if p[i] == p[j] {
+				internal[p[i]] += w
+			}
+		}
+	}
+
+	q := 0.0
+	for c := range degree {
+		q += internal[c]/total - (degree[c]/total)*(degree[c]/total)
+	}
+	return q
+}
+
+// Louvain detects communities by greedily moving nodes into the community of
+// a neighbour as long as the modularity increases, then merging the
+// communities into single nodes and repeating. Nodes are visited in order of
+// their id, so results are reproducible.
+func Louvain(db DatabaseService, weightProperty string) Partition {
+	g := newWeightedGraph(db, weightProperty)
+
+	// community of every original node
+	labels := make([]int, len(g))
+	for i := range labels {
+		labels[i] = i
+	}
+
+	for {
+		communities, moved := g.moveNodes()
+		if !moved {
+			break
+		}
+
+		for i := range labels {
+			labels[i] = communities[labels[i]]
+		}
+		g = g.aggregate(communities)
+	}
+
+	return partition(labels)
+}
+
+// moveNodes runs the local moving phase, returning the dense community index
+// of every node and whether any node moved.
+func (g weightedGraph) moveNodes() ([]int, bool) {
+	degrees, total := g.degrees()
+
+	community := make([]int, len(g))
+	tot := make([]float64, len(g))
+	for i := range g {
+		community[i] = i
+		tot[i] = degrees[i]
+	}
+	if total == 0 {
+		return community, false
+	}
+
+	moved := false
+	for improved := true; improved; {
+		improved = false
+		for i := range g {
+			current := community[i]
+			tot[current] -= degrees[i]
+
+			links := make(map[int]float64)
+			for j, w := range g[i] {
+				if j != i {
+					links[community[j]] += w
+				}
+			}
+
+			best, gain := current, links[current]-tot[current]*degrees[i]/total
+			for c, w := range links {
+				if delta := w - tot[c]*degrees[i]/total; delta > gain || (delta == gain && c < best && best != current) {
+					best, gain = c, delta
+				}
+			}
+
+			community[i] = best
+			tot[best] += degrees[i]
+			if best != current {
+				improved, moved = true, true
+			}
+		}
+	}
+
+	// Renumber densely, in order of the first member
+	index := make(map[int]int)
+	for i, c := range community {
+		if _, ok := index[c]; !ok {
+			index[c] = len(index)
+		}
+		community[i] = index[c]
+	}
+	return community, moved
+}
+
+// aggregate merges the nodes of every community into a single node.
+func (g weightedGraph) aggregate(communities []int) weightedGraph {
+	size := 0
+	for _, c := range communities {
+		size = max(size, c+1)
+	}
+
+	merged := make(weightedGraph, size)
+	for i := range merged {
+		merged[i] = make(map[int]float64)
+	}
+	for i := range g {
+		for j, w := range g[i] {
+			merged[communities[i]][communities[j]] += w
+		}
+	}
+	return merged
+}
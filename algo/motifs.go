@@ -0,0 +1,472 @@
+package algo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/sgi"
+)
+
+// Pattern is a small graph of labelled nodes and typed relations, nodes are
+// identified by their position. Nodes without labels and relations without
+// type stand for all nodes and relations.
+type Pattern struct {
+	// Labels of every node
+	Labels [][]string
+	// Relations between the nodes
+	Relations []PatternRelation
+}
+
+// PatternRelation relates two nodes of a pattern.
+type PatternRelation struct {
+	Start, End int
+	Type       string
+}
+
+// String renders the pattern as gcy MATCH pattern, the nodes are named by
+// their position, e.g. (n0:Person)-[:KNOWS]->(n1), (n1)-->(n2).
+func (p Pattern) String() string {
+	named := make([]bool, len(p.Labels))
+	node := func(n int) string {
+		str := "(n" + strconv.Itoa(n)
+		if !named[n] {
+			for _, label := range p.Labels[n] {
+				str += ":" + label
+			}
+			named[n] = true
+		}
+		return str + ")"
+	}
+
+	paths := make([]string, 0, len(p.Relations))
+	for _, rel := range p.Relations {
+		arrow := "-->"
+		if rel.Type != "" {
+			arrow = "-[:" + rel.Type + "]->"
+		}
+		start := node(rel.Start)
+		paths = append(paths, start+arrow+node(rel.End))
+	}
+	for n := range p.Labels {
+		if !named[n] {
+			paths = append(paths, node(n))
+		}
+	}
+	return strings.Join(paths, ", ")
+}
+
+// patternGraph adapts a pattern to the sgi.Graph interface, edges are
+// identified by the position of the relations.
+type patternGraph struct {
+	p Pattern
+}
+
+func (g patternGraph) Order() int             { return len(g.p.Labels) }
+func (g patternGraph) Contains(a, b int) bool { return len(g.Edges(a, b)) > 0 }
+func (g patternGraph) Successors(a int) []int {
+	ids := make([]int, 0)
+	for _, rel := range g.p.Relations {
+		if rel.Start == a {
+			ids = append(ids, rel.End)
+		}
+	}
+	return ids
+}
+func (g patternGraph) Predecessors(a int) []int {
+	ids := make([]int, 0)
+	for _, rel := range g.p.Relations {
+		if rel.End == a {
+			ids = append(ids, rel.Start)
+		}
+	}
+	return ids
+}
+func (g patternGraph) Relations(a int) []int {
+	return append(g.Successors(a), g.Predecessors(a)...)
+}
+func (g patternGraph) Edges(a, b int) []int {
+	ids := make([]int, 0)
+	for i, rel := range g.p.Relations {
+		if rel.Start == a && rel.End == b {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// canonical returns the certificate of the pattern and the pattern with its
+// nodes and relations in canonical order.
+func (p Pattern) canonical() (Pattern, string) {
+	form := sgi.Canonical(patternGraph{p}, sgi.Colors{
+		Node: func(node int) string { return strings.Join(p.Labels[node], ":") },
+		Edge: func(edge int) string { return p.Relations[edge].Type },
+	})
+
+	canonical := Pattern{Labels: make([][]string, len(p.Labels)), Relations: make([]PatternRelation, len(p.Relations))}
+	for n, pos := range form.Labeling {
+		canonical.Labels[pos] = p.Labels[n]
+	}
+	for i, rel := range p.Relations {
+		canonical.Relations[i] = PatternRelation{form.Labeling[rel.Start], form.Labeling[rel.End], rel.Type}
+	}
+	sort.Slice(canonical.Relations, func(i, j int) bool {
+		a, b := canonical.Relations[i], canonical.Relations[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Type < b.Type
+	})
+
+	return canonical, form.Certificate
+}
+
+// Motif is a class of isomorphic subgraphs and the number of times it occurs.
+type Motif struct {
+	Pattern Pattern
+	Count   int
+}
+
+// CountMotifs counts the connected subgraphs of 3 or 4 nodes by their
+// directed shape, ignoring labels, relation types and self relations.
+// Subgraphs are induced: all relations between their nodes are part of them.
+// Motifs are ordered by descending count.
+func CountMotifs(db DatabaseService, size int) ([]Motif, error) {
+	if size != 3 && size != 4 {
+		return nil, fmt.Errorf("motifs of %d nodes: only motifs of 3 or 4 nodes are counted", size)
+	}
+
+	a := newAdjacency(db)
+	neighbours := a.undirected()
+	out := make([]map[int]bool, a.order())
+	for n := range out {
+		out[n] = make(map[int]bool)
+		for _, m := range a.out[n] {
+			out[n][m] = true
+		}
+	}
+
+	// Subgraphs are told apart by their adjacency matrix first
+	shapes := make(map[int]int)
+	shape := func(sub []int) int {
+		bits := 0
+		for i, u := range sub {
+			for j, v := range sub {
+				if i != j && out[u][v] {
+					bits |= 1 << (i*size + j)
+				}
+			}
+		}
+		return bits
+	}
+
+	// ESU enumerates every connected subgraph once, from its smallest node
+	var extend func(sub, extension []int, root int)
+	extend = func(sub, extension []int, root int) {
+		if len(sub) == size {
+			shapes[shape(sub)] += 1
+			return
+		}
+
+		for len(extension) > 0 {
+			w := extension[len(extension)-1]
+			extension = extension[:len(extension)-1]
+
+			next := append([]int{}, extension...)
+			for u := range neighbours[w] {
+				if u <= root || touches(u, sub, neighbours) {
+					continue
+				}
+				next = append(next, u)
+			}
+
+			extend(append(sub[:len(sub):len(sub)], w), next, root)
+		}
+	}
+	for root := range neighbours {
+		extension := make([]int, 0, len(neighbours[root]))
+		for u := range neighbours[root] {
+			if u > root {
+				extension = append(extension, u)
+			}
+		}
+		extend([]int{root}, extension, root)
+	}
+
+	motifs := make(map[string]*Motif)
+	for bits, count := range shapes {
+		p := Pattern{Labels: make([][]string, size)}
+		for i := 0; i < size; i++ {
+			for j := 0; j < size; j++ {
+				if bits&(1<<(i*size+j)) != 0 {
+					p.Relations = append(p.Relations, PatternRelation{Start: i, End: j})
+				}
+			}
+		}
+
+		canonical, certificate := p.canonical()
+		if motif, ok := motifs[certificate]; ok {
+			motif.Count += count
+		} else {
+			motifs[certificate] = &Motif{canonical, count}
+		}
+	}
+
+	ordered := make([]Motif, 0, len(motifs))
+	for _, motif := range motifs {
+		ordered = append(ordered, *motif)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].Count != ordered[j].Count {
+			return ordered[i].Count > ordered[j].Count
+		}
+		return ordered[i].Pattern.String() < ordered[j].Pattern.String()
+	})
+	return ordered, nil
+}
+
+// touches checks if a node is part of the subgraph or a neighbour of it, ESU
+// only extends subgraphs by the other neighbours of the added node.
+func touches(u int, sub []int, neighbours []map[int]bool) bool {
+	for _, s := range sub {
+		if s == u || neighbours[s][u] {
+			return true
+		}
+	}
+	return false
+}
+
+// MiningOptions configures frequent subgraph mining, zero values select the
+// defaults.
+type MiningOptions struct {
+	// MinSupport is the least support of frequent patterns, 2 by default.
+	// The support of a pattern is the least number of distinct nodes any of
+	// its nodes is matched to.
+	MinSupport int
+	// MaxRelations bounds the number of relations of the patterns, 3 by
+	// default.
+	MaxRelations int
+}
+
+// FrequentPattern is a pattern occurring in the graph with its support.
+type FrequentPattern struct {
+	Pattern Pattern
+	Support int
+}
+
+// patternEmbedding matches the nodes and relations of a pattern, by their
+// ids, to the graph.
+type patternEmbedding struct {
+	nodes, relations []int
+}
+
+// extension grows a pattern by a relation, from a pattern node to another or
+// to a new node with the given labels.
+type extension struct {
+	from, to int
+	labels   string
+	relType  string
+	outgoing bool
+}
+
+// MineFrequentSubgraphs finds the connected patterns of labelled nodes and
+// typed relations with at least the minimum support. Like gSpan patterns grow
+// a relation at a time from frequent patterns, as the support of a pattern
+// never exceeds the support of its parts. Patterns reached in several ways
+// are told apart by their canonical form.
+// Patterns are ordered by size, then by descending support.
+func MineFrequentSubgraphs(db DatabaseService, opts MiningOptions) []FrequentPattern {
+	if opts.MinSupport <= 0 {
+		opts.MinSupport = 2
+	}
+	if opts.MaxRelations <= 0 {
+		opts.MaxRelations = 3
+	}
+
+	nodes := db.GetAllNodes()
+	labels := make(map[int][]string, len(nodes))
+	for _, n := range nodes {
+		labels[n.Id()] = append([]string{}, n.Labels()...)
+		sort.Strings(labels[n.Id()])
+	}
+
+	type candidate struct {
+		pattern    Pattern
+		embeddings []patternEmbedding
+	}
+
+	// single nodes by their labels
+	level := make([]candidate, 0)
+	byLabels := make(map[string]int)
+	for _, n := range nodes {
+		key := strings.Join(labels[n.Id()], ":")
+		i, ok := byLabels[key]
+		if !ok {
+			i = len(level)
+			byLabels[key] = i
+			level = append(level, candidate{pattern: Pattern{Labels: [][]string{labels[n.Id()]}}})
+		}
+		level[i].embeddings = append(level[i].embeddings, patternEmbedding{nodes: []int{n.Id()}})
+	}
+
+	frequent := make([]FrequentPattern, 0)
+	seen := make(map[string]bool)
+	for size := 0; size < opts.MaxRelations && len(level) > 0; size++ {
+		next := make([]candidate, 0)
+
+		for _, c := range level {
+			if size == 0 && mniSupport(c.embeddings) < opts.MinSupport {
+				continue
+			}
+
+			grown := make(map[extension][]patternEmbedding)
+			for _, e := range c.embeddings {
+				for ext, embedding := range extensions(db, e, labels) {
+					grown[ext] = append(grown[ext], embedding...)
+				}
+			}
+
+			for ext, embeddings := range grown {
+				p := c.pattern.extend(ext, labels, embeddings)
+				canonical, certificate := p.canonical()
+				if seen[certificate] {
+					continue
+				}
+				seen[certificate] = true
+
+				if support := mniSupport(embeddings); support >= opts.MinSupport {
+					frequent = append(frequent, FrequentPattern{canonical, support})
+					next = append(next, candidate{p, embeddings})
+				}
+			}
+		}
+
+		level = next
+	}
+
+	sort.Slice(frequent, func(i, j int) bool {
+		a, b := frequent[i], frequent[j]
+		if len(a.Pattern.Relations) != len(b.Pattern.Relations) {
+			return len(a.Pattern.Relations) < len(b.Pattern.Relations)
+		}
+		if a.Support != b.Support {
+			return a.Support > b.Support
+		}
+		return a.Pattern.String() < b.Pattern.String()
+	})
+	return frequent
+}
+
+// extensions lists the ways to grow an embedding by an unused relation of
+// one of its nodes, with the grown embeddings.
+func extensions(db DatabaseService, e patternEmbedding, labels map[int][]string) map[extension][]patternEmbedding {
+	exts := make(map[extension][]patternEmbedding)
+
+	used := func(rel Relation) bool {
+		for _, id := range e.relations {
+			if id == rel.Id() {
+				return true
+			}
+		}
+		return false
+	}
+	position := func(id int) int {
+		for i, n := range e.nodes {
+			if n == id {
+				return i
+			}
+		}
+		return sgi.NullNode
+	}
+	grow := func(ext extension, rel Relation, node int) {
+		grown := patternEmbedding{
+			nodes:     e.nodes,
+			relations: append(e.relations[:len(e.relations):len(e.relations)], rel.Id()),
+		}
+		if node != sgi.NullNode {
+			grown.nodes = append(e.nodes[:len(e.nodes):len(e.nodes)], node)
+		}
+		exts[ext] = append(exts[ext], grown)
+	}
+
+	for i, id := range e.nodes {
+		n, err := db.GetNode(id)
+		if err != nil {
+			continue
+		}
+
+		// self relations are listed in both directions
+		listed := make(map[int]bool)
+		for _, rel := range n.Relations(Both) {
+			if used(rel) || listed[rel.Id()] {
+				continue
+			}
+			listed[rel.Id()] = true
+
+			outgoing := rel.Start().Id() == id
+			other := rel.End().Id()
+			if !outgoing {
+				other = rel.Start().Id()
+			}
+
+			if j := position(other); j != sgi.NullNode {
+				// relations between the nodes are added from their start
+				if outgoing {
+					grow(extension{from: i, to: j, relType: rel.Type(), outgoing: true}, rel, sgi.NullNode)
+				}
+				continue
+			}
+
+			ext := extension{from: i, to: sgi.NullNode, labels: strings.Join(labels[other], ":"), relType: rel.Type(), outgoing: outgoing}
+			grow(ext, rel, other)
+		}
+	}
+
+	return exts
+}
+
+// extend copies the pattern grown by the extension, new nodes take the
+// labels of the node matched in the first embedding.
+func (p Pattern) extend(ext extension, labels map[int][]string, embeddings []patternEmbedding) Pattern {
+	grown := Pattern{
+		Labels:    append([][]string{}, p.Labels...),
+		Relations: append([]PatternRelation{}, p.Relations...),
+	}
+
+	rel := PatternRelation{Start: ext.from, End: ext.to, Type: ext.relType}
+	if ext.to == sgi.NullNode {
+		grown.Labels = append(grown.Labels, labels[embeddings[0].nodes[len(p.Labels)]])
+		rel.End = len(p.Labels)
+		if !ext.outgoing {
+			rel.Start, rel.End = rel.End, rel.Start
+		}
+	}
+	grown.Relations = append(grown.Relations, rel)
+
+	return grown
+}
+
+// mniSupport is the least number of distinct nodes a pattern node is matched
+// to, the minimum image based support.
+func mniSupport(embeddings []patternEmbedding) int {
+	if len(embeddings) == 0 {
+		return 0
+	}
+
+	support := -1
+	for i := range embeddings[0].nodes {
+		images := make(map[int]bool)
+		for _, e := range embeddings {
+			images[e.nodes[i]] = true
+		}
+		if support < 0 || len(images) < support {
+			support = len(images)
+		}
+	}
+	return support
+}
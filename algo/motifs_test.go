@@ -0,0 +1,214 @@
+package algo
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	"github.com/BuJo/goneo/data"
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/db/mem"
+)
+
+// bruteForceMotifs classifies every set of nodes inducing a connected
+// subgraph.
+func bruteForceMotifs(db DatabaseService, size int) map[string]int {
+	a := newAdjacency(db)
+	neighbours := a.undirected()
+	certificates := make(map[string]int)
+
+	var choose func(sub []int, next int)
+	choose = func(sub []int, next int) {
+		if len(sub) == size {
+			p := Pattern{Labels: make([][]string, size)}
+			for i, u := range sub {
+				for j, v := range sub {
+					for _, w := range a.neighbours(u, Outgoing) {
+						if i != j && w == v {
+							p.Relations = append(p.Relations, PatternRelation{Start: i, End: j})
+						}
+					}
+				}
+			}
+
+			// connected if a walk from the first node reaches all
+			reached := map[int]bool{0: true}
+			for changed := true; changed; {
+				changed = false
+				for i, u := range sub {
+					for j, v := range sub {
+						if reached[i] && !reached[j] && neighbours[u][v] {
+							reached[j], changed = true, true
+						}
+					}
+				}
+			}
+			if len(reached) == size {
+				_, certificate := p.canonical()
+				certificates[certificate] += 1
+			}
+			return
+		}
+		for n := next; n < a.order(); n++ {
+			choose(append(sub[:len(sub):len(sub)], n), n+1)
+		}
+	}
+	choose(nil, 0)
+
+	return certificates
+}
+
+func TestCountMotifs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	rels := make([][2]int, 0)
+	for i := 0; i < 30; i++ {
+		rels = append(rels, [2]int{r.Intn(12), r.Intn(12)})
+	}
+	db, _ := newGraph(12, rels...)
+
+	for _, size := range []int{3, 4} {
+		motifs, err := CountMotifs(db, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := bruteForceMotifs(db, size)
+		if len(motifs) != len(expected) {
+			t.Errorf("Expected %d motifs of %d nodes, got %d", len(expected), size, len(motifs))
+		}
+		for i, motif := range motifs {
+			_, certificate := motif.Pattern.canonical()
+			if expected[certificate] != motif.Count {
+				t.Errorf("Expected %s to occur %d times, got %d", motif.Pattern, expected[certificate], motif.Count)
+			}
+			if i > 0 && motifs[i-1].Count < motif.Count {
+				t.Error("Expected the motifs ordered by count, got ", motifs)
+			}
+		}
+	}
+
+	if _, err := CountMotifs(db, 5); err == nil {
+		t.Error("Expected only motifs of 3 or 4 nodes")
+	}
+}
+
+func TestFeedForwardMotif(t *testing.T) {
+	db, _ := newGraph(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{0, 2}, [2]int{3, 3})
+
+	motifs, _ := CountMotifs(db, 3)
+	if len(motifs) != 1 || motifs[0].Count != 1 || len(motifs[0].Pattern.Relations) != 3 {
+		t.Fatal("Expected a single feed forward loop, got ", motifs)
+	}
+
+	table, err := goneo.Evaluate(db, "match "+motifs[0].Pattern.String()+" return n0, n1, n2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 1 {
+		t.Errorf("Expected %s to match the loop once, got %d", motifs[0].Pattern, table.Len())
+	}
+}
+
+// newFriends relates people who like movies.
+func newFriends() DatabaseService {
+	db, _ := mem.NewDb("friends", nil)
+
+	for i := 0; i < 3; i++ {
+		a, b, m := db.NewNode("Person"), db.NewNode("Person"), db.NewNode("Movie")
+		a.RelateTo(b, "KNOWS")
+		b.RelateTo(m, "LIKES")
+	}
+	a, m := db.NewNode("Person"), db.NewNode("Movie")
+	m.RelateTo(a, "STARS")
+
+	return db
+}
+
+func TestMineFrequentSubgraphs(t *testing.T) {
+	db := newFriends()
+
+	patterns := MineFrequentSubgraphs(db, MiningOptions{MinSupport: 3})
+
+	supports := make(map[string]int)
+	for i, p := range patterns {
+		supports[p.Pattern.String()] = p.Support
+		if i > 0 && len(patterns[i-1].Pattern.Relations) > len(p.Pattern.Relations) {
+			t.Error("Expected the patterns ordered by size, got ", patterns)
+		}
+	}
+
+	if len(patterns) != 3 {
+		t.Error("Expected both relations and the path of both, got ", supports)
+	}
+	for _, p := range patterns {
+		if p.Support != 3 {
+			t.Errorf("Expected %s to be supported by every friend, got %d", p.Pattern, p.Support)
+		}
+		if len(p.Pattern.Relations) == 2 && len(p.Pattern.Labels) != 3 {
+			t.Error("Expected a path through three nodes, got ", p.Pattern)
+		}
+
+		table, err := goneo.Evaluate(db, "match "+p.Pattern.String()+" return n0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if table.Len() != 3 {
+			t.Errorf("Expected %s to match every friend, got %d", p.Pattern, table.Len())
+		}
+	}
+
+	// with less support the stars are frequent as well
+	if patterns := MineFrequentSubgraphs(db, MiningOptions{MinSupport: 1, MaxRelations: 1}); len(patterns) != 3 {
+		t.Error("Expected every relation type, got ", patterns)
+	}
+}
+
+func TestMinedPatternsAreDistinct(t *testing.T) {
+	db, _ := mem.NewDb("universe", nil)
+	data.NewUniverseGenerator(db).Generate()
+
+	patterns := MineFrequentSubgraphs(db, MiningOptions{MinSupport: 2, MaxRelations: 2})
+	if len(patterns) == 0 {
+		t.Fatal("Expected frequent patterns")
+	}
+
+	certificates := make(map[string]bool)
+	for _, p := range patterns {
+		_, certificate := p.Pattern.canonical()
+		if certificates[certificate] {
+			t.Error("Expected every pattern once, got ", p.Pattern, " twice")
+		}
+		certificates[certificate] = true
+
+		table, err := goneo.Evaluate(db, "match "+p.Pattern.String()+" return n0")
+		if err != nil {
+			t.Fatalf("%s: %v", p.Pattern, err)
+		}
+		if table.Len() < p.Support {
+			t.Errorf("Expected %s to match at least %d times, got %d", p.Pattern, p.Support, table.Len())
+		}
+	}
+}
+
+func TestPatternProcedures(t *testing.T) {
+	db := newFriends()
+
+	table, err := goneo.Evaluate(db, `call algo.motifs(3) yield pattern, count return pattern, count`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 1 || table.Get(0, "count") != 3 {
+		t.Error("Expected the paths through friends, got ", table)
+	}
+
+	table, err = goneo.Evaluate(db, `call algo.frequentSubgraphs(3, 2) yield pattern, support return pattern, support`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 3 {
+		t.Error("Expected the frequent patterns, got ", table)
+	}
+	if _, err := goneo.Evaluate(db, "match "+table.Get(2, "pattern").(string)+" return n0"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,601 @@
+package algo
+
+import (
+	"context"
+	"iter"
+	"strings"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+)
+
+// Centrality procedures yield every node with its score, highest first.
+// Given a write property, scores are stored on the nodes as well.
+//
+//	algo.pageRank([dampingFactor, iterations, weightProperty, writeProperty]) yield node, score
+//	algo.personalizedPageRank(source, [dampingFactor, iterations, weightProperty, writeProperty]) yield node, score
+//	algo.degree([direction, writeProperty]) yield node, score
+//	algo.closeness([direction, writeProperty]) yield node, score
+//	algo.betweenness([direction, writeProperty]) yield node, score
+//	algo.eigenvector([direction, iterations, writeProperty]) yield node, score
+//	algo.triangleCount([writeProperty]) yield node, score
+//	algo.clusteringCoefficient([writeProperty]) yield node, score
+//
+// Community procedures yield every node with its community, in order of the
+// node ids.
+//
+//	algo.wcc([writeProperty]) yield node, community
+//	algo.scc([writeProperty]) yield node, community
+//	algo.labelPropagation([iterations, seed, writeProperty]) yield node, community
+//	algo.louvain([weightProperty, writeProperty]) yield node, community
+//
+// Dependency procedures consider relations of a type, of all types if empty.
+//
+//	algo.topologicalSort([relType]) yield node, position
+//	algo.findCycles([relType]) yield path
+//	algo.transitiveReduction([relType]) yield relation
+//
+// Similarity procedures yield the topK most similar nodes of every node.
+// Neighbours are related by relations of the types, separated by "|". Given
+// a write type, similar nodes are related by it, the score stored as
+// property "score".
+//
+//	algo.jaccard([topK, types, direction, cutoff, writeType]) yield node, other, score
+//	algo.overlap([topK, types, direction, cutoff, writeType]) yield node, other, score
+//	algo.commonNeighbours([topK, types, direction, cutoff, writeType]) yield node, other, score
+//	algo.adamicAdar([topK, types, direction, cutoff, writeType]) yield node, other, score
+//	algo.cosine(properties, [topK, cutoff, writeType]) yield node, other, score
+//
+// Random walks yield their start node and the ids of the nodes walked,
+// choosing steps like node2vec unless p and q are 1.
+//
+//	algo.randomWalk([length, walksPerNode, p, q, seed, types, direction]) yield node, walk
+//
+// Flow procedures yield the value of the maximum flow from source to sink
+// with every relation carrying flow, or every relation of the minimum cut.
+//
+//	algo.maxFlow(source, sink, [capacityProperty, types]) yield value, relation, flow
+//	algo.minCut(source, sink, [capacityProperty, types]) yield value, relation
+//
+// Pattern procedures yield patterns in gcy syntax, to be used in MATCH.
+//
+//	algo.motifs([size]) yield pattern, count
+//	algo.frequentSubgraphs([minSupport, maxRelations]) yield pattern, support
+func init() {
+	ranked := []string{"node", "score"}
+	pageRank := []goneo.ProcedureArgument{
+		{Name: "dampingFactor", Default: 0.85},
+		{Name: "iterations", Default: 20},
+		{Name: "weightProperty", Default: ""},
+		{Name: "writeProperty", Default: ""},
+	}
+
+	goneo.RegisterProcedure("algo.pageRank", &goneo.Procedure{
+		Arguments: pageRank,
+		Outputs:   ranked,
+		Call:      pageRankProcedure,
+	})
+	goneo.RegisterProcedure("algo.personalizedPageRank", &goneo.Procedure{
+		Arguments: append([]goneo.ProcedureArgument{{Name: "source"}}, pageRank...),
+		Outputs:   ranked,
+		Call:      pageRankProcedure,
+	})
+
+	centralities := map[string]func(DatabaseService, Direction) Scores{
+		"algo.degree":      DegreeCentrality,
+		"algo.closeness":   ClosenessCentrality,
+		"algo.betweenness": BetweennessCentrality,
+	}
+	for name, centrality := range centralities {
+		goneo.RegisterProcedure(name, &goneo.Procedure{
+			Arguments: []goneo.ProcedureArgument{{Name: "direction", Default: "both"}, {Name: "writeProperty", Default: ""}},
+			Outputs:   ranked,
+			Call: func(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+				direction, err := args.String(0)
+				if err != nil {
+					return nil, err
+				}
+				return rankedRecords(db, centrality(db, DirectionFromString(direction)), args, 1)
+			},
+		})
+	}
+
+	goneo.RegisterProcedure("algo.eigenvector", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{{Name: "direction", Default: "both"}, {Name: "iterations", Default: 100}, {Name: "writeProperty", Default: ""}},
+		Outputs:   ranked,
+		Call:      eigenvectorProcedure,
+	})
+
+	scored := map[string]func(DatabaseService) Scores{
+		"algo.triangleCount": func(db DatabaseService) Scores {
+			triangles, _ := TriangleCount(db)
+			return triangles
+		},
+		"algo.clusteringCoefficient": ClusteringCoefficient,
+	}
+	for name, score := range scored {
+		goneo.RegisterProcedure(name, &goneo.Procedure{
+			Arguments: []goneo.ProcedureArgument{{Name: "writeProperty", Default: ""}},
+			Outputs:   ranked,
+			Call: func(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+				return rankedRecords(db, score(db), args, 0)
+			},
+		})
+	}
+
+	grouped := []string{"node", "community"}
+	components := map[string]func(DatabaseService) Partition{
+		"algo.wcc": WeaklyConnectedComponents,
+		"algo.scc": StronglyConnectedComponents,
+	}
+	for name, component := range components {
+		goneo.RegisterProcedure(name, &goneo.Procedure{
+			Arguments: []goneo.ProcedureArgument{{Name: "writeProperty", Default: ""}},
+			Outputs:   grouped,
+			Call: func(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+				return communityRecords(db, component(db), args, 0)
+			},
+		})
+	}
+
+	goneo.RegisterProcedure("algo.labelPropagation", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{{Name: "iterations", Default: 10}, {Name: "seed", Default: 1}, {Name: "writeProperty", Default: ""}},
+		Outputs:   grouped,
+		Call: func(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+			iterations, err := args.Int(0)
+			if err != nil {
+				return nil, err
+			}
+			seed, err := args.Int(1)
+			if err != nil {
+				return nil, err
+			}
+			return communityRecords(db, LabelPropagation(db, iterations, int64(seed)), args, 2)
+		},
+	})
+	goneo.RegisterProcedure("algo.louvain", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{{Name: "weightProperty", Default: ""}, {Name: "writeProperty", Default: ""}},
+		Outputs:   grouped,
+		Call: func(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+			weightProperty, err := args.String(0)
+			if err != nil {
+				return nil, err
+			}
+			return communityRecords(db, Louvain(db, weightProperty), args, 1)
+		},
+	})
+
+	typed := []goneo.ProcedureArgument{{Name: "relType", Default: ""}}
+	goneo.RegisterProcedure("algo.topologicalSort", &goneo.Procedure{
+		Arguments: typed,
+		Outputs:   []string{"node", "position"},
+		Call:      topologicalSortProcedure,
+	})
+	goneo.RegisterProcedure("algo.findCycles", &goneo.Procedure{
+		Arguments: typed,
+		Outputs:   []string{"path"},
+		Call:      findCyclesProcedure,
+	})
+	goneo.RegisterProcedure("algo.transitiveReduction", &goneo.Procedure{
+		Arguments: typed,
+		Outputs:   []string{"relation"},
+		Call:      transitiveReductionProcedure,
+	})
+
+	similar := []string{"node", "other", "score"}
+	neighbourhoods := map[string]func(DatabaseService, SimilarityOptions) []Similarity{
+		"algo.jaccard":          JaccardSimilarity,
+		"algo.overlap":          OverlapSimilarity,
+		"algo.commonNeighbours": CommonNeighbours,
+		"algo.adamicAdar":       AdamicAdar,
+	}
+	for name, similarity := range neighbourhoods {
+		goneo.RegisterProcedure(name, &goneo.Procedure{
+			Arguments: []goneo.ProcedureArgument{
+				{Name: "topK", Default: 10},
+				{Name: "types", Default: ""},
+				{Name: "direction", Default: "both"},
+				{Name: "cutoff", Default: 0.0},
+				{Name: "writeType", Default: ""},
+			},
+			Outputs: similar,
+			Call: func(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+				var opts SimilarityOptions
+				var err error
+				if opts.TopK, err = args.Int(0); err != nil {
+					return nil, err
+				}
+				types, err := args.String(1)
+				if err != nil {
+					return nil, err
+				}
+				if types != "" {
+					opts.Types = strings.Split(types, "|")
+				}
+				direction, err := args.String(2)
+				if err != nil {
+					return nil, err
+				}
+				opts.Direction = DirectionFromString(direction)
+				if opts.Cutoff, err = args.Float(3); err != nil {
+					return nil, err
+				}
+
+				return similarityRecords(similarity(db, opts), args, 4)
+			},
+		})
+	}
+	goneo.RegisterProcedure("algo.cosine", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{
+			{Name: "properties"},
+			{Name: "topK", Default: 10},
+			{Name: "cutoff", Default: 0.0},
+			{Name: "writeType", Default: ""},
+		},
+		Outputs: similar,
+		Call:    cosineProcedure,
+	})
+
+	goneo.RegisterProcedure("algo.randomWalk", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{
+			{Name: "length", Default: 10},
+			{Name: "walksPerNode", Default: 1},
+			{Name: "p", Default: 1.0},
+			{Name: "q", Default: 1.0},
+			{Name: "seed", Default: 1},
+			{Name: "types", Default: ""},
+			{Name: "direction", Default: "both"},
+		},
+		Outputs: []string{"node", "walk"},
+		Call:    randomWalkProcedure,
+	})
+
+	flow := []goneo.ProcedureArgument{
+		{Name: "source"},
+		{Name: "sink"},
+		{Name: "capacityProperty", Default: "capacity"},
+		{Name: "types", Default: ""},
+	}
+	goneo.RegisterProcedure("algo.maxFlow", &goneo.Procedure{
+		Arguments: flow,
+		Outputs:   []string{"value", "relation", "flow"},
+		Call:      maxFlowProcedure,
+	})
+	goneo.RegisterProcedure("algo.minCut", &goneo.Procedure{
+		Arguments: flow,
+		Outputs:   []string{"value", "relation"},
+		Call:      minCutProcedure,
+	})
+
+	goneo.RegisterProcedure("algo.motifs", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{{Name: "size", Default: 3}},
+		Outputs:   []string{"pattern", "count"},
+		Call:      motifsProcedure,
+	})
+	goneo.RegisterProcedure("algo.frequentSubgraphs", &goneo.Procedure{
+		Arguments: []goneo.ProcedureArgument{{Name: "minSupport", Default: 2}, {Name: "maxRelations", Default: 3}},
+		Outputs:   []string{"pattern", "support"},
+		Call:      frequentSubgraphsProcedure,
+	})
+}
+
+func pageRankProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	var opts PageRankOptions
+
+	// Personalized page rank starts with the source
+	if len(args) == 5 {
+		source, err := args.Node(0)
+		if err != nil {
+			return nil, err
+		}
+		opts.Sources = []Node{source}
+		args = args[1:]
+	}
+
+	var err error
+	if opts.DampingFactor, err = args.Float(0); err != nil {
+		return nil, err
+	}
+	if opts.Iterations, err = args.Int(1); err != nil {
+		return nil, err
+	}
+	if opts.WeightProperty, err = args.String(2); err != nil {
+		return nil, err
+	}
+
+	return rankedRecords(db, PageRank(db, opts), args, 3)
+}
+
+func eigenvectorProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	direction, err := args.String(0)
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := args.Int(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankedRecords(db, EigenvectorCentrality(db, DirectionFromString(direction), iterations), args, 2)
+}
+
+// rankedRecords writes the scores to the property named by argument i, if
+// any, and produces a record per node.
+func rankedRecords(db DatabaseService, s Scores, args goneo.ProcedureArgs, i int) (iter.Seq[map[string]interface{}], error) {
+	prop, err := args.String(i)
+	if err != nil {
+		return nil, err
+	}
+	if prop != "" {
+		if err := s.Write(db, prop); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, id := range s.Ranking() {
+			n, err := db.GetNode(id)
+			if err != nil {
+				continue
+			}
+			if !yield(map[string]interface{}{"node": n, "score": s[id]}) {
+				return
+			}
+		}
+	}, nil
+}
+
+// communityRecords writes the communities to the property named by argument
+// i, if any, and produces a record per node.
+func communityRecords(db DatabaseService, p Partition, args goneo.ProcedureArgs, i int) (iter.Seq[map[string]interface{}], error) {
+	prop, err := args.String(i)
+	if err != nil {
+		return nil, err
+	}
+	if prop != "" {
+		if err := p.Write(db, prop); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, n := range db.GetAllNodes() {
+			if !yield(map[string]interface{}{"node": n, "community": p[n.Id()]}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func topologicalSortProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	relType, err := args.String(0)
+	if err != nil {
+		return nil, err
+	}
+	sorted, err := TopologicalSort(db, relType)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for i, n := range sorted {
+			if !yield(map[string]interface{}{"node": n, "position": i}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func findCyclesProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	relType, err := args.String(0)
+	if err != nil {
+		return nil, err
+	}
+	cycles := FindCycles(db, relType)
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, cycle := range cycles {
+			if !yield(map[string]interface{}{"path": cycle}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func transitiveReductionProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	relType, err := args.String(0)
+	if err != nil {
+		return nil, err
+	}
+	reduction, err := TransitiveReduction(db, relType)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, rel := range reduction {
+			if !yield(map[string]interface{}{"relation": rel}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func cosineProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	var opts SimilarityOptions
+
+	properties, err := args.String(0)
+	if err != nil {
+		return nil, err
+	}
+	opts.Properties = strings.Split(properties, "|")
+	if opts.TopK, err = args.Int(1); err != nil {
+		return nil, err
+	}
+	if opts.Cutoff, err = args.Float(2); err != nil {
+		return nil, err
+	}
+
+	return similarityRecords(CosineSimilarity(db, opts), args, 3)
+}
+
+// similarityRecords relates the similar nodes by the type named by argument
+// i, if any, and produces a record per similarity.
+func similarityRecords(similarities []Similarity, args goneo.ProcedureArgs, i int) (iter.Seq[map[string]interface{}], error) {
+	relType, err := args.String(i)
+	if err != nil {
+		return nil, err
+	}
+	if relType != "" {
+		RelateSimilar(similarities, relType, "score")
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, s := range similarities {
+			if !yield(map[string]interface{}{"node": s.Node, "other": s.Other, "score": s.Score}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func randomWalkProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	var opts WalkOptions
+	var err error
+
+	if opts.Length, err = args.Int(0); err != nil {
+		return nil, err
+	}
+	if opts.WalksPerNode, err = args.Int(1); err != nil {
+		return nil, err
+	}
+	if opts.ReturnFactor, err = args.Float(2); err != nil {
+		return nil, err
+	}
+	if opts.InOutFactor, err = args.Float(3); err != nil {
+		return nil, err
+	}
+	seed, err := args.Int(4)
+	if err != nil {
+		return nil, err
+	}
+	opts.Seed = int64(seed)
+	types, err := args.String(5)
+	if err != nil {
+		return nil, err
+	}
+	if types != "" {
+		opts.Types = strings.Split(types, "|")
+	}
+	direction, err := args.String(6)
+	if err != nil {
+		return nil, err
+	}
+	opts.Direction = DirectionFromString(direction)
+
+	return func(yield func(map[string]interface{}) bool) {
+		for walk := range RandomWalks(db, opts) {
+			start, err := db.GetNode(walk[0])
+			if err != nil {
+				continue
+			}
+			if !yield(map[string]interface{}{"node": start, "walk": walk}) {
+				return
+			}
+		}
+	}, nil
+}
+
+// runMaxFlow computes the flow between the source and sink arguments.
+func runMaxFlow(args goneo.ProcedureArgs) (*Flow, error) {
+	source, err := args.Node(0)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := args.Node(1)
+	if err != nil {
+		return nil, err
+	}
+	var opts FlowOptions
+	if opts.CapacityProperty, err = args.String(2); err != nil {
+		return nil, err
+	}
+	types, err := args.String(3)
+	if err != nil {
+		return nil, err
+	}
+	if types != "" {
+		opts.Types = strings.Split(types, "|")
+	}
+
+	return MaxFlow(source, sink, opts)
+}
+
+func maxFlowProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	flow, err := runMaxFlow(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, f := range flow.Relations {
+			if !yield(map[string]interface{}{"value": flow.Value, "relation": f.Relation, "flow": f.Flow}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func minCutProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	flow, err := runMaxFlow(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, rel := range flow.Cut {
+			if !yield(map[string]interface{}{"value": flow.Value, "relation": rel}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func motifsProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	size, err := args.Int(0)
+	if err != nil {
+		return nil, err
+	}
+	motifs, err := CountMotifs(db, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, motif := range motifs {
+			if !yield(map[string]interface{}{"pattern": motif.Pattern.String(), "count": motif.Count}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func frequentSubgraphsProcedure(ctx context.Context, db DatabaseService, args goneo.ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	var opts MiningOptions
+	var err error
+	if opts.MinSupport, err = args.Int(0); err != nil {
+		return nil, err
+	}
+	if opts.MaxRelations, err = args.Int(1); err != nil {
+		return nil, err
+	}
+	patterns := MineFrequentSubgraphs(db, opts)
+
+	return func(yield func(map[string]interface{}) bool) {
+		for _, p := range patterns {
+			if !yield(map[string]interface{}{"pattern": p.Pattern.String(), "support": p.Support}) {
+				return
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,199 @@
+package algo
+
+import (
+	"math"
+	"strconv"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// SimilarityOptions configures similarity algorithms, zero values select the
+// defaults.
+type SimilarityOptions struct {
+	// Direction of the relations to the neighbours of a node, Both by
+	// default.
+	Direction Direction
+	// Types of relations to neighbours, all types if empty.
+	Types []string
+	// TopK limits the similar nodes per node, all if 0.
+	TopK int
+	// Cutoff leaves out pairs not scoring higher.
+	Cutoff float64
+	// Properties are the components of the vectors compared by cosine
+	// similarity.
+	Properties []string
+}
+
+// Similarity is the score of a node compared with another.
+type Similarity struct {
+	Node, Other Node
+	Score       float64
+}
+
+// JaccardSimilarity compares nodes by the share of their neighbours they have
+// in common: |A ∩ B| / |A ∪ B|.
+func JaccardSimilarity(db DatabaseService, opts SimilarityOptions) []Similarity {
+	return neighbourhoodSimilarity(db, opts, func(common, a, b int) float64 {
+		return float64(common) / float64(a+b-common)
+	})
+}
+
+// OverlapSimilarity compares nodes by the share of the smaller neighbourhood
+// they have in common: |A ∩ B| / min(|A|, |B|).
+func OverlapSimilarity(db DatabaseService, opts SimilarityOptions) []Similarity {
+	return neighbourhoodSimilarity(db, opts, func(common, a, b int) float64 {
+		return float64(common) / float64(min(a, b))
+	})
+}
+
+// CommonNeighbours predicts links between nodes by the number of neighbours
+// they have in common.
+func CommonNeighbours(db DatabaseService, opts SimilarityOptions) []Similarity {
+	return neighbourhoodSimilarity(db, opts, func(common, a, b int) float64 {
+		return float64(common)
+	})
+}
+
+// AdamicAdar predicts links between nodes by their common neighbours,
+// weighing rarely shared neighbours higher: Σ 1 / log(degree(z)).
+func AdamicAdar(db DatabaseService, opts SimilarityOptions) []Similarity {
+	n := newNeighbourhoods(db, opts)
+
+	return topK(db, opts, func(a int) map[int]float64 {
+		scores := make(map[int]float64)
+		for z := range n.neighbours[a] {
+			for b := range n.sharing[z] {
+				if b != a {
+					scores[b] += 1 / math.Log(float64(len(n.sharing[z])))
+				}
+			}
+		}
+		return scores
+	})
+}
+
+// CosineSimilarity compares nodes by the angle between the vectors of their
+// numeric properties, properties missing count as 0.
+func CosineSimilarity(db DatabaseService, opts SimilarityOptions) []Similarity {
+	nodes := db.GetAllNodes()
+
+	vectors := make([][]float64, len(nodes))
+	norms := make([]float64, len(nodes))
+	for _, node := range nodes {
+		v := make([]float64, len(opts.Properties))
+		for i, prop := range opts.Properties {
+			v[i], _ = strconv.ParseFloat(node.Properties()[prop], 64)
+			norms[node.Id()] += v[i] * v[i]
+		}
+		vectors[node.Id()], norms[node.Id()] = v, math.Sqrt(norms[node.Id()])
+	}
+
+	return topK(db, opts, func(a int) map[int]float64 {
+		scores := make(map[int]float64)
+		if norms[a] == 0 {
+			return scores
+		}
+		for b := range vectors {
+			if b == a || norms[b] == 0 {
+				continue
+			}
+			dot := 0.0
+			for i := range vectors[a] {
+				dot += vectors[a][i] * vectors[b][i]
+			}
+			scores[b] = dot / (norms[a] * norms[b])
+		}
+		return scores
+	})
+}
+
+// RelateSimilar materializes similarities as relations of the type from
+// every node to the nodes similar to it, the score stored in the property.
+func RelateSimilar(similarities []Similarity, relType, prop string) []Relation {
+	rels := make([]Relation, 0, len(similarities))
+	for _, s := range similarities {
+		rel := s.Node.RelateTo(s.Other, relType)
+		rel.SetProperty(prop, s.Score)
+		rels = append(rels, rel)
+	}
+	return rels
+}
+
+// neighbourhoods holds the neighbours of every node and, reversed, the nodes
+// sharing a neighbour.
+type neighbourhoods struct {
+	neighbours []map[int]bool
+	sharing    []map[int]bool
+}
+
+func newNeighbourhoods(db DatabaseService, opts SimilarityOptions) *neighbourhoods {
+	follow := PathOptions{Types: opts.Types}
+	nodes := db.GetAllNodes()
+
+	n := &neighbourhoods{make([]map[int]bool, len(nodes)), make([]map[int]bool, len(nodes))}
+	for i := range nodes {
+		n.neighbours[i], n.sharing[i] = make(map[int]bool), make(map[int]bool)
+	}
+	for _, node := range nodes {
+		for _, rel := range node.Relations(opts.Direction) {
+			if !follow.Follows(rel) {
+				continue
+			}
+			z := rel.End().Id()
+			if z == node.Id() {
+				z = rel.Start().Id()
+			}
+			n.neighbours[node.Id()][z] = true
+			n.sharing[z][node.Id()] = true
+		}
+	}
+	return n
+}
+
+// neighbourhoodSimilarity scores nodes sharing neighbours by the size of
+// their common neighbourhood and the sizes of their neighbourhoods.
+func neighbourhoodSimilarity(db DatabaseService, opts SimilarityOptions, score func(common, a, b int) float64) []Similarity {
+	n := newNeighbourhoods(db, opts)
+
+	return topK(db, opts, func(a int) map[int]float64 {
+		common := make(map[int]int)
+		for z := range n.neighbours[a] {
+			for b := range n.sharing[z] {
+				if b != a {
+					common[b] += 1
+				}
+			}
+		}
+
+		scores := make(map[int]float64, len(common))
+		for b, c := range common {
+			scores[b] = score(c, len(n.neighbours[a]), len(n.neighbours[b]))
+		}
+		return scores
+	})
+}
+
+// topK collects the highest scoring nodes of every node, ordered by node and
+// descending score.
+func topK(db DatabaseService, opts SimilarityOptions, scoresOf func(a int) map[int]float64) []Similarity {
+	nodes := db.GetAllNodes()
+
+	similarities := make([]Similarity, 0)
+	for a := range nodes {
+		scores := make(Scores)
+		for b, s := range scoresOf(a) {
+			if s > opts.Cutoff {
+				scores[b] = s
+			}
+		}
+
+		ranking := scores.Ranking()
+		if opts.TopK > 0 && len(ranking) > opts.TopK {
+			ranking = ranking[:opts.TopK]
+		}
+		for _, b := range ranking {
+			similarities = append(similarities, Similarity{nodes[a], nodes[b], scores[b]})
+		}
+	}
+	return similarities
+}
@@ -0,0 +1,139 @@
+package algo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+)
+
+// newLikes creates users 0-3 liking items 4-7, and a user following another.
+func newLikes() (DatabaseService, []Node) {
+	db, ns := newGraph(8)
+	for _, like := range [][2]int{{0, 4}, {0, 5}, {0, 6}, {1, 4}, {1, 5}, {2, 7}, {3, 4}} {
+		ns[like[0]].RelateTo(ns[like[1]], "LIKES")
+	}
+	ns[2].RelateTo(ns[0], "FOLLOWS")
+	return db, ns
+}
+
+// scoreOf finds the score of a pair of nodes, -1 if they are not similar.
+func scoreOf(similarities []Similarity, a, b Node) float64 {
+	for _, s := range similarities {
+		if s.Node == a && s.Other == b {
+			return s.Score
+		}
+	}
+	return -1
+}
+
+func TestJaccardSimilarity(t *testing.T) {
+	db, ns := newLikes()
+	likes := SimilarityOptions{Direction: Outgoing, Types: []string{"LIKES"}}
+
+	similarities := JaccardSimilarity(db, likes)
+	if s := scoreOf(similarities, ns[0], ns[1]); math.Abs(s-2.0/3) > 1e-9 {
+		t.Error("Expected users sharing two of three likes to be similar, got ", s)
+	}
+	if s := scoreOf(similarities, ns[1], ns[0]); math.Abs(s-2.0/3) > 1e-9 {
+		t.Error("Expected similarity to be symmetric, got ", s)
+	}
+	if s := scoreOf(similarities, ns[0], ns[2]); s != -1 {
+		t.Error("Expected users without common likes not to be similar, got ", s)
+	}
+	if s := scoreOf(similarities, ns[4], ns[5]); s != -1 {
+		t.Error("Expected only outgoing relations to be followed, got ", s)
+	}
+
+	similarities = JaccardSimilarity(db, SimilarityOptions{Direction: Incoming, Types: []string{"LIKES"}})
+	if s := scoreOf(similarities, ns[4], ns[5]); math.Abs(s-2.0/3) > 1e-9 {
+		t.Error("Expected items liked by the same users to be similar, got ", s)
+	}
+
+	similarities = JaccardSimilarity(db, SimilarityOptions{Direction: Outgoing})
+	if s := scoreOf(similarities, ns[2], ns[3]); s != -1 {
+		t.Error("Expected follows to be no likes, got ", s)
+	}
+	if s := scoreOf(similarities, ns[2], ns[1]); s != -1 {
+		t.Error("Expected follows to be no common neighbour, got ", s)
+	}
+}
+
+func TestSimilarityTopK(t *testing.T) {
+	db, ns := newLikes()
+
+	similarities := JaccardSimilarity(db, SimilarityOptions{Direction: Outgoing, TopK: 1})
+	if s := scoreOf(similarities, ns[0], ns[3]); s != -1 {
+		t.Error("Expected only the most similar user, got ", s)
+	}
+	if s := scoreOf(similarities, ns[0], ns[1]); s <= 0 {
+		t.Error("Expected the most similar user, got ", s)
+	}
+
+	similarities = JaccardSimilarity(db, SimilarityOptions{Direction: Outgoing, Cutoff: 0.5})
+	if s := scoreOf(similarities, ns[1], ns[3]); s != -1 {
+		t.Error("Expected to leave out dissimilar users, got ", s)
+	}
+}
+
+func TestLinkPrediction(t *testing.T) {
+	db, ns := newLikes()
+	likes := SimilarityOptions{Direction: Outgoing, Types: []string{"LIKES"}}
+
+	if s := scoreOf(OverlapSimilarity(db, likes), ns[0], ns[1]); s != 1 {
+		t.Error("Expected all likes of the user to overlap, got ", s)
+	}
+	if s := scoreOf(CommonNeighbours(db, likes), ns[0], ns[1]); s != 2 {
+		t.Error("Expected two common neighbours, got ", s)
+	}
+
+	adamicAdar := AdamicAdar(db, likes)
+	if s := scoreOf(adamicAdar, ns[0], ns[1]); math.Abs(s-(1/math.Log(3)+1/math.Log(2))) > 1e-9 {
+		t.Error("Expected neighbours weighed by their degree, got ", s)
+	}
+	if scoreOf(adamicAdar, ns[0], ns[3]) >= scoreOf(adamicAdar, ns[0], ns[1]) {
+		t.Error("Expected more common neighbours to score higher")
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	db, ns := newGraph(4)
+	for i, v := range [][2]string{{"1", "0"}, {"2", "0"}, {"0", "1"}, {"1", "1"}} {
+		ns[i].SetProperty("x", v[0])
+		ns[i].SetProperty("y", v[1])
+	}
+
+	similarities := CosineSimilarity(db, SimilarityOptions{Properties: []string{"x", "y"}})
+	if s := scoreOf(similarities, ns[0], ns[1]); math.Abs(s-1) > 1e-9 {
+		t.Error("Expected parallel vectors to be similar, got ", s)
+	}
+	if s := scoreOf(similarities, ns[0], ns[2]); s != -1 {
+		t.Error("Expected orthogonal vectors not to be similar, got ", s)
+	}
+	if s := scoreOf(similarities, ns[0], ns[3]); math.Abs(s-math.Sqrt2/2) > 1e-9 {
+		t.Error("Expected diagonal vector to be half similar, got ", s)
+	}
+}
+
+func TestCallSimilarity(t *testing.T) {
+	db, ns := newLikes()
+
+	table, err := goneo.Evaluate(db, `call algo.jaccard(1, "LIKES", "out", 0.5, "SIMILAR") yield node, other, score return node, other, score`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 2 || table.Get(0, "node") != ns[0] || table.Get(0, "other") != ns[1] {
+		t.Errorf("Expected the most similar users, got %v", table)
+	}
+
+	rels := make([]Relation, 0)
+	for _, rel := range ns[0].Relations(Outgoing) {
+		if rel.Type() == "SIMILAR" {
+			rels = append(rels, rel)
+		}
+	}
+	if len(rels) != 1 || rels[0].End() != ns[1] || rels[0].Property("score") != table.Get(0, "score") {
+		t.Error("Expected similar users to be related, got ", rels)
+	}
+}
@@ -0,0 +1,148 @@
+package algo
+
+import (
+	"iter"
+	"math/rand"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// WalkOptions configures random walks, zero values select the defaults.
+type WalkOptions struct {
+	// Direction of the relations to follow, Both by default.
+	Direction Direction
+	// Types of relations to follow, all types if empty.
+	Types []string
+	// Length is the number of nodes of a walk, 10 by default. Walks end
+	// early at nodes without relations to follow.
+	Length int
+	// WalksPerNode is the number of walks starting at every node, 1 by
+	// default.
+	WalksPerNode int
+	// ReturnFactor is node2vec's p, the higher the less likely a walk
+	// returns to the node it came from. 1 by default.
+	ReturnFactor float64
+	// InOutFactor is node2vec's q, the higher the more a walk stays close to
+	// the node it came from. 1 by default.
+	InOutFactor float64
+	// Seed of the random numbers, the same seed produces the same walks.
+	Seed int64
+	// Starts are the nodes to start walks at, all nodes if empty.
+	Starts []Node
+}
+
+// RandomWalks produces walks through the graph as node ids. With default
+// factors every relation is followed with the same probability, otherwise
+// the next node is chosen in the biased way of node2vec.
+func RandomWalks(db DatabaseService, opts WalkOptions) iter.Seq[[]int] {
+	if opts.Length == 0 {
+		opts.Length = 10
+	}
+	if opts.WalksPerNode == 0 {
+		opts.WalksPerNode = 1
+	}
+	if opts.ReturnFactor == 0 {
+		opts.ReturnFactor = 1
+	}
+	if opts.InOutFactor == 0 {
+		opts.InOutFactor = 1
+	}
+
+	return func(yield func([]int) bool) {
+		w := newWalker(db, opts)
+
+		starts := opts.Starts
+		if len(starts) == 0 {
+			starts = db.GetAllNodes()
+		}
+
+		for round := 0; round < opts.WalksPerNode; round++ {
+			for _, start := range starts {
+				if !yield(w.walk(start.Id())) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// walker chooses the steps of random walks.
+type walker struct {
+	opts WalkOptions
+	rnd  *rand.Rand
+
+	// next nodes of every node, once per relation to follow
+	next [][]int
+	// related nodes of every node, to tell how far a step leads
+	related []map[int]bool
+}
+
+func newWalker(db DatabaseService, opts WalkOptions) *walker {
+	follow := PathOptions{Types: opts.Types}
+	nodes := db.GetAllNodes()
+
+	w := &walker{
+		opts:    opts,
+		rnd:     rand.New(rand.NewSource(opts.Seed)),
+		next:    make([][]int, len(nodes)),
+		related: make([]map[int]bool, len(nodes)),
+	}
+	for _, n := range nodes {
+		w.related[n.Id()] = make(map[int]bool)
+		for _, rel := range n.Relations(opts.Direction) {
+			if !follow.Follows(rel) {
+				continue
+			}
+			o := rel.End().Id()
+			if o == n.Id() {
+				o = rel.Start().Id()
+			}
+			w.next[n.Id()] = append(w.next[n.Id()], o)
+			w.related[n.Id()][o] = true
+		}
+	}
+	return w
+}
+
+func (w *walker) walk(start int) []int {
+	walk := []int{start}
+	for len(walk) < w.opts.Length {
+		current := walk[len(walk)-1]
+		candidates := w.next[current]
+		if len(candidates) == 0 {
+			break
+		}
+
+		if len(walk) == 1 || (w.opts.ReturnFactor == 1 && w.opts.InOutFactor == 1) {
+			walk = append(walk, candidates[w.rnd.Intn(len(candidates))])
+			continue
+		}
+
+		// Weigh by the distance of the candidate to the previous node
+		previous := walk[len(walk)-2]
+		weights := make([]float64, len(candidates))
+		total := 0.0
+		for i, c := range candidates {
+			switch {
+			case c == previous:
+				weights[i] = 1 / w.opts.ReturnFactor
+			case w.related[previous][c]:
+				weights[i] = 1
+			default:
+				weights[i] = 1 / w.opts.InOutFactor
+			}
+			total += weights[i]
+		}
+
+		choice := w.rnd.Float64() * total
+		next := candidates[len(candidates)-1]
+		for i, c := range candidates {
+			if choice -= weights[i]; choice < 0 {
+				next = c
+				break
+			}
+		}
+		walk = append(walk, next)
+	}
+	return walk
+}
@@ -0,0 +1,84 @@
+package algo
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	. "github.com/BuJo/goneo/db"
+)
+
+func TestRandomWalks(t *testing.T) {
+	db, ns := newGraph(5, cliques[:6]...)
+	ns[3].RelateTo(ns[4], "LINKS")
+
+	walks := slices.Collect(RandomWalks(db, WalkOptions{Length: 6, WalksPerNode: 3, Seed: 42}))
+	if len(walks) != 15 {
+		t.Fatal("Expected 3 walks per node, got ", len(walks))
+	}
+	for i, walk := range walks {
+		if len(walk) != 6 || walk[0] != i%5 {
+			t.Errorf("Expected walk of 6 nodes from %d, got %v", i%5, walk)
+		}
+		for s := 1; s < len(walk); s++ {
+			if !newAdjacency(db).undirected()[walk[s-1]][walk[s]] {
+				t.Errorf("Expected walk to follow relations, got %v", walk)
+			}
+		}
+	}
+
+	if again := slices.Collect(RandomWalks(db, WalkOptions{Length: 6, WalksPerNode: 3, Seed: 42})); !reflect.DeepEqual(walks, again) {
+		t.Errorf("Expected the same walks for the same seed, got %v and %v", walks, again)
+	}
+	if other := slices.Collect(RandomWalks(db, WalkOptions{Length: 6, WalksPerNode: 3, Seed: 7})); reflect.DeepEqual(walks, other) {
+		t.Error("Expected different walks for another seed")
+	}
+
+	walks = slices.Collect(RandomWalks(db, WalkOptions{Direction: Outgoing, Starts: []Node{ns[3]}}))
+	if expected := [][]int{{3, 4}}; !reflect.DeepEqual(walks, expected) {
+		t.Errorf("Expected walk to end without relations to follow, got %v", walks)
+	}
+}
+
+func TestBiasedRandomWalks(t *testing.T) {
+	// a line of nodes
+	db, ns := newGraph(10)
+	for i := 1; i < len(ns); i++ {
+		ns[i-1].RelateTo(ns[i], "NEXT")
+	}
+
+	returns := func(opts WalkOptions) int {
+		count := 0
+		for walk := range RandomWalks(db, opts) {
+			for s := 2; s < len(walk); s++ {
+				if walk[s] == walk[s-2] {
+					count += 1
+				}
+			}
+		}
+		return count
+	}
+
+	outward := returns(WalkOptions{WalksPerNode: 10, ReturnFactor: 100, Seed: 1})
+	backward := returns(WalkOptions{WalksPerNode: 10, ReturnFactor: 0.01, Seed: 1})
+	uniform := returns(WalkOptions{WalksPerNode: 10, Seed: 1})
+	if outward >= uniform || uniform >= backward {
+		t.Errorf("Expected the return factor to influence returns, got %d, %d and %d", outward, uniform, backward)
+	}
+}
+
+func TestCallRandomWalk(t *testing.T) {
+	db, ns := newGraph(4, cliques[:6]...)
+
+	table, err := goneo.Evaluate(db, `call algo.randomWalk(5, 2) yield node, walk return node, walk`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 8 || table.Get(1, "node") != ns[1] {
+		t.Fatalf("Expected two walks per node, got %v", table)
+	}
+	if walk := table.Get(1, "walk").([]int); len(walk) != 5 || walk[0] != 1 {
+		t.Error("Expected walk from the node, got ", walk)
+	}
+}
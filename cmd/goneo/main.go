@@ -4,8 +4,14 @@ Flags:
 
 	-size=[small|big|universe]
 	-bind=:7474
+	-timeout=30s
+	-max-rows=0
+	-max-intermediate=0
+	-max-memory=0
 	-version
 
+Limits of 0 do not restrict queries.
+
 Sizes:
 
 	* small: Three-node cluster
@@ -18,8 +24,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/BuJo/goneo"
+	_ "github.com/BuJo/goneo/algo"
 	"github.com/BuJo/goneo/data"
 	"github.com/BuJo/goneo/web"
 )
@@ -29,6 +37,11 @@ var (
 	size    = flag.String("size", "small", "Size of generated graph")
 	version = flag.Bool("version", false, "Print version information")
 
+	timeout         = flag.Duration("timeout", 30*time.Second, "Maximum time per query")
+	maxRows         = flag.Int("max-rows", 0, "Maximum rows returned per query")
+	maxIntermediate = flag.Int("max-intermediate", 0, "Maximum intermediate rows per query")
+	maxMemory       = flag.Int64("max-memory", 0, "Maximum estimated bytes per query")
+
 	buildversion = "SNAPSHOT"
 )
 
@@ -51,7 +64,12 @@ func main() {
 		db = data.NewSmallGenerator(db).Generate()
 	}
 
-	server := web.NewGoneoServer(db)
+	server := web.NewGoneoServer(db, web.WithLimits(goneo.Limits{
+		MaxRows:         *maxRows,
+		MaxIntermediate: *maxIntermediate,
+		MaxMemory:       *maxMemory,
+		Timeout:         *timeout,
+	}))
 
 	if port := os.Getenv("PORT"); port != "" {
 		*binding = ":" + port
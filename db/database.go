@@ -1,6 +1,8 @@
 // Package db is the basic contract package for a database handled by goneo.
 package db
 
+import "iter"
+
 type Node interface {
 	Id() int
 	String() string
@@ -35,7 +37,8 @@ type DatabaseService interface {
 	GetRelation(id int) (Relation, error)
 	GetAllRelations() []Relation
 
-	FindPath(start, end Node) Path
+	// FindPath produces all paths from start to end allowed by the options.
+	FindPath(start, end Node, opts PathOptions) iter.Seq[Path]
 
 	FindNodeByProperty(prop, value string) []Node
 
@@ -3,6 +3,7 @@ package file
 import (
 	"encoding/binary"
 	"errors"
+	"iter"
 	"sync"
 	"sync/atomic"
 
@@ -300,9 +301,12 @@ func (db *filedb) createEdge(a, b *node) (*edge, error) {
 }
 
 func (db *filedb) GetAllRelations() []Relation                  { return nil }
-func (db *filedb) FindPath(start, end Node) Path                { return nil }
 func (db *filedb) FindNodeByProperty(prop, value string) []Node { return nil }
 
+func (db *filedb) FindPath(start, end Node, opts PathOptions) iter.Seq[Path] {
+	return FindPaths(start, end, opts)
+}
+
 func (db *filedb) Close() {
 	_ = db.saveNodes()
 }
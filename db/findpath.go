@@ -0,0 +1,117 @@
+package db
+
+import "iter"
+
+// FindPaths produces all paths from start to end, depth first. Cycles are
+// never followed twice, either nodes or relations are unique within a path
+// depending on the options.
+func FindPaths(start, end Node, opts PathOptions) iter.Seq[Path] {
+	return func(yield func(Path) bool) {
+		search := &pathSearch{
+			end:     end,
+			opts:    opts,
+			visited: map[int]bool{start.Id(): true},
+			used:    make(map[int]bool),
+			stack:   []*pathFrame{{node: start, rels: start.Relations(opts.Direction)}},
+		}
+
+		if start.Id() == end.Id() {
+			if !yield(NewPathBuilder(start).Build()) || opts.Uniqueness == NodeUniqueness {
+				return
+			}
+		}
+
+		for path := range search.next {
+			if !yield(pathOf(start, path)) {
+				return
+			}
+		}
+	}
+}
+
+// FirstPath returns the first of the paths, or nil if there is none.
+func FirstPath(paths iter.Seq[Path]) Path {
+	for path := range paths {
+		return path
+	}
+	return nil
+}
+
+// pathSearch is an iterative depth first search, so long paths do not
+// exhaust the stack.
+type pathSearch struct {
+	end  Node
+	opts PathOptions
+
+	visited map[int]bool
+	used    map[int]bool
+	stack   []*pathFrame
+	rels    []Relation
+}
+
+// pathFrame is a node on the current path and its relations left to follow.
+type pathFrame struct {
+	node Node
+	rels []Relation
+	next int
+}
+
+// next produces the relations of every path reaching the end.
+func (s *pathSearch) next(yield func([]Relation) bool) {
+	for len(s.stack) > 0 {
+		top := s.stack[len(s.stack)-1]
+
+		if top.next == len(top.rels) || (s.opts.MaxDepth > 0 && len(s.rels) >= s.opts.MaxDepth) {
+			s.backtrack()
+			continue
+		}
+
+		rel := top.rels[top.next]
+		top.next += 1
+
+		if !s.opts.Follows(rel) || s.used[rel.Id()] {
+			continue
+		}
+		o := otherNode(rel, top.node)
+		if s.opts.Uniqueness == NodeUniqueness && s.visited[o.Id()] {
+			continue
+		}
+
+		s.rels = append(s.rels, rel)
+		if o.Id() == s.end.Id() {
+			if !yield(s.rels) {
+				return
+			}
+			// No simple path leaves the end and comes back
+			if s.opts.Uniqueness == NodeUniqueness {
+				s.rels = s.rels[:len(s.rels)-1]
+				continue
+			}
+		}
+
+		s.used[rel.Id()] = true
+		s.visited[o.Id()] = true
+		s.stack = append(s.stack, &pathFrame{node: o, rels: o.Relations(s.opts.Direction)})
+	}
+}
+
+// backtrack leaves the last node of the current path.
+func (s *pathSearch) backtrack() {
+	top := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	delete(s.visited, top.node.Id())
+
+	if len(s.rels) > 0 {
+		delete(s.used, s.rels[len(s.rels)-1].Id())
+		s.rels = s.rels[:len(s.rels)-1]
+	}
+}
+
+// pathOf builds the path following the relations from start.
+func pathOf(start Node, rels []Relation) Path {
+	builder := NewPathBuilder(start)
+	for _, rel := range rels {
+		builder = builder.Append(rel)
+	}
+	return builder.Build()
+}
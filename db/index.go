@@ -0,0 +1,20 @@
+package db
+
+// LabelIndex is implemented by databases able to look up nodes by label
+// without scanning all nodes.
+type LabelIndex interface {
+	FindNodesByLabel(label string) []Node
+}
+
+// Statistics is implemented by databases able to cheaply count their
+// contents. Query planning uses it to estimate how selective a part of a
+// pattern is.
+type Statistics interface {
+	CountNodes() int
+	CountNodesByLabel(label string) int
+	CountNodesByProperty(prop, value string) int
+
+	// CountRelations counts relations of the given type, all relations for
+	// the empty type.
+	CountRelations(relType string) int
+}
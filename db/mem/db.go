@@ -4,6 +4,7 @@ package mem
 import (
 	"errors"
 	"fmt"
+	"iter"
 	"sort"
 
 	. "github.com/BuJo/goneo/db"
@@ -12,6 +13,11 @@ import (
 type databaseService struct {
 	nodes         []Node
 	relationships []Relation
+
+	// indexes
+	labels        map[string][]Node
+	properties    map[string]map[string][]Node
+	relationTypes map[string]int
 }
 
 // NewDb creates a DB instance of a simple memory backed graph DB
@@ -21,12 +27,20 @@ func NewDb(name string, options map[string][]string) (DatabaseService, error) {
 	db.nodes = make([]Node, 0)
 	db.relationships = make([]Relation, 0)
 
+	db.labels = make(map[string][]Node)
+	db.properties = make(map[string]map[string][]Node)
+	db.relationTypes = make(map[string]int)
+
 	return db, nil
 }
 
 func (db *databaseService) Close() {
 	db.nodes = nil
 	db.relationships = nil
+
+	db.labels = nil
+	db.properties = nil
+	db.relationTypes = nil
 }
 
 func (db *databaseService) NewNode(labels ...string) Node {
@@ -41,13 +55,20 @@ func (db *databaseService) NewNode(labels ...string) Node {
 	n.labels = make([]string, 0, 1)
 	n.labels = append(n.labels, labels...)
 
+	for _, label := range n.labels {
+		db.labels[label] = append(db.labels[label], n)
+	}
+
 	return n
 }
 
-func (db *databaseService) createRelation(a, b Node) *relation {
+func (db *databaseService) createRelation(a, b Node, relType string) *relation {
 	r := new(relation)
 	r.start = a
 	r.end = b
+	r.typ = relType
+
+	db.relationTypes[relType] += 1
 
 	db.relationships = append(db.relationships, r)
 	r.id = len(db.relationships) - 1
@@ -77,47 +98,60 @@ func (db *databaseService) GetAllRelations() []Relation {
 	return db.relationships
 }
 
-func (db *databaseService) FindPath(start, end Node) Path {
-
-	builder := NewPathBuilder(start)
-
-	for _, rel := range start.Relations(Outgoing) {
-		builder, done := findPathRec(builder.Append(rel), end)
-		if done {
-			return builder.Build()
-		}
+func (db *databaseService) FindPath(start, end Node, opts PathOptions) iter.Seq[Path] {
+	return FindPaths(start, end, opts)
+}
 
-	}
+func (db *databaseService) FindNodeByProperty(prop, value string) []Node {
+	found := make([]Node, 0)
 
-	return nil
+	return append(found, db.properties[prop][value]...)
 }
 
-func findPathRec(builder *PathBuilder, end Node) (b *PathBuilder, done bool) {
-	start := builder.Last()
-
-	if start.Id() == end.Id() {
-		return builder, true
+// indexProperty moves a node in the property index from its old to its new
+// value.
+func (db *databaseService) indexProperty(n *node, prop string, old string, hadOld bool, value string) {
+	values, ok := db.properties[prop]
+	if !ok {
+		values = make(map[string][]Node)
+		db.properties[prop] = values
 	}
 
-	for _, rel := range start.Relations(Outgoing) {
-		builder, done := findPathRec(builder.Append(rel), end)
-		if done {
-			return builder, done
-
+	if hadOld {
+		nodes := values[old]
+		for i, o := range nodes {
+			if o == Node(n) {
+				values[old] = append(nodes[:i:i], nodes[i+1:]...)
+				break
+			}
 		}
 	}
 
-	return builder, false
+	nodes := values[value]
+	i := sort.Search(len(nodes), func(i int) bool { return nodes[i].Id() >= n.id })
+	nodes = append(nodes, nil)
+	copy(nodes[i+1:], nodes[i:])
+	nodes[i] = n
+	values[value] = nodes
 }
 
-func (db *databaseService) FindNodeByProperty(prop, value string) []Node {
+func (db *databaseService) FindNodesByLabel(label string) []Node {
 	found := make([]Node, 0)
 
-	for _, node := range db.nodes {
-		if node.HasProperty(prop) && node.Property(prop) == value {
-			found = append(found, node)
-		}
-	}
+	return append(found, db.labels[label]...)
+}
+
+func (db *databaseService) CountNodes() int { return len(db.nodes) }
+
+func (db *databaseService) CountNodesByLabel(label string) int { return len(db.labels[label]) }
 
-	return found
+func (db *databaseService) CountNodesByProperty(prop, value string) int {
+	return len(db.properties[prop][value])
+}
+
+func (db *databaseService) CountRelations(relType string) int {
+	if relType == "" {
+		return len(db.relationships)
+	}
+	return db.relationTypes[relType]
 }
@@ -2,7 +2,9 @@ package mem
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
+	"slices"
 	"testing"
 
 	. "github.com/BuJo/goneo/db"
@@ -23,7 +25,7 @@ func ExampleNewDb() {
 
 	fmt.Println("relation: ", relAB)
 
-	path := db.FindPath(nodeA, nodeB)
+	path := FirstPath(db.FindPath(nodeA, nodeB, PathOptions{Direction: Outgoing}))
 
 	fmt.Println("path: ", path)
 
@@ -31,7 +33,7 @@ func ExampleNewDb() {
 
 	nodeB.RelateTo(nodeC, "BELONGS_TO")
 
-	path = db.FindPath(nodeA, nodeC)
+	path = FirstPath(db.FindPath(nodeA, nodeC, PathOptions{Direction: Outgoing}))
 
 	fmt.Println("path: ", path)
 	// Output:
@@ -137,7 +139,7 @@ func TestPathFinding(t *testing.T) {
 	nodeA.RelateTo(nodeB, "BELONGS_TO")
 	nodeB.RelateTo(nodeC, "BELONGS_TO")
 
-	path := db.FindPath(nodeA, nodeC)
+	path := FirstPath(db.FindPath(nodeA, nodeC, PathOptions{Direction: Outgoing}))
 
 	if len(path.Nodes()) != 3 {
 		t.Error("Should have 3 nodes in path")
@@ -157,13 +159,290 @@ func TestFailedPathFinding(t *testing.T) {
 	nodeA.RelateTo(nodeB, "BELONGS_TO")
 	nodeA.RelateTo(nodeC, "BELONGS_TO")
 
-	path := db.FindPath(nodeB, nodeC)
+	path := FirstPath(db.FindPath(nodeB, nodeC, PathOptions{Direction: Outgoing}))
 
 	if path != nil {
 		t.Error("Should not have found path")
 	}
 }
 
+func TestFindPathCycles(t *testing.T) {
+	db, _ := NewDb("test", nil)
+
+	a, b, c := db.NewNode(), db.NewNode(), db.NewNode()
+	a.RelateTo(b, "ARCS_TO")
+	b.RelateTo(a, "ARCS_TO")
+	b.RelateTo(c, "NEXT")
+
+	path := FirstPath(db.FindPath(a, c, PathOptions{Direction: Outgoing}))
+	if path == nil || len(path.Relations()) != 2 {
+		t.Error("Should find the path past the cycle, got ", path)
+	}
+
+	path = FirstPath(db.FindPath(c, a, PathOptions{Direction: Outgoing}))
+	if path != nil {
+		t.Error("Should not find a path against directions, got ", path)
+	}
+
+	path = FirstPath(db.FindPath(c, a, PathOptions{Direction: Incoming}))
+	if path == nil || len(path.Relations()) != 2 {
+		t.Error("Should follow incoming relations, got ", path)
+	}
+
+	// A cycle leads back to the start only if nodes may repeat
+	cycles := slices.Collect(db.FindPath(a, a, PathOptions{Direction: Outgoing}))
+	if len(cycles) != 1 || len(cycles[0].Relations()) != 0 {
+		t.Error("Should only find the empty path, got ", cycles)
+	}
+	cycles = slices.Collect(db.FindPath(a, a, PathOptions{Direction: Outgoing, Uniqueness: RelationshipUniqueness}))
+	if len(cycles) != 2 || len(cycles[1].Relations()) != 2 {
+		t.Error("Should find the cycle, got ", cycles)
+	}
+}
+
+func TestFindAllPaths(t *testing.T) {
+	db, _ := NewDb("test", nil)
+
+	nodes := make([]Node, 5)
+	for i := range nodes {
+		nodes[i] = db.NewNode()
+	}
+
+	// two ways from 0 to 3 and back again
+	nodes[0].RelateTo(nodes[1], "NEXT")
+	nodes[1].RelateTo(nodes[3], "NEXT")
+	nodes[0].RelateTo(nodes[2], "NEXT")
+	nodes[2].RelateTo(nodes[3], "SHORTCUT")
+	nodes[3].RelateTo(nodes[0], "BACK")
+	nodes[3].RelateTo(nodes[4], "NEXT")
+
+	paths := slices.Collect(db.FindPath(nodes[0], nodes[3], PathOptions{Direction: Outgoing}))
+	if len(paths) != 2 {
+		t.Error("Should find both paths, got ", paths)
+	}
+
+	paths = slices.Collect(db.FindPath(nodes[0], nodes[3], PathOptions{Direction: Outgoing, Types: []string{"NEXT"}}))
+	if len(paths) != 1 {
+		t.Error("Should only follow the allowed types, got ", paths)
+	}
+
+	paths = slices.Collect(db.FindPath(nodes[0], nodes[3], PathOptions{}))
+	if len(paths) != 3 {
+		t.Error("Should find the paths in any direction, got ", paths)
+	}
+
+	paths = slices.Collect(db.FindPath(nodes[0], nodes[4], PathOptions{Direction: Outgoing, MaxDepth: 2}))
+	if len(paths) != 0 {
+		t.Error("Should not find paths deeper than allowed, got ", paths)
+	}
+
+	for path := range db.FindPath(nodes[0], nodes[4], PathOptions{}) {
+		seen := make(map[int]bool)
+		for _, n := range path.Nodes() {
+			if seen[n.Id()] {
+				t.Error("Should only find simple paths, got ", path)
+			}
+			seen[n.Id()] = true
+		}
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	db, _ := NewDb("test", nil)
+
+	nodes := make([]Node, 6)
+	for i := range nodes {
+		nodes[i] = db.NewNode()
+	}
+
+	// long way round, a shortcut and a cycle
+	nodes[0].RelateTo(nodes[1], "NEXT")
+	nodes[1].RelateTo(nodes[2], "NEXT")
+	nodes[2].RelateTo(nodes[3], "NEXT")
+	nodes[3].RelateTo(nodes[4], "NEXT")
+	nodes[4].RelateTo(nodes[0], "NEXT")
+	nodes[1].RelateTo(nodes[3], "SHORTCUT")
+	nodes[4].RelateTo(nodes[5], "NEXT")
+
+	path := ShortestPath(nodes[0], nodes[4], PathOptions{Direction: Outgoing})
+	if path == nil || len(path.Relations()) != 3 {
+		t.Fatal("Should find the shortcut, got ", path)
+	}
+	if ns := path.Nodes(); ns[0] != nodes[0] || ns[1] != nodes[1] || ns[2] != nodes[3] || ns[3] != nodes[4] {
+		t.Error("Should take the shortcut, got ", path)
+	}
+
+	path = ShortestPath(nodes[0], nodes[4], PathOptions{Direction: Outgoing, Types: []string{"NEXT"}})
+	if path == nil || len(path.Relations()) != 4 {
+		t.Error("Should avoid the shortcut, got ", path)
+	}
+
+	path = ShortestPath(nodes[0], nodes[4], PathOptions{})
+	if path == nil || len(path.Relations()) != 1 {
+		t.Fatal("Should follow relations backwards, got ", path)
+	}
+	if ns := path.Nodes(); ns[0] != nodes[0] || ns[1] != nodes[4] {
+		t.Error("Path should lead from start to end, got ", path)
+	}
+
+	path = ShortestPath(nodes[0], nodes[5], PathOptions{Direction: Outgoing, MaxDepth: 3})
+	if path != nil {
+		t.Error("Should not find paths deeper than allowed, got ", path)
+	}
+
+	path = ShortestPath(nodes[5], nodes[0], PathOptions{Direction: Outgoing})
+	if path != nil {
+		t.Error("Should not find a path against directions, got ", path)
+	}
+}
+
+func TestAllShortestPaths(t *testing.T) {
+	db, _ := NewDb("test", nil)
+
+	// a diamond of diamonds has four shortest paths
+	a, b, c, d, e, f, g := db.NewNode(), db.NewNode(), db.NewNode(), db.NewNode(), db.NewNode(), db.NewNode(), db.NewNode()
+	a.RelateTo(b, "R")
+	a.RelateTo(c, "R")
+	b.RelateTo(d, "R")
+	c.RelateTo(d, "R")
+	d.RelateTo(e, "R")
+	d.RelateTo(f, "R")
+	e.RelateTo(g, "R")
+	f.RelateTo(g, "R")
+	a.RelateTo(g, "LONG")
+	g.RelateTo(a, "R")
+
+	paths := AllShortestPaths(a, g, PathOptions{Direction: Outgoing, Types: []string{"R"}})
+	if len(paths) != 4 {
+		t.Fatal("Should find 4 paths, found ", len(paths))
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if len(path.Relations()) != 4 {
+			t.Error("Path should be shortest, got ", path)
+		}
+		if ns := path.Nodes(); ns[0] != a || ns[len(ns)-1] != g {
+			t.Error("Path should lead from start to end, got ", path)
+		}
+		seen[path.String()] = true
+	}
+	if len(seen) != 4 {
+		t.Error("Paths should differ")
+	}
+
+	if paths := AllShortestPaths(a, a, PathOptions{}); len(paths) != 1 || len(paths[0].Relations()) != 0 {
+		t.Error("Should find the empty path to itself, got ", paths)
+	}
+}
+
+// weightedGraph builds a small road network:
+//
+//	a -1- b -1- c
+//	|           |
+//	4           1
+//	|           |
+//	d ----5---- e -1- f
+func weightedGraph() (DatabaseService, map[string]Node) {
+	db, _ := NewDb("test", nil)
+
+	nodes := make(map[string]Node)
+	for i, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		nodes[name] = db.NewNode()
+		nodes[name].SetProperty("name", name)
+		nodes[name].SetProperty("x", fmt.Sprint([]int{0, 1, 2, 0, 2, 3}[i]))
+		nodes[name].SetProperty("y", fmt.Sprint([]int{0, 0, 0, 1, 1, 1}[i]))
+	}
+
+	road := func(from, to string, cost interface{}) {
+		nodes[from].RelateTo(nodes[to], "ROAD").SetProperty("cost", cost)
+	}
+	road("a", "b", 1)
+	road("b", "c", 1.0)
+	road("c", "e", "1")
+	road("a", "d", 4)
+	road("d", "e", 5)
+	road("e", "f", 1)
+
+	return db, nodes
+}
+
+func TestDijkstra(t *testing.T) {
+	_, nodes := weightedGraph()
+
+	path, err := Dijkstra(nodes["a"], nodes["f"], PropertyWeight("cost", 1), PathOptions{Direction: Outgoing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path == nil || path.Cost != 4 || len(path.Relations()) != 4 {
+		t.Fatal("Should take the way via b and c, got ", path)
+	}
+	if ns := path.Nodes(); ns[0] != nodes["a"] || ns[2] != nodes["c"] || ns[4] != nodes["f"] {
+		t.Error("Should lead from a via c to f, got ", path)
+	}
+
+	path, err = Dijkstra(nodes["f"], nodes["a"], PropertyWeight("cost", 1), PathOptions{Direction: Outgoing})
+	if err != nil || path != nil {
+		t.Error("Should not find a path against directions, got ", path, err)
+	}
+
+	path, err = Dijkstra(nodes["a"], nodes["f"], PropertyWeight("missing", 1), PathOptions{})
+	if err != nil || path == nil || path.Cost != 3 {
+		t.Error("Should count missing weights as default, got ", path, err)
+	}
+
+	_, err = Dijkstra(nodes["a"], nodes["f"], func(Relation) float64 { return -1 }, PathOptions{})
+	if err != ErrNegativeWeight {
+		t.Error("Should reject negative weights, got ", err)
+	}
+}
+
+func TestAStar(t *testing.T) {
+	_, nodes := weightedGraph()
+
+	coordinate := func(n Node, prop string) float64 {
+		var f float64
+		fmt.Sscan(n.Property(prop).(string), &f)
+		return f
+	}
+	end := nodes["f"]
+	euclidean := func(n Node) float64 {
+		dx, dy := coordinate(n, "x")-coordinate(end, "x"), coordinate(n, "y")-coordinate(end, "y")
+		return math.Sqrt(dx*dx + dy*dy)
+	}
+
+	path, err := AStar(nodes["a"], end, PropertyWeight("cost", 1), euclidean, PathOptions{Direction: Outgoing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path == nil || path.Cost != 4 {
+		t.Error("Should find the cheapest path, got ", path)
+	}
+}
+
+func TestKShortestPaths(t *testing.T) {
+	_, nodes := weightedGraph()
+
+	paths, err := KShortestPaths(nodes["a"], nodes["f"], 3, PropertyWeight("cost", 1), PathOptions{Direction: Outgoing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatal("Should find both paths, got ", paths)
+	}
+	if paths[0].Cost != 4 || paths[1].Cost != 10 {
+		t.Error("Should order paths by cost, got ", paths)
+	}
+
+	paths, err = KShortestPaths(nodes["a"], nodes["f"], 3, PropertyWeight("cost", 1), PathOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Error("Undirected paths have to stay simple, got ", paths)
+	}
+}
+
 func TestNodeProperties(t *testing.T) {
 	db, _ := NewDb("test", nil)
 
@@ -230,6 +509,38 @@ func TestNodeRelating(t *testing.T) {
 	}
 }
 
+func TestIndexes(t *testing.T) {
+	db, _ := NewDb("test", nil)
+
+	human := db.NewNode("Human")
+	human.SetProperty("name", "Arthur")
+	robot := db.NewNode("Robot")
+	robot.SetProperty("name", "Marvin")
+	human.RelateTo(robot, "KNOWS")
+	robot.RelateTo(human, "IGNORES")
+	robot.RelateTo(robot, "KNOWS")
+
+	if nodes := db.(LabelIndex).FindNodesByLabel("Robot"); len(nodes) != 1 || nodes[0].Id() != robot.Id() {
+		t.Error("Should find robot by label")
+	}
+
+	stats := db.(Statistics)
+	if stats.CountNodes() != 2 || stats.CountNodesByLabel("Human") != 1 || stats.CountNodesByLabel("Alien") != 0 {
+		t.Error("Should count nodes by label")
+	}
+	if stats.CountRelations("") != 3 || stats.CountRelations("KNOWS") != 2 {
+		t.Error("Should count relations by type")
+	}
+
+	robot.SetProperty("name", "Arthur")
+	if stats.CountNodesByProperty("name", "Marvin") != 0 || stats.CountNodesByProperty("name", "Arthur") != 2 {
+		t.Error("Property index should follow changes")
+	}
+	if nodes := db.FindNodeByProperty("name", "Arthur"); len(nodes) != 2 || nodes[0].Id() != human.Id() {
+		t.Error("Should find nodes by property in id order")
+	}
+}
+
 type mocknode struct{ name string }
 type mockrel struct{ start, end Node }
 
@@ -313,6 +624,6 @@ func BenchmarkPathFinding(b *testing.B) {
 		x, _ = db.GetNode(rand.Intn(maxNodes))
 		y, _ = db.GetNode(rand.Intn(maxNodes))
 
-		db.FindPath(x, y)
+		FirstPath(db.FindPath(x, y, PathOptions{Direction: Outgoing}))
 	}
 }
@@ -41,7 +41,10 @@ func (n *node) SetProperty(name, val string) {
 	if n.properties == nil {
 		n.properties = make(map[string]string)
 	}
+	old, hadOld := n.properties[name]
 	n.properties[name] = val
+
+	n.db.indexProperty(n, name, old, hadOld, val)
 }
 func (n *node) Properties() map[string]string {
 	return n.properties
@@ -83,8 +86,7 @@ func (n *node) RelateTo(endI Node, relType string) Relation {
 		}
 	}
 
-	rel := n.db.createRelation(n, end)
-	rel.typ = relType
+	rel := n.db.createRelation(n, end, relType)
 
 	n.relations = append(n.relations, rel)
 	end.relations = append(end.relations, rel)
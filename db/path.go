@@ -34,8 +34,10 @@ func (path *simplePath) Nodes() (nodes []Node) {
 		return
 	}
 
+	left := path.start
 	for _, rel := range path.relations {
-		nodes = append(nodes, rel.End())
+		left = otherNode(rel, left)
+		nodes = append(nodes, left)
 	}
 
 	return
@@ -55,14 +57,25 @@ func (path *simplePath) Items() []PropertyContainer {
 		return items
 	}
 
+	left := path.start
 	for _, rel := range path.relations {
+		left = otherNode(rel, left)
 		items = append(items, rel)
-		items = append(items, rel.End())
+		items = append(items, left)
 	}
 
 	return items
 }
 
+// otherNode returns the node at the other side of the relation, relations
+// may be traversed against their direction.
+func otherNode(rel Relation, n Node) Node {
+	if rel.Start() == n {
+		return rel.End()
+	}
+	return rel.Start()
+}
+
 func (path *simplePath) String() (str string) {
 	str = path.start.String()
 	left := path.start
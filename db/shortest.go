@@ -0,0 +1,209 @@
+package db
+
+// PathOptions restricts the relations a path may follow.
+type PathOptions struct {
+	// Direction of the relations seen from the start node, Both by default.
+	Direction Direction
+	// Types of relations to follow, all types if empty.
+	Types []string
+	// MaxDepth is the maximum number of relations of a path, 0 does not
+	// limit the depth.
+	MaxDepth int
+	// Uniqueness decides what may not repeat within a path, NodeUniqueness
+	// by default.
+	Uniqueness Uniqueness
+}
+
+// Uniqueness decides which paths are considered while searching.
+type Uniqueness int
+
+const (
+	// NodeUniqueness visits every node at most once, only finding simple
+	// paths.
+	NodeUniqueness Uniqueness = iota
+	// RelationshipUniqueness follows every relation at most once, allowing
+	// paths to pass a node repeatedly.
+	RelationshipUniqueness
+)
+
+// Follows reports whether a relation may be part of a path.
+func (opts PathOptions) Follows(rel Relation) bool {
+	if len(opts.Types) == 0 {
+		return true
+	}
+	for _, t := range opts.Types {
+		if rel.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Reverse returns the opposite direction, Both stays Both.
+func (d Direction) Reverse() Direction {
+	switch d {
+	case Incoming:
+		return Outgoing
+	case Outgoing:
+		return Incoming
+	}
+	return d
+}
+
+// ShortestPath finds a path with the fewest relations between start and end.
+// It returns nil if there is no such path.
+func ShortestPath(start, end Node, opts PathOptions) Path {
+	paths := shortestPaths(start, end, opts, false)
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths[0]
+}
+
+// AllShortestPaths finds all paths with the fewest relations between start
+// and end.
+func AllShortestPaths(start, end Node, opts PathOptions) []Path {
+	return shortestPaths(start, end, opts, true)
+}
+
+// shortestPaths runs a bidirectional breadth-first search, always expanding
+// the smaller frontier, until both searches meet.
+func shortestPaths(start, end Node, opts PathOptions, all bool) []Path {
+	if start.Id() == end.Id() {
+		return []Path{NewPathBuilder(start).Build()}
+	}
+
+	forward := newSearchSide(start, opts.Direction)
+	backward := newSearchSide(end, opts.Direction.Reverse())
+
+	for len(forward.frontier) > 0 && len(backward.frontier) > 0 {
+		if opts.MaxDepth > 0 && forward.depth+backward.depth >= opts.MaxDepth {
+			return nil
+		}
+
+		side, other := forward, backward
+		if len(backward.frontier) < len(forward.frontier) {
+			side, other = backward, forward
+		}
+
+		meets := side.expand(opts, other)
+		if len(meets) == 0 {
+			continue
+		}
+
+		// Only nodes on a shortest path connect the halves
+		length := -1
+		for _, m := range meets {
+			if l := forward.dist[m.Id()] + backward.dist[m.Id()]; length < 0 || l < length {
+				length = l
+			}
+		}
+
+		paths := make([]Path, 0)
+		for _, m := range meets {
+			if forward.dist[m.Id()]+backward.dist[m.Id()] != length {
+				continue
+			}
+
+			for _, head := range forward.routes(m, all) {
+				for _, tail := range backward.routes(m, all) {
+					builder := NewPathBuilder(start)
+					for i := len(head) - 1; i >= 0; i-- {
+						builder = builder.Append(head[i])
+					}
+					for _, rel := range tail {
+						builder = builder.Append(rel)
+					}
+					paths = append(paths, builder.Build())
+
+					if !all {
+						return paths
+					}
+				}
+			}
+		}
+		return paths
+	}
+
+	return nil
+}
+
+// searchSide is one direction of a bidirectional breadth-first search.
+type searchSide struct {
+	direction Direction
+
+	dist     map[int]int
+	preds    map[int][]searchStep
+	frontier []Node
+	depth    int
+}
+
+// searchStep remembers how a node was reached.
+type searchStep struct {
+	rel  Relation
+	prev Node
+}
+
+func newSearchSide(root Node, direction Direction) *searchSide {
+	return &searchSide{
+		direction: direction,
+		dist:      map[int]int{root.Id(): 0},
+		preds:     make(map[int][]searchStep),
+		frontier:  []Node{root},
+	}
+}
+
+// expand visits the next level of nodes and returns those already visited
+// by the other side.
+func (s *searchSide) expand(opts PathOptions, other *searchSide) []Node {
+	s.depth += 1
+
+	next := make([]Node, 0)
+	meets := make([]Node, 0)
+	for _, n := range s.frontier {
+		for _, rel := range n.Relations(s.direction) {
+			if !opts.Follows(rel) {
+				continue
+			}
+
+			o := rel.End()
+			if o.Id() == n.Id() {
+				o = rel.Start()
+			}
+
+			if d, seen := s.dist[o.Id()]; !seen {
+				s.dist[o.Id()] = s.depth
+				next = append(next, o)
+				if _, met := other.dist[o.Id()]; met {
+					meets = append(meets, o)
+				}
+			} else if d != s.depth {
+				// reached earlier on a shorter route
+				continue
+			}
+			s.preds[o.Id()] = append(s.preds[o.Id()], searchStep{rel, n})
+		}
+	}
+	s.frontier = next
+
+	return meets
+}
+
+// routes returns the relations leading from n back to the root of the side,
+// all of them or only the first one.
+func (s *searchSide) routes(n Node, all bool) [][]Relation {
+	if s.dist[n.Id()] == 0 {
+		return [][]Relation{nil}
+	}
+
+	routes := make([][]Relation, 0)
+	for _, step := range s.preds[n.Id()] {
+		for _, route := range s.routes(step.prev, all) {
+			routes = append(routes, append([]Relation{step.rel}, route...))
+			if !all {
+				return routes
+			}
+		}
+	}
+	return routes
+}
@@ -3,6 +3,7 @@ package simplefile
 
 import (
 	"encoding/binary"
+	"iter"
 	"os"
 
 	. "github.com/BuJo/goneo/db"
@@ -39,12 +40,29 @@ func (db *databaseService) GetAllNodes() []Node          { return db.mem.GetAllN
 func (db *databaseService) GetRelation(id int) (Relation, error) { return db.mem.GetRelation(id) }
 func (db *databaseService) GetAllRelations() []Relation          { return db.mem.GetAllRelations() }
 
-func (db *databaseService) FindPath(start, end Node) Path { return db.mem.FindPath(start, end) }
+func (db *databaseService) FindPath(start, end Node, opts PathOptions) iter.Seq[Path] {
+	return db.mem.FindPath(start, end, opts)
+}
 
 func (db *databaseService) FindNodeByProperty(prop, value string) []Node {
 	return db.mem.FindNodeByProperty(prop, value)
 }
 
+func (db *databaseService) FindNodesByLabel(label string) []Node {
+	return db.mem.(LabelIndex).FindNodesByLabel(label)
+}
+
+func (db *databaseService) CountNodes() int { return db.mem.(Statistics).CountNodes() }
+func (db *databaseService) CountNodesByLabel(label string) int {
+	return db.mem.(Statistics).CountNodesByLabel(label)
+}
+func (db *databaseService) CountNodesByProperty(prop, value string) int {
+	return db.mem.(Statistics).CountNodesByProperty(prop, value)
+}
+func (db *databaseService) CountRelations(relType string) int {
+	return db.mem.(Statistics).CountRelations(relType)
+}
+
 func (db *databaseService) Close() {
 	log.Print("Saving to " + db.filename + ".tmp")
 	err := saveDbFile(db.filename+".tmp", db.mem)
@@ -24,7 +24,7 @@ func ExampleNewDb() {
 
 	fmt.Println("relation: ", relAB)
 
-	path := db.FindPath(nodeA, nodeB)
+	path := FirstPath(db.FindPath(nodeA, nodeB, PathOptions{Direction: Outgoing}))
 
 	fmt.Println("path: ", path)
 
@@ -32,7 +32,7 @@ func ExampleNewDb() {
 
 	nodeB.RelateTo(nodeC, "BELONGS_TO")
 
-	path = db.FindPath(nodeA, nodeC)
+	path = FirstPath(db.FindPath(nodeA, nodeC, PathOptions{Direction: Outgoing}))
 
 	fmt.Println("path: ", path)
 	// Output:
@@ -138,7 +138,7 @@ func TestPathFinding(t *testing.T) {
 	nodeA.RelateTo(nodeB, "BELONGS_TO")
 	nodeB.RelateTo(nodeC, "BELONGS_TO")
 
-	path := db.FindPath(nodeA, nodeC)
+	path := FirstPath(db.FindPath(nodeA, nodeC, PathOptions{Direction: Outgoing}))
 
 	if len(path.Nodes()) != 3 {
 		t.Error("Should have 3 nodes in path")
@@ -158,7 +158,7 @@ func TestFailedPathFinding(t *testing.T) {
 	nodeA.RelateTo(nodeB, "BELONGS_TO")
 	nodeA.RelateTo(nodeC, "BELONGS_TO")
 
-	path := db.FindPath(nodeB, nodeC)
+	path := FirstPath(db.FindPath(nodeB, nodeC, PathOptions{Direction: Outgoing}))
 
 	if path != nil {
 		t.Error("Should not have found path")
@@ -314,7 +314,7 @@ func BenchmarkPathFinding(b *testing.B) {
 		x, _ = db.GetNode(rand.Intn(maxNodes))
 		y, _ = db.GetNode(rand.Intn(maxNodes))
 
-		db.FindPath(x, y)
+		FirstPath(db.FindPath(x, y, PathOptions{Direction: Outgoing}))
 	}
 }
 
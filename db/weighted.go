@@ -0,0 +1,253 @@
+package db
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNegativeWeight is returned by weighted searches when a relation has a
+// negative cost.
+var ErrNegativeWeight = errors.New("negative relation weight")
+
+// WeightFunc returns the cost of following a relation.
+type WeightFunc func(rel Relation) float64
+
+// PropertyWeight reads the cost of a relation from a property. Relations
+// without a numeric value for the property cost defaultWeight.
+func PropertyWeight(prop string, defaultWeight float64) WeightFunc {
+	return func(rel Relation) float64 {
+		switch v := rel.Property(prop).(type) {
+		case float64:
+			return v
+		case float32:
+			return float64(v)
+		case int:
+			return float64(v)
+		case int64:
+			return float64(v)
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+		return defaultWeight
+	}
+}
+
+// WeightedPath is a path together with the sum of the weights of its
+// relations.
+type WeightedPath struct {
+	Path
+	Cost float64
+}
+
+func (p *WeightedPath) String() string {
+	return fmt.Sprintf("%s (cost %g)", p.Path, p.Cost)
+}
+
+// Dijkstra finds the cheapest path between start and end. It returns nil if
+// there is no path. Only Direction and Types of the options are used.
+func Dijkstra(start, end Node, weight WeightFunc, opts PathOptions) (*WeightedPath, error) {
+	return cheapestPath(start, end, weight, nil, opts, nil, nil)
+}
+
+// AStar finds the cheapest path between start and end, guided by a heuristic
+// estimating the remaining cost from a node to end. Nodes are not visited
+// again, so the heuristic has to be consistent for the result to be the
+// cheapest path: the estimate of a node may not exceed the weight of a
+// relation plus the estimate of the node at its other end, and is 0 at end.
+// Only Direction and Types of the options are used.
+func AStar(start, end Node, weight WeightFunc, heuristic func(Node) float64, opts PathOptions) (*WeightedPath, error) {
+	return cheapestPath(start, end, weight, heuristic, opts, nil, nil)
+}
+
+// KShortestPaths finds up to k cheapest paths without repeated nodes between
+// start and end, cheapest first, using Yen's algorithm. Only Direction and
+// Types of the options are used.
+func KShortestPaths(start, end Node, k int, weight WeightFunc, opts PathOptions) ([]*WeightedPath, error) {
+	paths := make([]*WeightedPath, 0, k)
+	if k <= 0 {
+		return paths, nil
+	}
+
+	first, err := cheapestPath(start, end, weight, nil, opts, nil, nil)
+	if err != nil || first == nil {
+		return paths, err
+	}
+	paths = append(paths, first)
+
+	candidates := make([]*WeightedPath, 0)
+	known := map[string]bool{pathKey(first): true}
+
+	for len(paths) < k {
+		previous := paths[len(paths)-1]
+		nodes, rels := previous.Nodes(), previous.Relations()
+
+		for i := 0; i < len(rels); i++ {
+			spur, root := nodes[i], rels[:i]
+
+			// Do not repeat found paths sharing the same root
+			excludedRels := make(map[int]bool)
+			for _, p := range paths {
+				if prefixOf(root, p.Relations()) && len(p.Relations()) > i {
+					excludedRels[p.Relations()[i].Id()] = true
+				}
+			}
+			// Keep paths simple
+			excludedNodes := make(map[int]bool)
+			for _, n := range nodes[:i] {
+				excludedNodes[n.Id()] = true
+			}
+
+			spurPath, err := cheapestPath(spur, end, weight, nil, opts, excludedRels, excludedNodes)
+			if err != nil {
+				return paths, err
+			}
+			if spurPath == nil {
+				continue
+			}
+
+			builder := NewPathBuilder(start)
+			cost := spurPath.Cost
+			for _, rel := range root {
+				builder = builder.Append(rel)
+				cost += weight(rel)
+			}
+			for _, rel := range spurPath.Relations() {
+				builder = builder.Append(rel)
+			}
+
+			candidate := &WeightedPath{builder.Build(), cost}
+			if key := pathKey(candidate); !known[key] {
+				known[key] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Cost != candidates[j].Cost {
+				return candidates[i].Cost < candidates[j].Cost
+			}
+			return len(candidates[i].Relations()) < len(candidates[j].Relations())
+		})
+		paths = append(paths, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	return paths, nil
+}
+
+// cheapestPath runs A*, or Dijkstra without heuristic, ignoring the excluded
+// relations and nodes.
+func cheapestPath(start, end Node, weight WeightFunc, heuristic func(Node) float64, opts PathOptions, excludedRels, excludedNodes map[int]bool) (*WeightedPath, error) {
+	if heuristic == nil {
+		heuristic = func(Node) float64 { return 0 }
+	}
+
+	cost := map[int]float64{start.Id(): 0}
+	prev := make(map[int]searchStep)
+	done := make(map[int]bool)
+
+	queue := &searchQueue{{node: start, priority: heuristic(start)}}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(searchItem).node
+		if done[current.Id()] {
+			continue
+		}
+		done[current.Id()] = true
+
+		if current.Id() == end.Id() {
+			return &WeightedPath{buildPath(start, end, prev), cost[end.Id()]}, nil
+		}
+
+		for _, rel := range current.Relations(opts.Direction) {
+			if !opts.Follows(rel) || excludedRels[rel.Id()] {
+				continue
+			}
+
+			o := otherNode(rel, current)
+			if done[o.Id()] || excludedNodes[o.Id()] {
+				continue
+			}
+
+			w := weight(rel)
+			if w < 0 || math.IsNaN(w) {
+				return nil, ErrNegativeWeight
+			}
+
+			c := cost[current.Id()] + w
+			if known, ok := cost[o.Id()]; !ok || c < known {
+				cost[o.Id()] = c
+				prev[o.Id()] = searchStep{rel, current}
+				heap.Push(queue, searchItem{o, c + heuristic(o)})
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// buildPath follows the steps back from end to start.
+func buildPath(start, end Node, prev map[int]searchStep) Path {
+	rels := make([]Relation, 0)
+	for n := end; n.Id() != start.Id(); {
+		step := prev[n.Id()]
+		rels = append(rels, step.rel)
+		n = step.prev
+	}
+
+	builder := NewPathBuilder(start)
+	for i := len(rels) - 1; i >= 0; i-- {
+		builder = builder.Append(rels[i])
+	}
+	return builder.Build()
+}
+
+// pathKey identifies a path by its relations.
+func pathKey(path Path) string {
+	ids := make([]string, 0, len(path.Relations()))
+	for _, rel := range path.Relations() {
+		ids = append(ids, strconv.Itoa(rel.Id()))
+	}
+	return strings.Join(ids, ",")
+}
+
+func prefixOf(prefix, rels []Relation) bool {
+	if len(prefix) > len(rels) {
+		return false
+	}
+	for i, rel := range prefix {
+		if rels[i].Id() != rel.Id() {
+			return false
+		}
+	}
+	return true
+}
+
+type searchItem struct {
+	node     Node
+	priority float64
+}
+
+// searchQueue is a priority queue of nodes, lowest priority first.
+type searchQueue []searchItem
+
+func (q searchQueue) Len() int            { return len(q) }
+func (q searchQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q searchQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *searchQueue) Push(x interface{}) { *q = append(*q, x.(searchItem)) }
+func (q *searchQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
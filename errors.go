@@ -0,0 +1,167 @@
+package goneo
+
+import (
+	"fmt"
+
+	"github.com/BuJo/goneo/gcy"
+)
+
+// SemanticError describes a query which parsed but cannot be evaluated, e.g.
+// because it refers to an unknown variable or function.
+type SemanticError struct {
+	Msg string
+}
+
+func (e *SemanticError) Error() string {
+	return "semantic error: " + e.Msg
+}
+
+// EntityNotFoundError is returned when a query refers to a node or relation
+// id which does not exist.
+type EntityNotFoundError struct {
+	Kind string // node or relationship
+	Id   int
+}
+
+func (e *EntityNotFoundError) Error() string {
+	return fmt.Sprintf("%s with id %d not found", e.Kind, e.Id)
+}
+
+// validateQuery checks that a query only uses bound variables and known
+// functions.
+func validateQuery(q *gcy.Query) error {
+	bound := make(map[string]bool)
+	for _, r := range q.Roots {
+		bound[r.Name] = true
+	}
+	if q.Match != nil {
+		bound[q.Match.Cost] = q.Match.Cost != ""
+		for _, path := range q.Match.Paths {
+			if err := validatePathFunction(path); err != nil {
+				return err
+			}
+			if err := validateHops(path, q.Match.Approximate); err != nil {
+				return err
+			}
+
+			bound[path.Name] = true
+			for n := path.Start; n != nil; {
+				bound[n.Name] = true
+				if n.RightRel == nil {
+					break
+				}
+				bound[n.RightRel.Name] = true
+				n = n.RightRel.RightNode
+			}
+		}
+	}
+
+	for _, c := range q.Calls {
+		if err := validateCall(c, bound); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range q.Returns {
+		if err := validateReturnable(r, bound); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateCall checks the procedure and its arguments, binding its outputs.
+func validateCall(c *gcy.Call, bound map[string]bool) error {
+	proc, ok := lookupProcedure(c.Name)
+	if !ok {
+		return &SemanticError{fmt.Sprintf("unknown procedure `%s`", c.Name)}
+	}
+
+	if len(c.Args) < proc.required() || len(c.Args) > len(proc.Arguments) {
+		return &SemanticError{fmt.Sprintf("%s expects %d to %d arguments, got %d", c.Name, proc.required(), len(proc.Arguments), len(c.Args))}
+	}
+	for _, arg := range c.Args {
+		if err := validateReturnable(arg, bound); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Yields) == 0 {
+		for _, o := range proc.Outputs {
+			bound[o] = true
+		}
+	}
+	for _, y := range c.Yields {
+		if y.Field != "" || !proc.hasOutput(y.Object) {
+			return &SemanticError{fmt.Sprintf("%s does not yield `%s`", c.Name, y.Name)}
+		}
+		bound[y.Alias] = true
+	}
+
+	return nil
+}
+
+// validatePathFunction checks that shortest paths connect two named nodes by
+// a single relation.
+func validatePathFunction(path *gcy.Path) error {
+	if path.Function == "" {
+		return nil
+	}
+
+	rel := path.Start.RightRel
+	if rel == nil || rel.RightNode.RightRel != nil {
+		return &SemanticError{fmt.Sprintf("%s needs a single relation", path.Function)}
+	}
+	if path.Start.Name == "" || rel.RightNode.Name == "" {
+		return &SemanticError{fmt.Sprintf("%s needs named start and end nodes", path.Function)}
+	}
+	if rel.Cardinality != "" && rel.MinHops > 1 {
+		return &SemanticError{fmt.Sprintf("%s supports a minimal length of 0 or 1, got %d", path.Function, rel.MinHops)}
+	}
+
+	return nil
+}
+
+// validateHops checks the lengths of the variable length relations of a
+// path. Matches relate distinct nodes, so only path functions may find paths
+// of length 0, approximate matches only follow single relations.
+func validateHops(path *gcy.Path, approximate bool) error {
+	for n := path.Start; n.RightRel != nil; n = n.RightRel.RightNode {
+		rel := n.RightRel
+		if rel.Cardinality == "" {
+			continue
+		}
+		if approximate {
+			return &SemanticError{fmt.Sprintf("approximate matches do not support variable length relations, got %s", rel)}
+		}
+		if path.Function == "" && rel.MinHops < 1 {
+			return &SemanticError{fmt.Sprintf("a minimal length of 0 needs a path function, got %s", rel)}
+		}
+	}
+
+	return nil
+}
+
+func validateReturnable(r *gcy.Returnable, bound map[string]bool) error {
+	switch r.Type {
+	case "variable":
+		if r.Object == "" || !bound[r.Object] {
+			return &SemanticError{fmt.Sprintf("variable `%s` not defined", r.Object)}
+		}
+	case "function":
+		if r.Object != "count" {
+			return &SemanticError{fmt.Sprintf("unknown function `%s`", r.Object)}
+		}
+		if len(r.Vars) != 1 {
+			return &SemanticError{fmt.Sprintf("count expects 1 argument, got %d", len(r.Vars))}
+		}
+		for _, v := range r.Vars {
+			if err := validateReturnable(v, bound); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,20 +2,28 @@
 /*
 Language EBNF:
 
+	Statement := [ "explain" | "profile" ] Query
 	Query := SearchQuery | DeleteQuery | CreateQuery
-	SearchQuery := ( Roots [ Match ] | Match ) Returns
+	SearchQuery := ( Roots [ Match ] | Match ) { Call } Returns
 	Roots := "start" Root
-	Root := name "=" NodeOrRel "(" id ")" ["," Root]
+	Root := name "=" NodeOrRel "(" ( id | Parameter ) ")" ["," Root]
 	NodeOrRel := "node" | "relation"
 	Returns := "return" ReturnVal ["," Return]
 	Return := name ["," Return]
-	Match := "match" PathPart
-	PathPart := PathAssignment | Path
-	PathAssignment := name "=" Path
+	Match := "match" PathPart { "," PathPart } [ "using" "approximate" [ \d+ ] [ "as" name ] ]
+	PathPart := PathAssignment | Path | PathFunction
+	PathAssignment := name "=" ( Path | PathFunction )
+	PathFunction := ( "shortestPath" | "allShortestPaths" ) "(" Path ")"
 	Path := NodeRel
-	NodeRel := "(" name ")" DirectionalRel "(" name ")"
+	NodeRel := Node DirectionalRel Node
+	Node := "(" name { ":" label } [ "{" Property { "," Property } "}" ] ")"
+	Property := name ":" ( string | Parameter )
+	Parameter := "$" name
 	DirectionalRel := "<-" "[" name [":" name ] [ RelCount ] "]"
-	RelCount := "*" | \d+ [".." * | \d+]
+	RelCount := "*" [ \d+ ] [ ".." [ \d+ ] ]
+	Call := "call" name { "." name } "(" [ Argument { "," Argument } ] ")" [ Yield ]
+	Argument := name | string | number | Parameter
+	Yield := "yield" name [ "as" name ] { "," name [ "as" name ] }
 
 */
 package gcy
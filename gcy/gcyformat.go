@@ -0,0 +1,82 @@
+package gcy
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// String renders the path in gcy syntax.
+func (path *Path) String() string {
+	str := ""
+	for n := path.Start; n != nil; {
+		str += n.String()
+
+		if n.RightRel == nil {
+			break
+		}
+		str += n.RightRel.String()
+		n = n.RightRel.RightNode
+	}
+
+	if path.Function != "" {
+		str = path.Function + "(" + str + ")"
+	}
+	if path.Name != "" {
+		str = path.Name + " = " + str
+	}
+
+	return str
+}
+
+// String renders the node in gcy syntax.
+func (n *Node) String() string {
+	str := "(" + n.Name
+
+	for _, l := range n.Labels {
+		str += ":" + l
+	}
+
+	if len(n.Props)+len(n.Params) > 0 {
+		keys := make([]string, 0, len(n.Props)+len(n.Params))
+		for k := range n.Props {
+			keys = append(keys, k)
+		}
+		for k := range n.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		props := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if param, ok := n.Params[k]; ok {
+				props = append(props, k+": $"+param)
+			} else {
+				props = append(props, k+": "+strconv.Quote(n.Props[k]))
+			}
+		}
+		str += " {" + strings.Join(props, ", ") + "}"
+	}
+
+	return str + ")"
+}
+
+// String renders the relation in gcy syntax, without the connected nodes.
+func (rel *Relation) String() string {
+	inner := rel.Name
+	if len(rel.Types) > 0 {
+		inner += ":" + strings.Join(rel.Types, "|")
+	}
+	inner += rel.Cardinality
+	if inner != "" {
+		inner = "[" + inner + "]"
+	}
+
+	switch rel.Direction {
+	case "->":
+		return "-" + inner + "->"
+	case "<-":
+		return "<-" + inner + "-"
+	}
+	return "-" + inner + "-"
+}
@@ -12,6 +12,7 @@ type itemType int
 type item struct {
 	typ itemType
 	val string
+	pos int // byte offset in the input
 }
 
 const (
@@ -22,6 +23,7 @@ const (
 
 	itemNumber
 	itemString
+	itemParameter
 
 	// symbols
 	itemLParen
@@ -58,6 +60,11 @@ const (
 	itemReturn
 	itemWith
 	itemAs
+	itemExplain
+	itemProfile
+	itemCall
+	itemYield
+	itemUsing
 
 	itemEOF
 )
@@ -70,6 +77,67 @@ var key = map[string]itemType{
 	"return": itemReturn,
 	"with":   itemWith,
 	"as":     itemAs,
+
+	"explain": itemExplain,
+	"profile": itemProfile,
+
+	"call":  itemCall,
+	"yield": itemYield,
+
+	"using": itemUsing,
+}
+
+var itemNames = map[itemType]string{
+	itemError:      "error",
+	itemIdentifier: "identifier",
+	itemField:      "field",
+	itemNumber:     "number",
+	itemString:     "string",
+	itemParameter:  "parameter",
+
+	itemLParen:   `"("`,
+	itemRParen:   `")"`,
+	itemLBrace:   `"{"`,
+	itemRBrace:   `"}"`,
+	itemLBracket: `"["`,
+	itemRBracket: `"]"`,
+	itemComma:    `","`,
+	itemColon:    `":"`,
+	itemEqual:    `"="`,
+	itemStar:     `"*"`,
+	itemPipe:     `"|"`,
+	itemDot:      `"."`,
+	itemRange:    `".."`,
+	itemRelDir:   "relationship direction",
+	itemMinus:    `"-"`,
+	itemPlus:     `"+"`,
+
+	itemQuotedString: "quoted string",
+	itemKeyword:      "keyword",
+	itemEOF:          "EOF",
+}
+
+// String names the type of token for error messages, keywords by their
+// spelling.
+func (t itemType) String() string {
+	if name, ok := itemNames[t]; ok {
+		return name
+	}
+	for word, typ := range key {
+		if typ == t {
+			return strconv.Quote(word)
+		}
+	}
+	return fmt.Sprintf("[%d]", int(t))
+}
+
+// describe names the token and, unless given by its type, its value.
+func (i item) describe() string {
+	switch i.typ {
+	case itemIdentifier, itemField, itemNumber, itemString, itemParameter, itemRelDir, itemQuotedString:
+		return fmt.Sprintf("%s %s", i.typ, i.val)
+	}
+	return i.typ.String()
 }
 
 // (partial) Copyright 2011 The Go Authors. All rights reserved.
@@ -96,7 +164,7 @@ func (i item) String() string {
 	}
 	s := fmt.Sprintf("%q", i.val)
 	if len(s) == 0 || s == "\"\"" {
-		s = fmt.Sprintf("[%d]", i.typ)
+		s = i.typ.String()
 	}
 	return s
 }
@@ -126,7 +194,7 @@ func (l *lexer) run() {
 
 // emit passes an item back to the client
 func (l *lexer) emit(t itemType) {
-	l.items <- item{t, l.input[l.start:l.pos]}
+	l.items <- item{t, l.input[l.start:l.pos], l.start}
 
 	//log.Printf("emitted item from %d to %d: %s\n", l.start, l.pos, item{t, l.input[l.start:l.pos]})
 
@@ -165,7 +233,7 @@ func (l *lexer) backup() {
 
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
 	//log.Printf("error at: %d(%s)\n", l.pos, l.input[l.pos:])
-	l.items <- item{itemError, fmt.Sprintf(format, args...)}
+	l.items <- item{itemError, fmt.Sprintf(format, args...), l.pos}
 	return nil
 }
 
@@ -218,7 +286,7 @@ func (l *lexer) peek() rune {
 const (
 	markIdentifier = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_"
 	markNumbers    = "1234567890"
-	markSpace      = "\n 	"
+	markSpace      = "\r\n 	"
 	markEOL        = "\n"
 	eof            = 0
 )
@@ -233,9 +301,9 @@ func lexGcy(l *lexer) stateFn {
 	//log.Printf("peek: %c\n", l.peek())
 
 	switch r := l.next(); {
-	case r == eof || isEndOfLine(r):
+	case r == eof:
 		return l.errorf("unclosed action")
-	case isSpace(r):
+	case isSpace(r) || isEndOfLine(r):
 		return lexSpace
 	case r == '=':
 		l.emit(itemEqual)
@@ -247,6 +315,8 @@ func lexGcy(l *lexer) stateFn {
 		l.emit(itemColon)
 	case r == '|':
 		l.emit(itemPipe)
+	case r == '$':
+		return lexParameter
 	case r == '"':
 		return lexQuote
 	case r == '`':
@@ -360,17 +430,16 @@ func lexField(l *lexer) stateFn {
 	return lexFieldOrVariable(l, itemField)
 }
 
-// lexVariable scans a Variable: $Alphanumeric.
+// lexParameter scans a parameter: $Alphanumeric.
 // The $ has been scanned.
-func lexVariable(l *lexer) stateFn {
-	if l.atBoundary() { // Nothing interesting follows -> "$".
-		l.emit(itemIdentifier)
-		return lexGcy
+func lexParameter(l *lexer) stateFn {
+	if l.atBoundary() {
+		return l.errorf("missing parameter name")
 	}
-	return lexFieldOrVariable(l, itemIdentifier)
+	return lexFieldOrVariable(l, itemParameter)
 }
 
-// lexVariable scans a field or variable: [.$]Alphanumeric.
+// lexFieldOrVariable scans a field or parameter: [.$]Alphanumeric.
 // The . or $ has been scanned.
 func lexFieldOrVariable(l *lexer, typ itemType) stateFn {
 	if l.atBoundary() { // Nothing interesting follows -> "." or "$".
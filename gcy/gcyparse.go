@@ -3,38 +3,61 @@ package gcy
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/BuJo/goneo/log"
 )
 
 type (
 	Query struct {
+		Explain bool // only plan the query
+		Profile bool // execute the query and collect statistics
+
 		Roots   []*Root
 		Match   *Match
+		Calls   []*Call
 		Returns []*Returnable
 		Deletes []*Returnable
 		Creates []*Returnable
+
+		// Parameters lists the names of all parameters used, in order of
+		// appearance
+		Parameters []string
 	}
 
 	Root struct {
 		Name   string
 		Typ    string
 		IdVars []int
+		Param  string // ids are given by a parameter
 	}
 
 	Match struct {
 		Paths []*Path
+
+		// Approximate matches may miss up to Tolerance relations or nodes
+		// of the pattern, see "using approximate". Cost names the variable
+		// bound to the cost of each match.
+		Approximate bool
+		Tolerance   int
+		Cost        string
 	}
 
 	Path struct {
 		Name  string
 		Start *Node
+
+		// Function is "shortestPath" or "allShortestPaths" if only the
+		// shortest paths of the pattern are wanted
+		Function string
 	}
 
 	Node struct {
 		Name   string
 		Labels []string
 		Props  map[string]string
+		Params map[string]string // property values given by parameters
 
 		LeftRel, RightRel *Relation
 	}
@@ -45,11 +68,22 @@ type (
 		Types       []string
 		Cardinality string
 
+		// MinHops and MaxHops bound variable length relations, a MaxHops of 0
+		// does not limit the length
+		MinHops, MaxHops int
+
 		LeftNode, RightNode *Node
 	}
 
+	// Call invokes a procedure, Yields select and rename its outputs.
+	Call struct {
+		Name   string
+		Args   []*Returnable
+		Yields []*Returnable
+	}
+
 	Returnable struct {
-		Type string // int, string, function, variable
+		Type string // int, float, string, parameter, function, variable
 
 		Name          string
 		Alias         string
@@ -71,18 +105,41 @@ func (list errorList) Error() string {
 	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
 }
 
-func newError(msg string) error {
-	return fmt.Errorf("%s", msg)
+// Unwrap allows inspecting the single errors with errors.As.
+func (list errorList) Unwrap() []error {
+	return list
+}
+
+// SyntaxError describes a query which could not be parsed.
+type SyntaxError struct {
+	Filename string
+	Line     int // starting at 1
+	Column   int // starting at 1, counting runes
+	Token    string
+	Msg      string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Msg)
 }
 
 type parser struct {
+	filename string
+	input    string
+
 	errors  errorList
 	scanner chan item
-	tok     item // one token look-ahead
+	params  []string // parameters seen so far
+	tok     item     // one token look-ahead
 }
 
 func (p *parser) next() {
-	p.tok = <-p.scanner
+	var ok bool
+	p.tok, ok = <-p.scanner
+
+	if !ok {
+		p.tok.pos = len(p.input)
+	}
 
 	if p.tok.typ == itemError {
 		p.error(p.tok.val)
@@ -90,23 +147,50 @@ func (p *parser) next() {
 }
 
 func (p *parser) error(msg string) {
-	p.errors = append(p.errors, newError(msg))
+	p.errors = append(p.errors, p.syntaxError(p.tok, msg))
+}
+
+// syntaxError locates the token in the input.
+func (p *parser) syntaxError(tok item, msg string) *SyntaxError {
+	pos := min(tok.pos, len(p.input))
+	before := p.input[:pos]
+
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+
+	token := tok.val
+	switch tok.typ {
+	case itemError:
+		// the lexer reports the message, show what it stumbled over
+		token = "EOF"
+		if r, _ := utf8.DecodeRuneInString(p.input[pos:]); pos < len(p.input) {
+			token = string(r)
+		}
+	case itemEOF:
+		token = "EOF"
+	default:
+		if token == "" {
+			token = tok.typ.String()
+		}
+	}
+
+	return &SyntaxError{Filename: p.filename, Line: line, Column: column, Token: token, Msg: msg}
 }
 
-func (p *parser) errorExpected(msg string) {
-	msg = `expected "` + msg + `"`
-	p.error(msg)
+// errorExpected reports what was expected instead of the current token.
+func (p *parser) errorExpected(what string) {
+	p.error("expected " + what + ", got " + p.tok.describe())
 }
 
 func (p *parser) expect(tok string) {
 	if p.tok.val != tok {
-		p.errorExpected(tok + "(got:" + p.tok.String() + ")")
+		p.errorExpected(strconv.Quote(tok))
 	}
 	p.next() // make progress in any case
 }
 func (p *parser) expectType(tok itemType) {
 	if p.tok.typ != tok {
-		p.errorExpected(fmt.Sprintf("%s, got %s", item{typ: tok}, p.tok))
+		p.errorExpected(tok.String())
 	}
 	p.next() // make progress in any case
 }
@@ -187,6 +271,8 @@ func (p *parser) parseRoot() (r *Root) {
 			case itemStar:
 				p.expectType(itemStar)
 				r.IdVars = append(r.IdVars, -1)
+			case itemParameter:
+				r.Param = p.parameter()
 
 			default:
 				break Loop
@@ -292,6 +378,95 @@ func (p *parser) parseFunction(name string) *Returnable {
 	return function
 }
 
+// parameter consumes a parameter, remembering its name.
+func (p *parser) parameter() string {
+	name := strings.TrimPrefix(p.tok.val, "$")
+	p.expectType(itemParameter)
+
+	for _, known := range p.params {
+		if known == name {
+			return name
+		}
+	}
+	p.params = append(p.params, name)
+
+	return name
+}
+
+func (p *parser) parseCall() *Call {
+	call := &Call{Name: p.tok.val}
+	p.expectType(itemIdentifier)
+
+	for p.tok.typ == itemDot {
+		p.expectType(itemDot)
+		call.Name += "." + p.tok.val
+		p.expectType(itemField)
+	}
+
+	p.expectType(itemLParen)
+	for p.tok.typ != itemRParen && p.tok.typ != itemEOF && p.tok.typ != itemError {
+		call.Args = append(call.Args, p.parseArgument())
+
+		if p.tok.typ != itemComma {
+			break
+		}
+		p.expectType(itemComma)
+	}
+	p.expectType(itemRParen)
+
+	if p.tok.typ == itemYield {
+		p.expectType(itemYield)
+
+		for p.tok.typ == itemIdentifier {
+			yield := p.parseVariable(p.tok.val)
+			p.expectType(itemIdentifier)
+
+			yield.Alias = yield.Name
+			if p.tok.typ == itemAs {
+				p.expectType(itemAs)
+				yield.Alias = p.tok.val
+				p.expectType(itemIdentifier)
+			}
+			call.Yields = append(call.Yields, yield)
+
+			if p.tok.typ != itemComma {
+				break
+			}
+			p.expectType(itemComma)
+		}
+	}
+
+	return call
+}
+
+// parseArgument parses a variable or literal passed to a procedure.
+func (p *parser) parseArgument() *Returnable {
+	switch p.tok.typ {
+	case itemString:
+		arg := &Returnable{Type: "string", Name: p.tok.val, Value: p.tok.val[1 : len(p.tok.val)-1]}
+		p.expectType(itemString)
+		return arg
+	case itemNumber:
+		arg := &Returnable{Type: "int", Name: p.tok.val}
+		if i, err := strconv.ParseInt(p.tok.val, 0, 64); err == nil {
+			arg.Value = int(i)
+		} else if f, err := strconv.ParseFloat(p.tok.val, 64); err == nil {
+			arg.Type, arg.Value = "float", f
+		} else {
+			p.error(err.Error())
+		}
+		p.expectType(itemNumber)
+		return arg
+	case itemParameter:
+		name := p.tok.val
+		return &Returnable{Type: "parameter", Name: name, Object: p.parameter()}
+	}
+
+	name := p.tok.val
+	p.expectType(itemIdentifier)
+	return p.parseVariable(name)
+}
+
 func (p *parser) parseDelete() []*Returnable {
 	return nil
 }
@@ -306,14 +481,24 @@ func (p *parser) parseMatch() *Match {
 	for {
 		path := new(Path)
 
-		if p.tok.typ == itemLParen {
-			path.Start = p.parsePath()
-		} else {
+		if p.tok.typ == itemIdentifier && !isPathFunction(p.tok.val) {
 			path.Name = p.tok.val
 			p.expectType(itemIdentifier)
 
 			p.expectType(itemEqual)
+		}
 
+		if p.tok.typ == itemIdentifier && isPathFunction(p.tok.val) {
+			path.Function = "shortestPath"
+			if strings.EqualFold(p.tok.val, "allShortestPaths") {
+				path.Function = "allShortestPaths"
+			}
+			p.expectType(itemIdentifier)
+
+			p.expectType(itemLParen)
+			path.Start = p.parsePath()
+			p.expectType(itemRParen)
+		} else {
 			path.Start = p.parsePath()
 		}
 
@@ -327,9 +512,40 @@ func (p *parser) parseMatch() *Match {
 		}
 		break
 	}
+
+	if p.tok.typ == itemUsing {
+		p.expectType(itemUsing)
+		p.parseHint(match)
+	}
+
 	return match
 }
 
+// parseHint reads how the paths should be matched.
+func (p *parser) parseHint(match *Match) {
+	if p.tok.typ != itemIdentifier || !strings.EqualFold(p.tok.val, "approximate") {
+		p.errorExpected(`"approximate"`)
+		return
+	}
+	p.expectType(itemIdentifier)
+
+	match.Approximate = true
+	match.Tolerance = 1
+	if p.tok.typ == itemNumber {
+		n, err := strconv.Atoi(p.tok.val)
+		if err != nil || n < 0 {
+			p.error("tolerance must be a non-negative number, got " + p.tok.val)
+		}
+		p.expectType(itemNumber)
+		match.Tolerance = n
+	}
+	if p.tok.typ == itemAs {
+		p.expectType(itemAs)
+		match.Cost = p.tok.val
+		p.expectType(itemIdentifier)
+	}
+}
+
 func (p *parser) parsePath() *Node {
 	node := p.parseNode()
 
@@ -372,14 +588,26 @@ func (p *parser) parseNode() *Node {
 			key := p.tok.val
 			p.expectType(itemIdentifier)
 			p.expectType(itemColon)
-			val := p.tok.val[1 : len(p.tok.val)-1]
-			p.expectType(itemString)
 
-			node.Props[key] = val
+			switch p.tok.typ {
+			case itemParameter:
+				if node.Params == nil {
+					node.Params = make(map[string]string)
+				}
+				node.Params[key] = p.parameter()
+			case itemString:
+				val := p.tok.val[1 : len(p.tok.val)-1]
+				p.expectType(itemString)
+
+				node.Props[key] = val
+			default:
+				p.errorExpected(itemString.String() + " or " + itemParameter.String())
+			}
 
 			if p.tok.typ != itemComma {
 				break
 			}
+			p.expectType(itemComma)
 		}
 
 		p.expectType(itemRBrace)
@@ -419,8 +647,7 @@ func (p *parser) parseRelation() *Relation {
 		}
 
 		if p.tok.typ == itemStar {
-			p.expectType(itemStar)
-			// TODO: implement properly
+			p.parseHops(rel)
 		}
 
 		p.expectType(itemRBracket)
@@ -436,10 +663,61 @@ func (p *parser) parseRelation() *Relation {
 	return rel
 }
 
+// parseHops parses the length of a variable length relation: *, *n, *..m,
+// *n.. or *n..m.
+func (p *parser) parseHops(rel *Relation) {
+	p.expectType(itemStar)
+	rel.Cardinality = "*"
+	rel.MinHops = 1
+
+	if p.tok.typ == itemNumber {
+		rel.MinHops = p.hops()
+		rel.MaxHops = rel.MinHops
+		rel.Cardinality += strconv.Itoa(rel.MinHops)
+	}
+
+	if p.tok.typ == itemRange {
+		p.expectType(itemRange)
+		rel.Cardinality += ".."
+		rel.MaxHops = 0
+
+		if p.tok.typ == itemNumber {
+			rel.MaxHops = p.hops()
+			rel.Cardinality += strconv.Itoa(rel.MaxHops)
+		}
+	}
+
+	if rel.MaxHops > 0 && rel.MaxHops < rel.MinHops {
+		p.error(fmt.Sprintf("relation length %s is empty", rel.Cardinality))
+	}
+}
+
+func (p *parser) hops() int {
+	n, err := strconv.Atoi(p.tok.val)
+	if err != nil || n < 0 {
+		p.error("relation length must be a non-negative number, got " + p.tok.val)
+	}
+	p.expectType(itemNumber)
+	return n
+}
+
+func isPathFunction(name string) bool {
+	return strings.EqualFold(name, "shortestPath") || strings.EqualFold(name, "allShortestPaths")
+}
+
 func (p *parser) parseQuery() *Query {
 
 	query := new(Query)
 
+	switch p.tok.typ {
+	case itemExplain:
+		p.expectType(itemExplain)
+		query.Explain = true
+	case itemProfile:
+		p.expectType(itemProfile)
+		query.Profile = true
+	}
+
 	for p.tok.typ != itemEOF {
 		switch p.tok.typ {
 		case itemStart:
@@ -454,6 +732,9 @@ func (p *parser) parseQuery() *Query {
 		case itemCreate:
 			p.expectType(itemCreate)
 			query.Creates = p.parseCreate()
+		case itemCall:
+			p.expectType(itemCall)
+			query.Calls = append(query.Calls, p.parseCall())
 		case itemReturn:
 			p.expectType(itemReturn)
 			query.Returns = p.parseReturns()
@@ -466,24 +747,29 @@ func (p *parser) parseQuery() *Query {
 	return query
 }
 
-func (p *parser) parse(filename string, channel chan item) *Query {
+func (p *parser) parse(filename, input string, channel chan item) *Query {
+	p.filename, p.input = filename, input
 	p.scanner = channel
 
 	p.next() // initializes first token
 
 	query := p.parseQuery()
+	if query != nil {
+		query.Parameters = p.params
+	}
 
 	return query
 }
 
 // Parse a give string using gcy. The filename is only used for reporting.
 // Make sure to check the returned error, this function may return partially
-// parsed elements.
+// parsed elements. All problems found are reported as *SyntaxError, use
+// errors.As to inspect them.
 func Parse(filename string, src string) (*Query, error) {
 	var p parser
 
 	_, channel := lex(filename, src)
-	query := p.parse(filename, channel)
+	query := p.parse(filename, src, channel)
 
 	if query == nil {
 		p.error("Invalid query, Lexing might have failed")
@@ -1,6 +1,7 @@
 package gcy
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -117,3 +118,242 @@ func TestParseCaseSensitivity(t *testing.T) {
 	}
 
 }
+
+func TestParseExplainProfile(t *testing.T) {
+	q, err := Parse("goneo", "EXPLAIN match (n:Tag) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Explain || q.Profile {
+		t.Error("should only be explained")
+	}
+
+	q, err = Parse("goneo", "profile start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Explain || !q.Profile {
+		t.Error("should only be profiled")
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	q, err := Parse("goneo", `start a=node($ids) match (a)-->(n:Tag {tag: $tag, kind: "genre"}) return n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Roots[0].Param != "ids" {
+		t.Error("root should refer to parameter ids, got ", q.Roots[0].Param)
+	}
+
+	n := q.Match.Paths[0].Start.RightRel.RightNode
+	if n.Params["tag"] != "tag" || n.Props["kind"] != "genre" {
+		t.Errorf("node should have a tag parameter and a kind property, got %v %v", n.Params, n.Props)
+	}
+
+	if len(q.Parameters) != 2 || q.Parameters[0] != "ids" || q.Parameters[1] != "tag" {
+		t.Error("query should list its parameters, got ", q.Parameters)
+	}
+
+	expected := `(n:Tag {kind: "genre", tag: $tag})`
+	if str := n.String(); str != expected {
+		t.Errorf("expected %s, got %s", expected, str)
+	}
+}
+
+func TestParseVariableLength(t *testing.T) {
+	tests := []struct {
+		rel      string
+		min, max int
+	}{
+		{"[*]", 1, 0},
+		{"[:T*3]", 3, 3},
+		{"[:T*..10]", 1, 10},
+		{"[r:T*2..]", 2, 0},
+		{"[*0..5]", 0, 5},
+	}
+
+	for _, test := range tests {
+		q, err := Parse("goneo", "match (a)-"+test.rel+"->(b) return a")
+		if err != nil {
+			t.Fatal(test.rel, err)
+		}
+
+		rel := q.Match.Paths[0].Start.RightRel
+		if rel.MinHops != test.min || rel.MaxHops != test.max {
+			t.Errorf("%s: expected %d..%d hops, got %d..%d", test.rel, test.min, test.max, rel.MinHops, rel.MaxHops)
+		}
+		if str := rel.String(); str != "-"+test.rel+"->" {
+			t.Errorf("%s: rendered as %s", test.rel, str)
+		}
+	}
+
+	if _, err := Parse("goneo", "match (a)-[*5..2]->(b) return a"); err == nil {
+		t.Error("Should reject empty lengths")
+	}
+}
+
+func TestParseShortestPath(t *testing.T) {
+	q, err := Parse("goneo", "match p = shortestPath((a:Person)-[:KNOWS*..10]->(b)), allShortestPaths((a)-[*]-(c)) return p")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(q.Match.Paths) != 2 {
+		t.Fatal("Should parse two paths, got ", len(q.Match.Paths))
+	}
+
+	shortest, all := q.Match.Paths[0], q.Match.Paths[1]
+	if shortest.Name != "p" || shortest.Function != "shortestPath" || all.Function != "allShortestPaths" {
+		t.Error("Should parse path functions, got ", shortest, all)
+	}
+
+	expected := "p = shortestPath((a:Person)-[:KNOWS*..10]->(b))"
+	if str := shortest.String(); str != expected {
+		t.Errorf("expected %s, got %s", expected, str)
+	}
+}
+
+func TestParseApproximate(t *testing.T) {
+	q, err := Parse("goneo", "match (a:Person)-[:KNOWS]->(b), (b)-->(c) using approximate 2 return a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Match.Paths) != 2 || !q.Match.Approximate || q.Match.Tolerance != 2 {
+		t.Error("Should parse the hint after all paths, got ", q.Match)
+	}
+
+	q, err = Parse("goneo", "match (a)-->(b) USING Approximate return a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Match.Approximate || q.Match.Tolerance != 1 {
+		t.Error("Should tolerate a single difference by default, got ", q.Match)
+	}
+
+	q, err = Parse("goneo", "match (a)-->(b) using approximate 0 return a")
+	if err != nil || q.Match.Tolerance != 0 {
+		t.Error("Should tolerate no differences at all, got ", err)
+	}
+	if _, err := Parse("goneo", "match (a)-->(b) using approximate 1.5 return a"); err == nil || !strings.Contains(err.Error(), "non-negative") {
+		t.Error("Should only tolerate whole differences, got ", err)
+	}
+	q, err = Parse("goneo", "match (a)-->(b) using approximate 1 as cost return a, cost")
+	if err != nil || q.Match.Tolerance != 1 || q.Match.Cost != "cost" {
+		t.Error("Should name the cost of approximate matches, got ", err)
+	}
+
+	if q, _ := Parse("goneo", "match (a)-->(b) return a"); q.Match.Approximate {
+		t.Error("Should match exactly without a hint")
+	}
+	if _, err := Parse("goneo", "match (a)-->(b) using exact return a"); err == nil {
+		t.Error("Should only know approximate matching")
+	}
+}
+
+func TestParseCall(t *testing.T) {
+	q, err := Parse("goneo", `match (a), (b) call db.paths.dijkstra(a, b, "cost", 2, 0.5, $dir) yield path as p, cost return p, cost`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(q.Calls) != 1 {
+		t.Fatal("Should parse a call, got ", q.Calls)
+	}
+	call := q.Calls[0]
+	if call.Name != "db.paths.dijkstra" {
+		t.Error("Should parse the procedure name, got ", call.Name)
+	}
+
+	types := make([]string, 0)
+	for _, arg := range call.Args {
+		types = append(types, arg.Type)
+	}
+	if strings.Join(types, ",") != "variable,variable,string,int,float,parameter" {
+		t.Error("Should parse all kinds of arguments, got ", types)
+	}
+	if call.Args[2].Value != "cost" || call.Args[3].Value != 2 || call.Args[5].Object != "dir" {
+		t.Error("Should parse argument values, got ", call.Args[2].Value, call.Args[3].Value, call.Args[5].Object)
+	}
+
+	if len(call.Yields) != 2 || call.Yields[0].Name != "path" || call.Yields[0].Alias != "p" || call.Yields[1].Alias != "cost" {
+		t.Error("Should parse yielded outputs, got ", call.Yields)
+	}
+
+	q, err = Parse("goneo", `call db.labels() return label`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Calls[0].Args) != 0 || len(q.Calls[0].Yields) != 0 {
+		t.Error("Should parse calls without arguments and yields")
+	}
+}
+
+func TestPathString(t *testing.T) {
+	q, err := Parse("goneo", `match p = (n:Tag {tag: "Drama"})<-[:IS_TAGGED]-(v)-->(w) return n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `p = (n:Tag {tag: "Drama"})<-[:IS_TAGGED]-(v)-->(w)`
+	if str := q.Match.Paths[0].String(); str != expected {
+		t.Errorf("expected %s, got %s", expected, str)
+	}
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	_, err := Parse("goneo", "match (e:Episode)\nreturn e.title+e2.title")
+	if err == nil {
+		t.Fatal("Parsing should fail")
+	}
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatal("Error should contain a syntax error: ", err)
+	}
+
+	if syntaxErr.Line != 2 || syntaxErr.Column != 15 {
+		t.Errorf("Error should be at 2:15, is at %d:%d", syntaxErr.Line, syntaxErr.Column)
+	}
+	if syntaxErr.Token != "+" {
+		t.Error("Error should name the offending token, got ", syntaxErr.Token)
+	}
+	if !strings.HasPrefix(syntaxErr.Error(), "goneo:2:15: ") {
+		t.Error("Error should be prefixed by its position: ", syntaxErr.Error())
+	}
+}
+
+func TestSyntaxErrorToken(t *testing.T) {
+	_, err := Parse("goneo", "start n=node(*) return n as")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatal("Error should contain a syntax error: ", err)
+	}
+	if syntaxErr.Token != "EOF" || syntaxErr.Column != 28 {
+		t.Errorf("Error should point at the end, got %q at %d", syntaxErr.Token, syntaxErr.Column)
+	}
+	if syntaxErr.Msg != "expected identifier, got EOF" {
+		t.Error("Error should name the expected and actual tokens, got ", syntaxErr.Msg)
+	}
+
+	_, err = Parse("goneo", "match (n return n")
+	if !errors.As(err, &syntaxErr) || syntaxErr.Msg != `expected ")", got "return"` || syntaxErr.Token != "return" {
+		t.Error("Error should name the expected and actual tokens, got ", err)
+	}
+}
+
+func TestSyntaxErrorProperty(t *testing.T) {
+	for query, msg := range map[string]string{
+		"match (n {tag: 5}) return n": "expected string or parameter, got number 5",
+		"match (n {tag: }) return n":  `expected string or parameter, got "}"`,
+	} {
+		_, err := Parse("goneo", query)
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || syntaxErr.Msg != msg {
+			t.Errorf("%s: properties should be strings or parameters, got %v", query, err)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package goneo
 
 import (
-	"errors"
+	"context"
+	"math"
 
 	. "github.com/BuJo/goneo/db"
 	"github.com/BuJo/goneo/gcy"
@@ -10,234 +11,104 @@ import (
 )
 
 type (
-	evalContext struct {
-		vars map[string][]PropertyContainer
-
-		subgraphNameMap    map[string]int
-		subgraphRevNameMap map[int]string
+	// row binds query variables to values, it is the unit flowing through
+	// the evaluation of a query.
+	row map[string]interface{}
 
-		db DatabaseService
-	}
+	evalContext struct {
+		ctx    context.Context
+		cancel context.CancelFunc
+		db     DatabaseService
 
-	query   struct{ q *gcy.Query }
-	match   struct{ m *gcy.Match }
-	root    struct{ r *gcy.Root }
-	returns struct{ r *gcy.Returnable }
-)
+		limits       Limits
+		intermediate int
+		memory       int64
 
-func (q *query) evaluate(ctx evalContext) *TabularData {
+		profile bool
+		stats   map[operator]*executionStats
 
-	ctx.vars = make(map[string][]PropertyContainer)
-	ctx.subgraphNameMap = make(map[string]int)
-	ctx.subgraphRevNameMap = make(map[int]string)
+		// parameters of a prepared statement
+		params map[string]interface{}
 
-	for _, r := range q.q.Roots {
-		(&root{r}).evaluate(ctx)
+		err error
 	}
+)
 
-	if q.q.Match != nil {
-		(&match{q.q.Match}).evaluate(ctx)
+// fail records the first error happening while evaluating.
+func (ctx *evalContext) fail(err error) {
+	if ctx.err == nil {
+		ctx.err = err
 	}
+}
 
-	table := &TabularData{}
-
-	for _, r := range q.q.Returns {
-		table = table.Merge((&returns{r}).evaluate(ctx))
+// cancelled reports whether evaluation should stop, recording the reason.
+func (ctx *evalContext) cancelled() bool {
+	if ctx.ctx.Err() != nil {
+		// the cause tells timeouts of the query from the caller giving up
+		ctx.fail(context.Cause(ctx.ctx))
+		return true
 	}
-
-	return table
+	return ctx.err != nil
 }
 
-// BUG(jo): db cannot encode undirected graph
-// BUG(jo): cannot encode more than one possible relation type
-
-func (mm *match) evaluate(ctx evalContext) *TabularData {
-	m := mm.m
-
-	subgraph, _ := OpenDb("mem:temporary")
-
-	for _, p := range m.Paths {
-		var builder *PathBuilder
-		for currentNode := p.Start; currentNode != nil; {
-			var n Node
-
-			if currentNode.Name != "" {
-				if id, ok := ctx.subgraphNameMap[currentNode.Name]; ok {
-					n, _ = subgraph.GetNode(id)
-					log.Print("tried to find node name ", currentNode.Name, ", found", n)
-				}
-			}
-
-			if n == nil {
-				n = subgraph.NewNode(currentNode.Labels...)
-				ctx.subgraphNameMap[currentNode.Name] = n.Id()
-				ctx.subgraphRevNameMap[n.Id()] = currentNode.Name
-
-				log.Print("created new node: ", n, "(", currentNode, ")")
-
-				for k, v := range currentNode.Props {
-					n.SetProperty(k, v)
-				}
-			}
-
-			if builder == nil {
-				log.Print("first run, node: ", n, "(", currentNode, ")")
-				builder = NewPathBuilder(n)
-			} else {
-				prevNode := builder.Last()
-				log.Print("next run, ", prevNode, "->", n, "(", currentNode, ")")
-
-				// TODO: utter crap, path is specific, has no "optional variants"
-				// TODO: utter crap, db relations have no "optional variants"
-				typ := ""
-				if ok := len(currentNode.LeftRel.Types) > 0; ok {
-					typ = currentNode.LeftRel.Types[0]
-				}
-				var rel Relation
-				if currentNode.LeftRel.Direction == "->" {
-					rel = prevNode.RelateTo(n, typ)
-				} else {
-					rel = n.RelateTo(prevNode, typ)
-				}
-				//log.Print("created new subgraph rel: ", rel, currentNode.LeftRel)
-
-				builder = builder.Append(rel)
-			}
-
-			if currentNode.RightRel == nil {
-				currentNode = nil
-			} else {
-				currentNode = currentNode.RightRel.RightNode
-			}
-		}
+// merge combines the bindings of two rows.
+func (r row) merge(o row) row {
+	next := make(row, len(r)+len(o))
+	for k, v := range r {
+		next[k] = v
 	}
-
-	knownMappings := map[string]int{}
-	for k, v := range ctx.vars {
-		for _, n := range v {
-			knownMappings[k] = n.Id()
-		}
+	for k, v := range o {
+		next[k] = v
 	}
-
-	mappings := sgi.FindVF2SubgraphIsomorphism(&dbGraph{subgraph}, &dbGraph{ctx.db}, func(state sgi.State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
-		//log.Print("tyring to find mapping for subgraph id ", toQueryNode, " in ", ctx.subgraphRevNameMap)
-		if name, hasName := ctx.subgraphRevNameMap[toQueryNode]; hasName {
-
-			if t2, hasMapping := knownMappings[name]; hasMapping {
-				//log.Print(name, " mapped in ", ctx.vars, "targetId should be ", t2, " is ", toTargetNode)
-				return t2 == toTargetNode
-			}
-			//log.Print(name, " not mapped in ", knownMappings, ", trying normal mapping")
-		}
-
-		return isSemanticallyFeasable(state, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode)
-	})
-
-	for _, mapping := range mappings {
-		for q, t := range mapping {
-			name := ctx.subgraphRevNameMap[q]
-			node, _ := ctx.db.GetNode(t)
-
-			if _, ok := ctx.vars[name]; !ok {
-				ctx.vars[name] = make([]PropertyContainer, 1)
-			}
-
-			ctx.vars[name] = append(ctx.vars[name], node)
-		}
-	}
-
-	return nil
+	return next
 }
 
-func (rr *root) evaluate(ctx evalContext) *TabularData {
-	r := rr.r
-	_, ok := ctx.vars[r.Name]
-
-	if !ok {
-		ctx.vars[r.Name] = make([]PropertyContainer, 0)
+func (r row) with(name string, value interface{}) row {
+	next := make(row, len(r)+1)
+	for k, v := range r {
+		next[k] = v
 	}
-
-	if r.Typ == "node" {
-		if len(r.IdVars) == 1 && r.IdVars[0] == -1 {
-			for _, node := range ctx.db.GetAllNodes() {
-				ctx.vars[r.Name] = append(ctx.vars[r.Name], node)
-			}
-		} else {
-			for _, id := range r.IdVars {
-				node, _ := ctx.db.GetNode(id)
-				ctx.vars[r.Name] = append(ctx.vars[r.Name], node)
-			}
-		}
-	} else {
-		if len(r.IdVars) == 1 && r.IdVars[0] == -1 {
-			for _, rel := range ctx.db.GetAllRelations() {
-				ctx.vars[r.Name] = append(ctx.vars[r.Name], rel)
-			}
-		} else {
-			for _, id := range r.IdVars {
-				rel, _ := ctx.db.GetRelation(id)
-				ctx.vars[r.Name] = append(ctx.vars[r.Name], rel)
-			}
-		}
+	if name != "" {
+		next[name] = value
 	}
+	return next
+}
 
-	log.Print("handled root: ", r, ", vars: ", ctx.vars)
+// planQuery builds the operators executing a query.
+func planQuery(q *gcy.Query, db DatabaseService) operator {
+	pl := newPlanner(db)
 
-	return &TabularData{}
-}
-func (rr *returns) evaluate(ctx evalContext) *TabularData {
-	r := rr.r
-	table := &TabularData{}
-
-	table.line = make([]map[string]interface{}, 0)
-
-	for _, o := range evaluateReturnable(ctx, r) {
-		line := make(map[string]interface{})
-
-		switch o := o.(type) {
-		case Node:
-			if r.Field != "" {
-				line[r.Alias] = o.Property(r.Field)
-			} else {
-				line[r.Alias] = o
-			}
-		default:
-			line[r.Alias] = o
-		}
-		table.line = append(table.line, line)
+	var op operator = &argumentOp{}
+	op.stats().estimated = 1
 
+	for _, r := range q.Roots {
+		root := &rootOp{input: op, r: r}
+		root.estimated = op.stats().estimated * pl.rootCount(r)
+		op = root
 	}
 
-	log.Print("evaluating return, ", table.line)
-
-	return table
-}
+	if q.Match != nil {
+		op = pl.planMatch(op, q.Match)
+	}
 
-func evaluateReturnable(ctx evalContext, r *gcy.Returnable) []interface{} {
-	objs := make([]interface{}, 0)
+	for _, c := range q.Calls {
+		proc, _ := lookupProcedure(c.Name)
+		call := &callOp{input: op, call: c, proc: proc}
+		call.estimated = op.stats().estimated
+		op = call
+	}
 
-	switch r.Type {
-	case "variable":
-		for _, o := range ctx.vars[r.Object] {
-			if o != nil {
-				objs = append(objs, o)
-			}
-		}
-	case "function":
-		subobjs := make([][]interface{}, 0)
-		for _, item := range r.Vars {
-			subobjs = append(subobjs, evaluateReturnable(ctx, item))
-		}
-		switch r.Object {
-		case "count":
-			if len(subobjs) != 1 {
-				log.Fatal("ERROR evaluating variable ", r.Name, ", need 1 variable")
-			}
-			objs = append(objs, len(subobjs[0]))
+	for _, r := range q.Returns {
+		if isAggregate(r) {
+			aggregation := &aggregationOp{input: op, r: q.Returns}
+			aggregation.estimated = math.Max(1, math.Sqrt(op.stats().estimated))
+			return aggregation
 		}
 	}
 
-	return objs
+	projection := &projectionOp{input: op, r: q.Returns}
+	projection.estimated = op.stats().estimated
+	return projection
 }
 
 // Evaluate a gcy query
@@ -245,21 +116,43 @@ func evaluateReturnable(ctx evalContext, r *gcy.Returnable) []interface{} {
 // Example:
 //
 //	start n=node(*) return n
+//
+// Queries prefixed with EXPLAIN are not executed but only planned, PROFILE
+// executes the query and measures each step. The plan is available via
+// TabularData.Plan in both cases.
 func Evaluate(db DatabaseService, qry string) (*TabularData, error) {
-	q, err := gcy.Parse("goneo", qry)
+	rows, err := EvaluateContext(context.Background(), db, qry)
 	if err != nil {
 		return nil, err
 	}
-	table := (&query{q}).evaluate(evalContext{db: db})
-	if table == nil {
-		return nil, errors.New("could not evaluate query")
+
+	return rows.Table()
+}
+
+// EvaluateContext evaluates a gcy query lazily. The returned Rows pull
+// results from the database only as they are requested. Evaluation stops as
+// soon as ctx is done, Rows.Err then reports the reason. Resources used by
+// the query are restricted by the Limits given via WithLimits.
+//
+// Queries which cannot be parsed fail with a *gcy.SyntaxError, queries
+// referring to unknown variables or functions with a *SemanticError. Missing
+// nodes or relations given by id are reported as *EntityNotFoundError.
+func EvaluateContext(ctx context.Context, db DatabaseService, qry string) (*Rows, error) {
+	stmt, err := Prepare(qry)
+	if err != nil {
+		return nil, err
 	}
 
-	return table, nil
+	return stmt.EvaluateContext(ctx, db, nil)
 }
 
-// defines semantic feasibility of the given state M(s) and n' m'
+// defines semantic feasibility of the given state M(s) and n' m', relations
+// are compared by isEdgeFeasable
 func isSemanticallyFeasable(state sgi.State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+	if fromQueryNode != sgi.NullNode {
+		return true
+	}
+
 	graph := state.GetGraph().(*dbGraph).db
 	subgraph := state.GetSubgraph().(*dbGraph).db
 
@@ -267,58 +160,41 @@ func isSemanticallyFeasable(state sgi.State, fromQueryNode, fromTargetNode, toQu
 	t2, _ := graph.GetNode(toTargetNode)
 
 	// Labels
-	labelsOk := t2.HasLabel(q2.Labels()...)
+	if !t2.HasLabel(q2.Labels()...) {
+		return false
+	}
 
 	// Properties
-	propsOk := true
 	for k, v := range q2.Properties() {
 		if ok := t2.HasProperty(k); !ok || t2.Property(k) != v {
-			propsOk = false
-			break
+			return false
 		}
 	}
 
-	if fromQueryNode == sgi.NullNode {
-		log.Printf("queryNode: %s , targetNode: %s\n", q2, t2)
-		return labelsOk && propsOk
-	}
-
-	q1, _ := subgraph.GetNode(fromQueryNode)
-	t1, _ := graph.GetNode(fromTargetNode)
-
-	// query relation
-	var qRel Relation
-	var qDir = Both
-	for _, rel := range q1.Relations(Both) {
-		if rel.Start().Id() == q1.Id() && rel.End().Id() == q2.Id() {
-			qRel, qDir = rel, Outgoing
-		} else if rel.End().Id() == q1.Id() && rel.Start().Id() == q2.Id() {
-			qRel, qDir = rel, Incoming
-		}
-	}
+	log.Printf("queryNode: %s , targetNode: %s\n", q2, t2)
+	return true
+}
 
-	// target relation
-	var tRel Relation
-	var tDir = Both
-	for _, rel := range t1.Relations(Both) {
-		if rel.Start().Id() == t1.Id() && rel.End().Id() == t2.Id() {
-			tRel, tDir = rel, Outgoing
-		} else if rel.End().Id() == t1.Id() && rel.Start().Id() == t2.Id() {
-			tRel, tDir = rel, Incoming
-		}
+// isEdgeFeasable compares a query relation to a relation of the graph, the
+// types are any of the types allowed for the query relation.
+func isEdgeFeasable(graph DatabaseService, types []string, targetEdge int) bool {
+	if len(types) == 0 {
+		return true
 	}
-
-	log.Printf("queryNodes: %s%s%s , targetNodes: %s%s%s | ", q1, qDir, q2, t1, tDir, t2)
-	//log.Printf("rel: (%s~>%s), dir: (%s~>%s) \n", qRel.Type(), tRel.Type(), qDir, tDir)
-	log.Print(qRel, tRel)
-
-	return (qRel.Type() == "" || qRel.Type() == tRel.Type()) && (qDir == Both || qDir == tDir) && labelsOk && propsOk
+	rel, err := graph.GetRelation(targetEdge)
+	return err == nil && containsString(types, rel.Type())
 }
 
 type dbGraph struct {
 	db DatabaseService
 }
 
+// NewGraph adapts a database to the sgi.Graph interface, graph nodes are
+// identified by the ids of the database nodes.
+func NewGraph(db DatabaseService) sgi.Graph {
+	return &dbGraph{db}
+}
+
 func (g *dbGraph) Order() int {
 	return len(g.db.GetAllNodes())
 }
@@ -353,6 +229,17 @@ func (g *dbGraph) Predecessors(a int) []int {
 	//log.Print("gr:Pred:",a,ids)
 	return ids
 }
+func (g *dbGraph) Edges(a, b int) []int {
+
+	node, _ := g.db.GetNode(a)
+	ids := make([]int, 0)
+	for _, rel := range node.Relations(Outgoing) {
+		if rel.End().Id() == b {
+			ids = append(ids, rel.Id())
+		}
+	}
+	return ids
+}
 func (g *dbGraph) Relations(a int) []int {
 
 	node, _ := g.db.GetNode(a)
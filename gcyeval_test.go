@@ -1,12 +1,14 @@
 package goneo
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/BuJo/goneo/data"
 	"github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/gcy"
 )
 
 func setupTestDb(t *testing.T) db.DatabaseService {
@@ -87,6 +89,158 @@ func TestMultiMatch(t *testing.T) {
 	NewTableTester(t, table, err).Has("e1.episode", "2")
 }
 
+func TestRelationVariable(t *testing.T) {
+	graph := setupTestDb(t)
+
+	table, err := Evaluate(graph, "match (n:Tag)<-[r:IS_TAGGED]-(v) return n, r, v")
+	NewTableTester(t, table, err).HasLen(6).HasColumns("n", "r", "v")
+	for i := 0; err == nil && i < table.Len(); i++ {
+		rel, ok := table.Get(i, "r").(db.Relation)
+		if !ok || rel.Type() != "IS_TAGGED" || rel.Start() != table.Get(i, "v") || rel.End() != table.Get(i, "n") {
+			t.Error("Expected the relation between the nodes, got ", table.Get(i, "r"))
+		}
+	}
+
+	// Subgraph matching binds relations as well
+	table, err = Evaluate(graph, "match (c)-[r:APPEARED_IN]->(e)-[:ARCS_TO*]->(e2) return c, r, e")
+	NewTableTester(t, table, err).HasLen(1)
+	if err == nil {
+		rel, ok := table.Get(0, "r").(db.Relation)
+		if !ok || rel.Type() != "APPEARED_IN" || rel.Start() != table.Get(0, "c") || rel.End() != table.Get(0, "e") {
+			t.Error("Expected the relation between the nodes, got ", table.Get(0, "r"))
+		}
+	}
+
+	// A relationship variable used twice binds the same relation
+	table, err = Evaluate(graph, "match (a)-[r]->(b), (b)-[r]->(c) return r")
+	NewTableTester(t, table, err).HasLen(0)
+	all, err := Evaluate(graph, "match (a)-[r]->(b) return r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err = Evaluate(graph, "match (a)-[r]->(b), (a)-[r]->(b) return r")
+	NewTableTester(t, table, err).HasLen(all.Len())
+
+	// Multiple relations between the same nodes are told apart
+	a, b := graph.NewNode("Twin"), graph.NewNode("Twin")
+	a.RelateTo(b, "KNOWS")
+	a.RelateTo(b, "LIKES")
+	b.RelateTo(graph.NewNode(), "ARCS_TO")
+	table, err = Evaluate(graph, "match (x:Twin)-[r]->(y:Twin) return r")
+	NewTableTester(t, table, err).HasLen(2)
+	table, err = Evaluate(graph, "match (x:Twin)-[r:LIKES|KNOWS]->(y:Twin)-[:ARCS_TO*]->(z) return r")
+	NewTableTester(t, table, err).HasLen(2)
+	table, err = Evaluate(graph, "match (x:Twin)-[r:LIKES]->(y:Twin)-[:ARCS_TO*]->(z) return r")
+	NewTableTester(t, table, err).HasLen(1)
+}
+
+func TestVarLengthMatch(t *testing.T) {
+	graph := setupTestDb(t)
+
+	count := func(query string) int {
+		table, err := Evaluate(graph, query)
+		if err != nil {
+			t.Fatal(query, ": ", err)
+		}
+		return table.Get(0, "c").(int)
+	}
+
+	for _, q := range []struct {
+		varLength string
+		explicit  []string
+	}{
+		{"match (a:Actor)-[*2]->(c) return count(a) as c", []string{
+			"match (a:Actor)-->(b)-->(c) return count(a) as c",
+		}},
+		{"match (a:Actor)-[*1..2]-(c) return count(a) as c", []string{
+			"match (a:Actor)--(c) return count(a) as c",
+			"match (a:Actor)--(b)--(c) return count(a) as c",
+		}},
+		{"match (e:Episode)-[:LEADS_TO*2..3]->(e2) return count(e) as c", []string{
+			"match (e:Episode)-[:LEADS_TO]->(b)-[:LEADS_TO]->(e2) return count(e) as c",
+			"match (e:Episode)-[:LEADS_TO]->(b)-[:LEADS_TO]->(d)-[:LEADS_TO]->(e2) return count(e) as c",
+		}},
+	} {
+		explicit := 0
+		for _, query := range q.explicit {
+			explicit += count(query)
+		}
+		if varLength := count(q.varLength); varLength != explicit {
+			t.Errorf("Expected %q to match as often as its explicit relations, got %d and %d", q.varLength, varLength, explicit)
+		}
+	}
+
+	// Relations are bound to the relations of each path
+	table, err := Evaluate(graph, "match (e:Episode {episode: \"1\"})-[r:LEADS_TO|ARCS_TO*2]->(e2) return e2, r")
+	NewTableTester(t, table, err).HasLen(2)
+	for i := 0; err == nil && i < table.Len(); i++ {
+		rels, ok := table.Get(i, "r").([]db.Relation)
+		if !ok || len(rels) != 2 || rels[0].End() != rels[1].Start() || rels[1].End() != table.Get(i, "e2") {
+			t.Error("Expected the relations of the path, got ", table.Get(i, "r"))
+		}
+	}
+
+	// Into an already bound node
+	table, err = Evaluate(graph, "match (e:Episode {episode: \"1\"}), (e2:Episode {episode: \"4\"}), (e)-[r:LEADS_TO*..5]->(e2) return r")
+	NewTableTester(t, table, err).HasLen(1)
+	if err == nil {
+		if rels, ok := table.Get(0, "r").([]db.Relation); !ok || len(rels) != 3 {
+			t.Error("Expected the path between the episodes, got ", table.Get(0, "r"))
+		}
+	}
+}
+
+func TestApproximateMatch(t *testing.T) {
+	graph, _ := OpenDb("mem:approximate")
+	alice, bob, carol := graph.NewNode("Person"), graph.NewNode("Person"), graph.NewNode("Robot")
+	alice.RelateTo(bob, "KNOWS")
+	bob.RelateTo(carol, "KNOWS")
+
+	// Nobody knows someone who knows them back
+	table, err := Evaluate(graph, "match (a:Person)-[r:KNOWS]->(b:Person)-[s:KNOWS]->(a) return a, b, r, s")
+	NewTableTester(t, table, err).HasLen(0)
+
+	table, err = Evaluate(graph, "match (a:Person)-[r:KNOWS]->(b:Person)-[s:KNOWS]->(a) using approximate return a, b, r, s")
+	NewTableTester(t, table, err).HasLen(2).HasColumns("a", "b", "r", "s")
+	for i := 0; err == nil && i < table.Len(); i++ {
+		r, s := table.Get(i, "r"), table.Get(i, "s")
+		if (r == nil) == (s == nil) {
+			t.Error("Expected a single relation to be missing, got ", r, s)
+		}
+	}
+
+	// The robot is no person, which is tolerated as a single difference
+	table, err = Evaluate(graph, "match (a:Person)-[:KNOWS]->(b:Person)-[:KNOWS]->(c:Person) using approximate 1 return c")
+	NewTableTester(t, table, err).HasLen(1)
+	if err == nil && table.Get(0, "c") != carol {
+		t.Error("Expected the robot to be tolerated, got ", table.Get(0, "c"))
+	}
+
+	table, err = Evaluate(graph, "start a=node(0) match (a)-[:LIKES]->(b) using approximate return b")
+	NewTableTester(t, table, err).HasLen(2)
+
+	// The cheapest matches come first
+	table, err = Evaluate(graph, "match (a:Person)-[:KNOWS]->(b:Person) using approximate 1 as cost return a, b, cost")
+	NewTableTester(t, table, err).HasColumns("a", "b", "cost")
+	if err == nil && (table.Len() < 2 || table.Get(0, "cost") != 0.0 || table.Get(0, "b") != bob) {
+		t.Fatal("Expected the exact match first, got ", table)
+	}
+	for i := 1; err == nil && i < table.Len(); i++ {
+		if table.Get(i-1, "cost").(float64) > table.Get(i, "cost").(float64) {
+			t.Error("Expected matches ranked by cost, got ", table)
+		}
+	}
+
+	// Without tolerance parallel relations are matched as by exact matches
+	universe := setupTestDb(t)
+	exact, err := Evaluate(universe, "match (a)-->(b)-->(c) return count(a) as c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err = Evaluate(universe, "match (a)-->(b)-->(c) using approximate 0 return count(a) as c")
+	NewTableTester(t, table, err).Has("c", exact.Get(0, "c"))
+}
+
 func TestPathVariable(t *testing.T) {
 	db := setupTestDb(t)
 
@@ -108,6 +262,68 @@ func TestFunctionCount(t *testing.T) {
 	NewTableTester(t, table, err).Has("nrArcs", 1)
 }
 
+func TestShortestPath(t *testing.T) {
+	graph := setupTestDb(t)
+
+	table, err := Evaluate(graph, `match p = shortestPath((a:Episode {episode: "1"})-[:LEADS_TO*..20]->(b:Episode {episode: "14"})) return p`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 1 {
+		t.Fatal("Should find a single path, found ", table.Len())
+	}
+	if path := table.Get(0, "p").(db.Path); len(path.Relations()) != 13 {
+		t.Error("Should follow all episodes, got ", path)
+	}
+
+	// Story arcs and characters are shortcuts
+	table, err = Evaluate(graph, `match p = shortestPath((a:Episode {episode: "1"})-[r*]-(b:Episode {episode: "14"})) return p, r`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := table.Get(0, "p").(db.Path); len(path.Relations()) >= 13 {
+		t.Error("Should take a shortcut, got ", path)
+	}
+	if rels := table.Get(0, "r").([]db.Relation); len(rels) != len(table.Get(0, "p").(db.Path).Relations()) {
+		t.Error("Should bind the relations of the path, got ", rels)
+	}
+
+	table, err = Evaluate(graph, `match p = shortestPath((a:Episode {episode: "1"})-[:LEADS_TO*..5]->(b:Episode {episode: "14"})) return p`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 0 {
+		t.Error("Should not find paths longer than allowed, got ", table.Get(0, "p"))
+	}
+}
+
+func TestAllShortestPaths(t *testing.T) {
+	graph := setupTestDb(t)
+
+	table, err := Evaluate(graph, `match (a:Episode {episode: "2"}), (b:Episode {episode: "10"}), p = allShortestPaths((a)-[:APPEARED_IN*]-(b)) return p`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	NewTableTester(t, table, err).HasLen(1)
+	if path := table.Get(0, "p").(db.Path); !strings.Contains(path.String(), "Adelai Niska") {
+		t.Error("Should find the path via Niska, got ", path)
+	}
+
+	table, err = Evaluate(graph, `explain match p = allShortestPaths((a:Episode {episode: "2"})-[:APPEARED_IN*]-(b:Episode {episode: "10"})) return p`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := operators(table.Plan()); ops[1] != "AllShortestPaths" {
+		t.Error("Should search paths after finding the end nodes, got ", ops)
+	}
+
+	_, err = Evaluate(graph, `match p = shortestPath((a)-[*]->(b)-->(c)) return p`)
+	var semanticErr *SemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Error("Should only search single relations, got ", err)
+	}
+}
+
 func TestErrorBehaviour(t *testing.T) {
 	db := setupTestDb(t)
 
@@ -119,6 +335,50 @@ func TestErrorBehaviour(t *testing.T) {
 	if !strings.Contains(err.Error(), "bad") {
 		t.Fatal("Should contain bad character error")
 	}
+
+	var syntaxErr *gcy.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Should be a syntax error, is %T", err)
+	}
+}
+
+func TestSemanticErrors(t *testing.T) {
+	db := setupTestDb(t)
+
+	queries := []string{
+		"match (e:Episode) return e2",
+		"match (e:Episode) return sum(e)",
+		"match (e:Episode) return count(e, e)",
+		"start n=node(0) return count(m)",
+		"match (e:Episode)-[*0..2]->(e2) return e2",
+		"match (e:Episode)-[:LEADS_TO*]->(e2) using approximate return e2",
+	}
+
+	for _, query := range queries {
+		_, err := Evaluate(db, query)
+
+		var semanticErr *SemanticError
+		if !errors.As(err, &semanticErr) {
+			t.Errorf("%s: should be a semantic error, is %v", query, err)
+		}
+	}
+}
+
+func TestEntityNotFound(t *testing.T) {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "start n=node(0, 100000) return n")
+	if table != nil {
+		t.Error("Should not return partial results")
+	}
+
+	var notFoundErr *EntityNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("Should be an entity not found error, is %v", err)
+	}
+	if notFoundErr.Kind != "node" || notFoundErr.Id != 100000 {
+		t.Error("Should report the missing node, got ", notFoundErr)
+	}
 }
 
 type TableTester struct {
@@ -0,0 +1,118 @@
+package goneo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limits restricts the resources a single query may use. Zero values do not
+// limit anything. Evaluation is aborted with a *LimitExceededError as soon as
+// a limit is exceeded.
+type Limits struct {
+	// MaxRows is the number of rows a query may return.
+	MaxRows int
+	// MaxIntermediate is the number of rows all operators of a query may
+	// produce together, including partial matches tried by the subgraph
+	// matcher.
+	MaxIntermediate int
+	// MaxMemory is the estimated number of bytes a query may hold, e.g. in
+	// hash tables, aggregations or collected results.
+	MaxMemory int64
+	// Timeout is the wall-clock time a query may take.
+	Timeout time.Duration
+}
+
+// LimitExceededError reports which limit aborted the evaluation.
+type LimitExceededError struct {
+	Limit string // name of the field in Limits
+	Max   int64
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Limit == "Timeout" {
+		return fmt.Sprintf("query exceeded timeout of %s", time.Duration(e.Max))
+	}
+	return fmt.Sprintf("query exceeded limit %s of %d", e.Limit, e.Max)
+}
+
+// Unwrap allows checking timeouts with errors.Is(err, context.DeadlineExceeded).
+func (e *LimitExceededError) Unwrap() error {
+	if e.Limit == "Timeout" {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+type limitsKey struct{}
+
+// WithLimits returns a context limiting all queries evaluated with it. If ctx
+// already carries limits, the stricter limit of both is used.
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	if outer, ok := ctx.Value(limitsKey{}).(Limits); ok {
+		limits = outer.stricter(limits)
+	}
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// limitsFrom returns the limits of a context, or no limits at all.
+func limitsFrom(ctx context.Context) Limits {
+	limits, _ := ctx.Value(limitsKey{}).(Limits)
+	return limits
+}
+
+func (l Limits) stricter(o Limits) Limits {
+	min := func(a, b int64) int64 {
+		if a == 0 || (b != 0 && b < a) {
+			return b
+		}
+		return a
+	}
+
+	return Limits{
+		MaxRows:         int(min(int64(l.MaxRows), int64(o.MaxRows))),
+		MaxIntermediate: int(min(int64(l.MaxIntermediate), int64(o.MaxIntermediate))),
+		MaxMemory:       min(l.MaxMemory, o.MaxMemory),
+		Timeout:         time.Duration(min(int64(l.Timeout), int64(o.Timeout))),
+	}
+}
+
+// withTimeout applies the timeout limit to the context of an evaluation.
+func (l Limits) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, l.Timeout, &LimitExceededError{"Timeout", int64(l.Timeout)})
+}
+
+// produced counts rows produced by operators, returns false if evaluation
+// has to stop.
+func (ctx *evalContext) produced(n int) bool {
+	if ctx.limits.MaxIntermediate > 0 {
+		ctx.intermediate += n
+		if ctx.intermediate > ctx.limits.MaxIntermediate {
+			ctx.fail(&LimitExceededError{"MaxIntermediate", int64(ctx.limits.MaxIntermediate)})
+			return false
+		}
+	}
+	return !ctx.cancelled()
+}
+
+// hold accounts for a row kept in memory, returns false if evaluation has to
+// stop. Memory is only estimated and never released during an evaluation.
+func (ctx *evalContext) hold(r row) bool {
+	if ctx.limits.MaxMemory > 0 {
+		ctx.memory += rowSize(r)
+		if ctx.memory > ctx.limits.MaxMemory {
+			ctx.fail(&LimitExceededError{"MaxMemory", ctx.limits.MaxMemory})
+			return false
+		}
+	}
+	return !ctx.cancelled()
+}
+
+// rowSize estimates the bytes of a row, entities are shared and not counted.
+func rowSize(r row) int64 {
+	const mapOverhead, bindingSize = 48, 32
+	return mapOverhead + int64(len(r))*bindingSize
+}
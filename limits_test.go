@@ -0,0 +1,130 @@
+package goneo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func expectLimit(t *testing.T, err error, limit string) {
+	t.Helper()
+
+	var limitErr *LimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatal("Expected exceeded limit, got ", err)
+	}
+	if limitErr.Limit != limit {
+		t.Errorf("Expected limit %s to be exceeded, got %s", limit, limitErr.Limit)
+	}
+}
+
+func TestLimitRows(t *testing.T) {
+	db := setupTestDb(t)
+
+	ctx := WithLimits(context.Background(), Limits{MaxRows: 3})
+
+	rows, err := EvaluateContext(ctx, db, "start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := rows.Table()
+	if table != nil {
+		t.Error("Should not return partial results")
+	}
+	expectLimit(t, err, "MaxRows")
+
+	rows, err = EvaluateContext(ctx, db, "start n=node(0, 1, 2) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rows.Table(); err != nil {
+		t.Error("Should allow results within the limit, got ", err)
+	}
+}
+
+func TestLimitIntermediate(t *testing.T) {
+	db := setupTestDb(t)
+
+	ctx := WithLimits(context.Background(), Limits{MaxIntermediate: 10})
+
+	// Only a single row is returned, but many are needed to count
+	rows, err := EvaluateContext(ctx, db, "match (a)--(b) return count(a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rows.Table()
+	expectLimit(t, err, "MaxIntermediate")
+
+	// The subgraph matcher counts partial matches
+	rows, err = EvaluateContext(ctx, db, "match (e:Episode)-[:LEADS_TO*]->(e2) return count(e2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rows.Table()
+	expectLimit(t, err, "MaxIntermediate")
+}
+
+func TestLimitMemory(t *testing.T) {
+	db := setupTestDb(t)
+
+	ctx := WithLimits(context.Background(), Limits{MaxMemory: 1024})
+
+	rows, err := EvaluateContext(ctx, db, "start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rows.Table()
+	expectLimit(t, err, "MaxMemory")
+
+	// Streaming does not hold rows
+	rows, err = EvaluateContext(ctx, db, "start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Error("Streaming rows should not need memory, got ", err)
+	}
+
+	// Products buffer one side while streaming
+	rows, err = EvaluateContext(ctx, db, "match (a:Tag), (b) return a, b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	expectLimit(t, rows.Err(), "MaxMemory")
+}
+
+func TestLimitTimeout(t *testing.T) {
+	db := setupTestDb(t)
+
+	ctx := WithLimits(context.Background(), Limits{Timeout: time.Nanosecond})
+
+	rows, err := EvaluateContext(ctx, db, "match (a)--(b) return count(a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// results are evaluated lazily, the timeout passes before the first one
+	<-rows.eval.ctx.Done()
+	_, err = rows.Table()
+
+	expectLimit(t, err, "Timeout")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Timeouts should be deadline errors")
+	}
+}
+
+func TestStricterLimits(t *testing.T) {
+	ctx := WithLimits(context.Background(), Limits{MaxRows: 10, Timeout: time.Second})
+	ctx = WithLimits(ctx, Limits{MaxRows: 100, MaxMemory: 1024})
+
+	limits := limitsFrom(ctx)
+	if limits.MaxRows != 10 || limits.MaxMemory != 1024 || limits.Timeout != time.Second || limits.MaxIntermediate != 0 {
+		t.Error("Should use the stricter limits, got ", limits)
+	}
+}
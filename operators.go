@@ -0,0 +1,1284 @@
+package goneo
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/gcy"
+	"github.com/BuJo/goneo/log"
+	"github.com/BuJo/goneo/sgi"
+)
+
+type (
+	// argumentOp produces the single empty row every query starts with.
+	argumentOp struct {
+		operatorStats
+	}
+
+	// rootOp looks up nodes or relations by id, or scans all of them.
+	rootOp struct {
+		operatorStats
+
+		input operator
+		r     *gcy.Root
+	}
+
+	// subgraphMatchOp finds a pattern by subgraph isomorphism.
+	subgraphMatchOp struct {
+		operatorStats
+
+		input operator
+		m     *gcy.Match
+
+		// variables already bound by the input
+		anchors []string
+
+		subgraph           DatabaseService
+		subgraphNameMap    map[string]int
+		subgraphRevNameMap map[int]string
+
+		// names and allowed types of pattern relations, by relation
+		subgraphRelNames map[int]string
+		subgraphRelTypes map[int][]string
+
+		// properties compared with parameters, by pattern node
+		subgraphParams map[int]map[string]string
+	}
+
+	// projectionOp computes the returned values per row.
+	projectionOp struct {
+		operatorStats
+
+		input operator
+		r     []*gcy.Returnable
+	}
+
+	// aggregationOp groups by all non aggregated values and computes the
+	// aggregates per group.
+	aggregationOp struct {
+		operatorStats
+
+		input operator
+		r     []*gcy.Returnable
+	}
+)
+
+func (op *argumentOp) describe() (string, string) { return "Argument", "" }
+func (op *argumentOp) identifiers() []string      { return nil }
+func (op *argumentOp) inputs() []operator         { return nil }
+
+func (op *argumentOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) { yield(row{}) }
+}
+
+func (op *rootOp) describe() (string, string) {
+	typ := "Node"
+	if op.r.Typ != "node" {
+		typ = "Relationship"
+	}
+
+	if op.scansAll() {
+		return "All" + typ + "sScan", op.r.Name
+	}
+	if op.r.Param != "" {
+		return typ + "ByIdSeek", op.r.Name + " IN $" + op.r.Param
+	}
+
+	ids := make([]string, 0, len(op.r.IdVars))
+	for _, id := range op.r.IdVars {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	return typ + "ByIdSeek", op.r.Name + " IN [" + strings.Join(ids, ", ") + "]"
+}
+func (op *rootOp) identifiers() []string { return appendIdentifier(op.input.identifiers(), op.r.Name) }
+func (op *rootOp) inputs() []operator    { return []operator{op.input} }
+
+func (op *rootOp) scansAll() bool {
+	return len(op.r.IdVars) == 1 && op.r.IdVars[0] == -1
+}
+
+func (op *rootOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			entities, err := op.entities(ctx)
+			if err != nil {
+				ctx.fail(err)
+				return
+			}
+			for _, o := range entities {
+				if ctx.cancelled() || !yield(in.with(op.r.Name, o)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// entities looks up all nodes or relations a root refers to.
+func (op *rootOp) entities(ctx *evalContext) ([]PropertyContainer, error) {
+	r := op.r
+	db := ctx.dbFor(op)
+	entities := make([]PropertyContainer, 0)
+
+	ids := r.IdVars
+	if r.Param != "" {
+		var err error
+		if ids, err = idsParameter(r.Param, ctx.params[r.Param]); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Typ == "node" {
+		if op.scansAll() {
+			for _, node := range db.GetAllNodes() {
+				entities = append(entities, node)
+			}
+		} else {
+			for _, id := range ids {
+				node, err := db.GetNode(id)
+				if err != nil {
+					return nil, &EntityNotFoundError{"node", id}
+				}
+				entities = append(entities, node)
+			}
+		}
+	} else {
+		if op.scansAll() {
+			for _, rel := range db.GetAllRelations() {
+				entities = append(entities, rel)
+			}
+		} else {
+			for _, id := range ids {
+				rel, err := db.GetRelation(id)
+				if err != nil {
+					return nil, &EntityNotFoundError{"relationship", id}
+				}
+				entities = append(entities, rel)
+			}
+		}
+	}
+
+	log.Print("handled root: ", r, ", entities: ", entities)
+
+	return entities, nil
+}
+
+// idsParameter converts the value of a parameter to entity ids. Single ids
+// and lists of ids are supported, numbers may come from decoded JSON.
+func idsParameter(name string, value interface{}) ([]int, error) {
+	toId := func(v interface{}) (int, bool) {
+		switch id := v.(type) {
+		case int:
+			return id, true
+		case int64:
+			return int(id), true
+		case float64:
+			return int(id), id == math.Trunc(id)
+		case string:
+			i, err := strconv.Atoi(id)
+			return i, err == nil
+		}
+		return 0, false
+	}
+
+	var values []interface{}
+	switch v := value.(type) {
+	case []int:
+		return v, nil
+	case []interface{}:
+		values = v
+	default:
+		values = []interface{}{v}
+	}
+
+	ids := make([]int, 0, len(values))
+	for _, v := range values {
+		id, ok := toId(v)
+		if !ok {
+			return nil, &SemanticError{fmt.Sprintf("parameter `%s` must contain ids, got %v", name, v)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// BUG(jo): db cannot encode undirected graph
+
+func newSubgraphMatchOp(input operator, m *gcy.Match) *subgraphMatchOp {
+	op := &subgraphMatchOp{
+		input:              input,
+		m:                  m,
+		subgraphNameMap:    make(map[string]int),
+		subgraphRevNameMap: make(map[int]string),
+		subgraphParams:     make(map[int]map[string]string),
+		subgraphRelNames:   make(map[int]string),
+		subgraphRelTypes:   make(map[int][]string),
+	}
+
+	subgraph, _ := OpenDb("mem:temporary")
+	op.subgraph = subgraph
+
+	for _, p := range m.Paths {
+		var builder *PathBuilder
+		for currentNode := p.Start; currentNode != nil; {
+			var n Node
+
+			if currentNode.Name != "" {
+				if id, ok := op.subgraphNameMap[currentNode.Name]; ok {
+					n, _ = subgraph.GetNode(id)
+					log.Print("tried to find node name ", currentNode.Name, ", found", n)
+				}
+			}
+
+			if n == nil {
+				n = subgraph.NewNode(currentNode.Labels...)
+				op.subgraphNameMap[currentNode.Name] = n.Id()
+				op.subgraphRevNameMap[n.Id()] = currentNode.Name
+
+				log.Print("created new node: ", n, "(", currentNode, ")")
+
+				for k, v := range currentNode.Props {
+					n.SetProperty(k, v)
+				}
+			}
+			if len(currentNode.Params) > 0 {
+				if op.subgraphParams[n.Id()] == nil {
+					op.subgraphParams[n.Id()] = make(map[string]string)
+				}
+				for k, param := range currentNode.Params {
+					op.subgraphParams[n.Id()][k] = param
+				}
+			}
+
+			if builder == nil {
+				log.Print("first run, node: ", n, "(", currentNode, ")")
+				builder = NewPathBuilder(n)
+			} else {
+				prevNode := builder.Last()
+				log.Print("next run, ", prevNode, "->", n, "(", currentNode, ")")
+
+				// TODO: utter crap, path is specific, has no "optional variants"
+				typ := ""
+				if ok := len(currentNode.LeftRel.Types) > 0; ok {
+					typ = currentNode.LeftRel.Types[0]
+				}
+				var rel Relation
+				if currentNode.LeftRel.Direction == "->" {
+					rel = prevNode.RelateTo(n, typ)
+				} else {
+					rel = n.RelateTo(prevNode, typ)
+				}
+
+				op.subgraphRelTypes[rel.Id()] = currentNode.LeftRel.Types
+				if currentNode.LeftRel.Name != "" && currentNode.LeftRel.Cardinality == "" {
+					op.subgraphRelNames[rel.Id()] = currentNode.LeftRel.Name
+				}
+
+				builder = builder.Append(rel)
+			}
+
+			if currentNode.RightRel == nil {
+				currentNode = nil
+			} else {
+				currentNode = currentNode.RightRel.RightNode
+			}
+		}
+	}
+
+	for _, name := range input.identifiers() {
+		if _, ok := op.subgraphNameMap[name]; ok {
+			op.anchors = append(op.anchors, name)
+		}
+	}
+
+	return op
+}
+
+func (op *subgraphMatchOp) describe() (string, string) {
+	paths := make([]string, 0, len(op.m.Paths))
+	for _, p := range op.m.Paths {
+		paths = append(paths, p.String())
+	}
+	details := strings.Join(paths, ", ")
+
+	if len(op.anchors) > 0 {
+		details += "; anchors: " + strings.Join(op.anchors, ", ")
+	}
+	if op.m.Approximate {
+		details += fmt.Sprintf("; approximate: %d", op.m.Tolerance)
+	}
+
+	return "SubgraphMatch", details
+}
+
+func (op *subgraphMatchOp) identifiers() []string {
+	ids := op.input.identifiers()
+	for name := range op.subgraphNameMap {
+		ids = appendIdentifier(ids, name)
+	}
+	for _, name := range op.subgraphRelNames {
+		ids = appendIdentifier(ids, name)
+	}
+	if op.m.Cost != "" {
+		ids = appendIdentifier(ids, op.m.Cost)
+	}
+	return ids
+}
+
+func (op *subgraphMatchOp) inputs() []operator { return []operator{op.input} }
+
+func (op *subgraphMatchOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			if !op.executeRow(ctx, in, yield) {
+				return
+			}
+		}
+	}
+}
+
+// executeRow yields all matches extending the given bindings, returns false
+// if evaluation should stop.
+func (op *subgraphMatchOp) executeRow(ctx *evalContext, in row, yield func(row) bool) bool {
+	db := ctx.dbFor(op)
+
+	knownMappings := map[string]int{}
+	for _, name := range op.anchors {
+		if n, ok := in[name].(Node); ok {
+			knownMappings[name] = n.Id()
+		}
+	}
+
+	// required pairs hold even for approximate matches
+	required := func(state sgi.State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		if !ctx.produced(1) {
+			return false
+		}
+		if ctx.profile {
+			ctx.executed(op).candidates += 1
+		}
+
+		if name, hasName := op.subgraphRevNameMap[toQueryNode]; hasName {
+			if t2, hasMapping := knownMappings[name]; hasMapping {
+				return t2 == toTargetNode
+			}
+		}
+		return true
+	}
+
+	same := func(state sgi.State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		if params, ok := op.subgraphParams[toQueryNode]; ok {
+			t2, _ := db.GetNode(toTargetNode)
+			for k, param := range params {
+				if !t2.HasProperty(k) || t2.Property(k) != fmt.Sprint(ctx.params[param]) {
+					return false
+				}
+			}
+		}
+
+		return isSemanticallyFeasable(state, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode)
+	}
+
+	efeas := func(queryEdge, targetEdge int) bool {
+		return isEdgeFeasable(db, op.subgraphRelTypes[queryEdge], targetEdge)
+	}
+
+	bind := func(embedding sgi.Embedding) (row, bool) {
+		out := in
+		for q, t := range embedding.Nodes {
+			node, err := db.GetNode(t)
+			if err != nil {
+				ctx.fail(&EntityNotFoundError{"node", t})
+				return nil, false
+			}
+			out = out.with(op.subgraphRevNameMap[q], node)
+		}
+		for q, t := range embedding.Edges {
+			name, ok := op.subgraphRelNames[q]
+			if !ok {
+				continue
+			}
+			rel, err := db.GetRelation(t)
+			if err != nil {
+				ctx.fail(&EntityNotFoundError{"relation", t})
+				return nil, false
+			}
+			out = out.with(name, rel)
+		}
+		return out, true
+	}
+
+	if op.m.Approximate {
+		// Matches are ranked by cost, so they are held until the search
+		// is done. Missing relations stay unbound.
+		type match struct {
+			out row
+			m   sgi.ApproximateMapping
+		}
+		var matches []match
+		opts := sgi.ApproximateOptions{MaxDifferences: op.m.Tolerance, Required: required}
+		sgi.FindApproximateMappingsFunc(&dbGraph{op.subgraph}, &dbGraph{db}, same, efeas, opts, func(m sgi.ApproximateMapping) bool {
+			out, ok := bind(m.Embedding)
+			if !ok {
+				return false
+			}
+			if op.m.Cost != "" {
+				out = out.with(op.m.Cost, m.Cost)
+			}
+			matches = append(matches, match{out, m})
+			return ctx.hold(out)
+		})
+		if ctx.cancelled() {
+			return false
+		}
+
+		slices.SortStableFunc(matches, func(a, b match) int {
+			if a.m.Cost != b.m.Cost {
+				return cmp.Compare(a.m.Cost, b.m.Cost)
+			}
+			return a.m.Differences() - b.m.Differences()
+		})
+		for _, match := range matches {
+			if ctx.cancelled() || !yield(match.out) {
+				return false
+			}
+		}
+		return !ctx.cancelled()
+	}
+
+	cont := true
+	visit := func(embedding sgi.Embedding) bool {
+		out, ok := bind(embedding)
+		cont = ok && !ctx.cancelled() && yield(out)
+		return cont
+	}
+
+	fsem := func(state sgi.State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		return required(state, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode) &&
+			same(state, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode)
+	}
+	sgi.FindEmbeddingsFunc(&dbGraph{op.subgraph}, &dbGraph{db}, fsem, efeas, sgi.Monomorphism, visit)
+
+	return cont && !ctx.cancelled()
+}
+
+func (op *projectionOp) describe() (string, string) { return "Projection", describeReturnables(op.r) }
+func (op *projectionOp) identifiers() []string      { return returnColumns(op.r) }
+func (op *projectionOp) inputs() []operator         { return []operator{op.input} }
+
+func (op *projectionOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			line := make(row, len(op.r))
+			for _, r := range op.r {
+				line[r.Alias] = evaluateReturnable(in, r)
+			}
+
+			if !yield(line) {
+				return
+			}
+		}
+	}
+}
+
+func (op *aggregationOp) describe() (string, string) {
+	return "EagerAggregation", describeReturnables(op.r)
+}
+func (op *aggregationOp) identifiers() []string { return returnColumns(op.r) }
+func (op *aggregationOp) inputs() []operator    { return []operator{op.input} }
+
+func (op *aggregationOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		type group struct {
+			line   row
+			counts map[string]int
+		}
+		groups := make([]*group, 0)
+		groupsByKey := make(map[string]*group)
+
+		for in := range run(ctx, op.input) {
+			key := ""
+			line := make(row, len(op.r))
+			for _, r := range op.r {
+				if !isAggregate(r) {
+					line[r.Alias] = evaluateReturnable(in, r)
+					key += fmt.Sprintf("%T:%v|", line[r.Alias], line[r.Alias])
+				}
+			}
+
+			g, ok := groupsByKey[key]
+			if !ok {
+				if !ctx.hold(line) {
+					return
+				}
+				g = &group{line, make(map[string]int)}
+				groupsByKey[key] = g
+				groups = append(groups, g)
+			}
+
+			for _, r := range op.r {
+				if isAggregate(r) {
+					for _, v := range r.Vars {
+						if evaluateReturnable(in, v) != nil {
+							g.counts[r.Alias] += 1
+						}
+					}
+				}
+			}
+		}
+
+		if ctx.cancelled() {
+			return
+		}
+
+		if len(groups) == 0 && op.onlyAggregates() {
+			groups = append(groups, &group{make(row), make(map[string]int)})
+		}
+
+		for _, g := range groups {
+			for _, r := range op.r {
+				if isAggregate(r) {
+					g.line[r.Alias] = g.counts[r.Alias]
+				}
+			}
+			if !yield(g.line) {
+				return
+			}
+		}
+	}
+}
+
+func (op *aggregationOp) onlyAggregates() bool {
+	for _, r := range op.r {
+		if !isAggregate(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAggregate(r *gcy.Returnable) bool {
+	return r.Type == "function" && r.Object == "count"
+}
+
+// evaluateReturnable computes a single non aggregated value from bound
+// variables.
+func evaluateReturnable(in row, r *gcy.Returnable) interface{} {
+	switch r.Type {
+	case "int", "float", "string":
+		return r.Value
+	case "variable":
+		o := in[r.Object]
+		if pc, ok := o.(PropertyContainer); ok && r.Field != "" {
+			return pc.Property(r.Field)
+		}
+		return o
+	}
+
+	return nil
+}
+
+func returnColumns(rets []*gcy.Returnable) []string {
+	cols := make([]string, 0, len(rets))
+	for _, r := range rets {
+		cols = append(cols, r.Alias)
+	}
+	return cols
+}
+
+func describeReturnables(rets []*gcy.Returnable) string {
+	strs := make([]string, 0, len(rets))
+	for _, r := range rets {
+		if r.Alias != r.Name {
+			strs = append(strs, r.Name+" AS "+r.Alias)
+		} else {
+			strs = append(strs, r.Name)
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
+// appendIdentifier adds a variable name if not already known.
+func appendIdentifier(ids []string, name string) []string {
+	if name == "" {
+		return ids
+	}
+	for _, id := range ids {
+		if id == name {
+			return ids
+		}
+	}
+	return append(append(make([]string, 0, len(ids)+1), ids...), name)
+}
+
+type (
+	// allNodesScanOp binds every node of the database.
+	allNodesScanOp struct {
+		operatorStats
+
+		input    operator
+		variable string
+		distinct []string
+	}
+
+	// nodeByLabelScanOp binds all nodes having a label.
+	nodeByLabelScanOp struct {
+		operatorStats
+
+		input    operator
+		variable string
+		label    string
+		distinct []string
+	}
+
+	// nodeIndexSeekOp binds all nodes having a property value.
+	nodeIndexSeekOp struct {
+		operatorStats
+
+		input    operator
+		variable string
+		prop     string
+		value    propertyValue
+		distinct []string
+	}
+
+	// expandOp follows relations from a bound node. Expanding into an already
+	// bound node only checks for the existence of a relation, unless the
+	// relation is bound to a variable. Variable length relations are followed
+	// along paths not repeating a relation, their variable is bound to the
+	// relations of the path.
+	expandOp struct {
+		operatorStats
+
+		input     operator
+		from, to  string
+		rel       string
+		types     []string
+		direction Direction
+		into      bool
+
+		cardinality      string
+		minHops, maxHops int
+
+		// nodes the new node has to differ from
+		distinct []string
+	}
+
+	// filterOp drops rows where a node misses labels or properties, or
+	// where nodes are not distinct.
+	filterOp struct {
+		operatorStats
+
+		input    operator
+		variable string
+		labels   []string
+		props    map[string]propertyValue
+
+		distinct []string
+	}
+
+	// hashJoinOp joins rows of both inputs having the same nodes bound to
+	// the key variables.
+	hashJoinOp struct {
+		operatorStats
+
+		left, right operator
+		keys        []string
+	}
+
+	// cartesianProductOp combines every row of the left with every row of the
+	// right input.
+	cartesianProductOp struct {
+		operatorStats
+
+		left, right operator
+	}
+)
+
+func (op *allNodesScanOp) describe() (string, string) { return "AllNodesScan", op.variable }
+func (op *allNodesScanOp) identifiers() []string {
+	return appendIdentifier(op.input.identifiers(), op.variable)
+}
+func (op *allNodesScanOp) inputs() []operator { return []operator{op.input} }
+
+func (op *allNodesScanOp) execute(ctx *evalContext) iter.Seq[row] {
+	return scanNodes(ctx, op, op.input, op.variable, op.distinct, func(db DatabaseService) []Node {
+		return db.GetAllNodes()
+	})
+}
+
+func (op *nodeByLabelScanOp) describe() (string, string) {
+	return "NodeByLabelScan", op.variable + ":" + op.label
+}
+func (op *nodeByLabelScanOp) identifiers() []string {
+	return appendIdentifier(op.input.identifiers(), op.variable)
+}
+func (op *nodeByLabelScanOp) inputs() []operator { return []operator{op.input} }
+
+func (op *nodeByLabelScanOp) execute(ctx *evalContext) iter.Seq[row] {
+	return scanNodes(ctx, op, op.input, op.variable, op.distinct, func(db DatabaseService) []Node {
+		return findNodesByLabel(db, op.label)
+	})
+}
+
+// findNodesByLabel uses the label index of the database if possible.
+func findNodesByLabel(db DatabaseService, label string) []Node {
+	if index, ok := db.(LabelIndex); ok {
+		return index.FindNodesByLabel(label)
+	}
+
+	nodes := make([]Node, 0)
+	for _, n := range db.GetAllNodes() {
+		if n.HasLabel(label) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func (op *nodeIndexSeekOp) describe() (string, string) {
+	return "NodeIndexSeek", fmt.Sprintf("%s.%s = %s", op.variable, op.prop, op.value)
+}
+func (op *nodeIndexSeekOp) identifiers() []string {
+	return appendIdentifier(op.input.identifiers(), op.variable)
+}
+func (op *nodeIndexSeekOp) inputs() []operator { return []operator{op.input} }
+
+func (op *nodeIndexSeekOp) execute(ctx *evalContext) iter.Seq[row] {
+	return scanNodes(ctx, op, op.input, op.variable, op.distinct, func(db DatabaseService) []Node {
+		return db.FindNodeByProperty(op.prop, op.value.resolve(ctx))
+	})
+}
+
+// scanNodes binds the found nodes to the variable for every input row.
+func scanNodes(ctx *evalContext, op operator, input operator, variable string, distinct []string, find func(DatabaseService) []Node) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		var nodes []Node
+
+		for in := range run(ctx, input) {
+			if nodes == nil {
+				nodes = find(ctx.dbFor(op))
+			}
+
+			for _, n := range nodes {
+				if ctx.cancelled() {
+					return
+				}
+				if !distinctFrom(in, n, distinct) {
+					continue
+				}
+				if !yield(in.with(variable, n)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func newExpandOp(input operator, rel *patternRel, from, to string, direction Direction) *expandOp {
+	return &expandOp{
+		input:     input,
+		from:      from,
+		to:        to,
+		rel:       rel.name,
+		types:     rel.types,
+		direction: direction,
+
+		cardinality: rel.cardinality,
+		minHops:     rel.minHops,
+		maxHops:     rel.maxHops,
+	}
+}
+
+func (op *expandOp) describe() (string, string) {
+	name := "Expand(All)"
+	if op.into {
+		name = "Expand(Into)"
+	}
+	if op.cardinality != "" {
+		name = "VarLength" + name
+	}
+
+	rel := &gcy.Relation{Name: op.rel, Types: op.types, Direction: op.direction.String(), Cardinality: op.cardinality}
+	if op.direction == Both {
+		rel.Direction = "-"
+	}
+	return name, "(" + op.from + ")" + rel.String() + "(" + op.to + ")"
+}
+func (op *expandOp) identifiers() []string {
+	ids := appendIdentifier(op.input.identifiers(), op.to)
+	if op.rel != "" {
+		ids = appendIdentifier(ids, op.rel)
+	}
+	return ids
+}
+func (op *expandOp) inputs() []operator { return []operator{op.input} }
+
+func (op *expandOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			from, ok := in[op.from].(Node)
+			if !ok {
+				continue
+			}
+			to, _ := in[op.to].(Node)
+
+			if op.cardinality != "" {
+				if !op.expandPaths(ctx, in, from, to, yield) {
+					return
+				}
+				continue
+			}
+
+			rels := from.Relations(op.direction)
+			ctx.hit(op, len(rels))
+
+			for _, rel := range rels {
+				if ctx.cancelled() {
+					return
+				}
+				if len(op.types) > 0 && !containsString(op.types, rel.Type()) {
+					continue
+				}
+
+				other := rel.End()
+				if other.Id() == from.Id() {
+					other = rel.Start()
+				}
+
+				if !op.bindsRel(in, rel) {
+					continue
+				}
+
+				if op.into {
+					if other.Id() != to.Id() {
+						continue
+					}
+					if op.rel != "" {
+						if !yield(in.with(op.rel, rel)) {
+							return
+						}
+						continue
+					}
+					if !yield(in) {
+						return
+					}
+					// Existence of one relation is enough
+					break
+				}
+
+				if !distinctFrom(in, other, op.distinct) {
+					continue
+				}
+				out := in.with(op.to, other)
+				if op.rel != "" {
+					out = out.with(op.rel, rel)
+				}
+				if !yield(out) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// expandPaths yields the paths from the node within the length of the
+// relation, returns false if evaluation should stop.
+func (op *expandOp) expandPaths(ctx *evalContext, in row, from, to Node, yield func(row) bool) bool {
+	used := make(map[int]bool)
+	path := make([]Relation, 0)
+	found := false
+
+	var walk func(node Node) bool
+	walk = func(node Node) bool {
+		if len(path) >= op.minHops && op.bindsRel(in, path) {
+			switch {
+			case op.into && node.Id() == to.Id():
+				out := in
+				if op.rel != "" {
+					out = out.with(op.rel, slices.Clone(path))
+				} else if found {
+					// Existence of one path is enough
+					return true
+				}
+				found = true
+				if !yield(out) {
+					return false
+				}
+			case !op.into && distinctFrom(in, node, op.distinct):
+				out := in.with(op.to, node)
+				if op.rel != "" {
+					out = out.with(op.rel, slices.Clone(path))
+				}
+				if !yield(out) {
+					return false
+				}
+			}
+		}
+		if op.maxHops > 0 && len(path) == op.maxHops {
+			return true
+		}
+
+		rels := node.Relations(op.direction)
+		ctx.hit(op, len(rels))
+		for _, rel := range rels {
+			if ctx.cancelled() {
+				return false
+			}
+			if used[rel.Id()] || len(op.types) > 0 && !containsString(op.types, rel.Type()) {
+				continue
+			}
+
+			other := rel.End()
+			if other.Id() == node.Id() {
+				other = rel.Start()
+			}
+
+			used[rel.Id()] = true
+			path = append(path, rel)
+			cont := walk(other)
+			path = path[:len(path)-1]
+			used[rel.Id()] = false
+
+			if !cont {
+				return false
+			}
+		}
+		return true
+	}
+
+	return walk(from)
+}
+
+// bindsRel checks that a relation, or the relations of a path, may be bound
+// to the relationship variable, which may be bound already by another part
+// of the pattern.
+func (op *expandOp) bindsRel(in row, rel interface{}) bool {
+	bound, ok := in[op.rel]
+	if op.rel == "" || !ok {
+		return true
+	}
+
+	switch rel := rel.(type) {
+	case Relation:
+		b, ok := bound.(Relation)
+		return ok && b.Id() == rel.Id()
+	case []Relation:
+		b, ok := bound.([]Relation)
+		return ok && slices.EqualFunc(b, rel, func(x, y Relation) bool { return x.Id() == y.Id() })
+	}
+	return false
+}
+
+func (op *filterOp) describe() (string, string) {
+	predicates := make([]string, 0)
+	for _, l := range op.labels {
+		predicates = append(predicates, op.variable+":"+l)
+	}
+	for _, k := range sortedKeys(op.props) {
+		predicates = append(predicates, fmt.Sprintf("%s.%s = %s", op.variable, k, op.props[k]))
+	}
+	if len(op.distinct) > 0 {
+		predicates = append(predicates, "distinct("+strings.Join(op.distinct, ", ")+")")
+	}
+	return "Filter", strings.Join(predicates, " AND ")
+}
+func (op *filterOp) identifiers() []string { return op.input.identifiers() }
+func (op *filterOp) inputs() []operator    { return []operator{op.input} }
+
+func (op *filterOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			if op.matches(ctx, in) && !yield(in) {
+				return
+			}
+		}
+	}
+}
+
+func (op *filterOp) matches(ctx *evalContext, in row) bool {
+	if op.variable != "" {
+		n, ok := in[op.variable].(Node)
+		if !ok || !n.HasLabel(op.labels...) {
+			return false
+		}
+		for k, v := range op.props {
+			if !n.HasProperty(k) || n.Property(k) != v.resolve(ctx) {
+				return false
+			}
+		}
+	}
+
+	seen := make(map[int]bool, len(op.distinct))
+	for _, name := range op.distinct {
+		if n, ok := in[name].(Node); ok {
+			if seen[n.Id()] {
+				return false
+			}
+			seen[n.Id()] = true
+		}
+	}
+
+	return true
+}
+
+func (op *hashJoinOp) describe() (string, string) { return "HashJoin", strings.Join(op.keys, ", ") }
+func (op *hashJoinOp) identifiers() []string {
+	ids := op.left.identifiers()
+	for _, id := range op.right.identifiers() {
+		ids = appendIdentifier(ids, id)
+	}
+	return ids
+}
+func (op *hashJoinOp) inputs() []operator { return []operator{op.left, op.right} }
+
+func (op *hashJoinOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		table := make(map[string][]row)
+		for r := range run(ctx, op.right) {
+			if !ctx.hold(r) {
+				return
+			}
+			table[op.key(r)] = append(table[op.key(r)], r)
+		}
+		if ctx.cancelled() {
+			return
+		}
+
+		for l := range run(ctx, op.left) {
+			for _, r := range table[op.key(l)] {
+				if !yield(l.merge(r)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (op *hashJoinOp) key(r row) string {
+	key := ""
+	for _, k := range op.keys {
+		if n, ok := r[k].(Node); ok {
+			key += fmt.Sprintf("%d|", n.Id())
+		} else {
+			key += "-|"
+		}
+	}
+	return key
+}
+
+func (op *cartesianProductOp) describe() (string, string) { return "CartesianProduct", "" }
+func (op *cartesianProductOp) identifiers() []string {
+	ids := op.left.identifiers()
+	for _, id := range op.right.identifiers() {
+		ids = appendIdentifier(ids, id)
+	}
+	return ids
+}
+func (op *cartesianProductOp) inputs() []operator { return []operator{op.left, op.right} }
+
+func (op *cartesianProductOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		var rights []row
+
+		for l := range run(ctx, op.left) {
+			if rights == nil {
+				rights = make([]row, 0)
+				for r := range run(ctx, op.right) {
+					if !ctx.hold(r) {
+						return
+					}
+					rights = append(rights, r)
+				}
+			}
+
+			for _, r := range rights {
+				if ctx.cancelled() || !yield(l.merge(r)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// distinctFrom checks that a node is not yet bound to any of the variables.
+func distinctFrom(in row, n Node, variables []string) bool {
+	for _, name := range variables {
+		if o, ok := in[name].(Node); ok && o.Id() == n.Id() {
+			return false
+		}
+	}
+	return true
+}
+
+// shortestPathOp searches the shortest paths between two bound nodes.
+type shortestPathOp struct {
+	operatorStats
+
+	input    operator
+	path     *gcy.Path
+	from, to string
+	options  PathOptions
+	minHops  int
+}
+
+func newShortestPathOp(input operator, path *gcy.Path) *shortestPathOp {
+	rel := path.Start.RightRel
+
+	op := &shortestPathOp{input: input, path: path, from: path.Start.Name, to: rel.RightNode.Name, minHops: 1}
+	op.options.Types = rel.Types
+	switch rel.Direction {
+	case "->":
+		op.options.Direction = Outgoing
+	case "<-":
+		op.options.Direction = Incoming
+	}
+
+	if rel.Cardinality == "" {
+		op.options.MaxDepth = 1
+	} else {
+		op.options.MaxDepth, op.minHops = rel.MaxHops, rel.MinHops
+	}
+
+	op.estimated = input.stats().estimated
+	return op
+}
+
+func (op *shortestPathOp) describe() (string, string) {
+	if op.all() {
+		return "AllShortestPaths", op.path.String()
+	}
+	return "ShortestPath", op.path.String()
+}
+func (op *shortestPathOp) identifiers() []string {
+	ids := appendIdentifier(op.input.identifiers(), op.path.Start.RightRel.Name)
+	return appendIdentifier(ids, op.path.Name)
+}
+func (op *shortestPathOp) inputs() []operator { return []operator{op.input} }
+
+func (op *shortestPathOp) all() bool { return op.path.Function == "allShortestPaths" }
+
+func (op *shortestPathOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			from, ok := in[op.from].(Node)
+			if !ok {
+				continue
+			}
+			to, ok := in[op.to].(Node)
+			if !ok {
+				continue
+			}
+
+			var paths []Path
+			if op.all() {
+				paths = AllShortestPaths(from, to, op.options)
+			} else if path := ShortestPath(from, to, op.options); path != nil {
+				paths = []Path{path}
+			}
+
+			for _, path := range paths {
+				if ctx.cancelled() {
+					return
+				}
+				ctx.hit(op, len(path.Relations()))
+				if len(path.Relations()) < op.minHops {
+					continue
+				}
+
+				out := in.with(op.path.Name, path).with(op.path.Start.RightRel.Name, path.Relations())
+				if !yield(out) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// callOp calls a procedure for every input row.
+type callOp struct {
+	operatorStats
+
+	input operator
+	call  *gcy.Call
+	proc  *Procedure
+}
+
+func (op *callOp) describe() (string, string) {
+	args := make([]string, 0, len(op.call.Args))
+	for _, arg := range op.call.Args {
+		args = append(args, arg.Name)
+	}
+	details := op.call.Name + "(" + strings.Join(args, ", ") + ")"
+
+	if len(op.call.Yields) > 0 {
+		details += " YIELD " + describeReturnables(op.call.Yields)
+	}
+	return "ProcedureCall", details
+}
+func (op *callOp) identifiers() []string {
+	ids := op.input.identifiers()
+	for _, name := range op.outputs() {
+		ids = appendIdentifier(ids, name)
+	}
+	return ids
+}
+func (op *callOp) inputs() []operator { return []operator{op.input} }
+
+// outputs returns the names the yielded outputs are bound to.
+func (op *callOp) outputs() []string {
+	if len(op.call.Yields) == 0 {
+		return op.proc.Outputs
+	}
+	return returnColumns(op.call.Yields)
+}
+
+func (op *callOp) execute(ctx *evalContext) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for in := range run(ctx, op.input) {
+			args := make(ProcedureArgs, len(op.proc.Arguments))
+			for i, arg := range op.proc.Arguments {
+				args[i] = arg.Default
+			}
+			for i, arg := range op.call.Args {
+				if arg.Type == "parameter" {
+					args[i] = ctx.params[arg.Object]
+				} else {
+					args[i] = evaluateReturnable(in, arg)
+				}
+			}
+
+			records, err := op.proc.Call(ctx.ctx, ctx.dbFor(op), args)
+			if err != nil {
+				ctx.fail(err)
+				return
+			}
+
+			for record := range records {
+				if ctx.cancelled() {
+					return
+				}
+
+				out := in
+				if len(op.call.Yields) == 0 {
+					for _, name := range op.proc.Outputs {
+						out = out.with(name, record[name])
+					}
+				}
+				for _, y := range op.call.Yields {
+					out = out.with(y.Alias, record[y.Object])
+				}
+
+				if !yield(out) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package goneo
+
+import (
+	"context"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// Weighted path searches callable from gcy. Relation types are separated by
+// "|", directions are "out", "in" or "both".
+//
+//	db.dijkstra(start, end, weightProperty, [types, direction, defaultWeight]) yield path, cost
+//	db.aStar(start, end, weightProperty, xProperty, yProperty, [types, direction, defaultWeight]) yield path, cost
+//	db.kShortestPaths(start, end, k, weightProperty, [types, direction, defaultWeight]) yield index, path, cost
+func init() {
+	optional := []ProcedureArgument{{"types", ""}, {"direction", "both"}, {"defaultWeight", 1.0}}
+
+	RegisterProcedure("db.dijkstra", &Procedure{
+		Arguments: append([]ProcedureArgument{{Name: "start"}, {Name: "end"}, {Name: "weightProperty"}}, optional...),
+		Outputs:   []string{"path", "cost"},
+		Call:      dijkstraProcedure,
+	})
+	RegisterProcedure("db.aStar", &Procedure{
+		Arguments: append([]ProcedureArgument{{Name: "start"}, {Name: "end"}, {Name: "weightProperty"}, {Name: "xProperty"}, {Name: "yProperty"}}, optional...),
+		Outputs:   []string{"path", "cost"},
+		Call:      aStarProcedure,
+	})
+	RegisterProcedure("db.kShortestPaths", &Procedure{
+		Arguments: append([]ProcedureArgument{{Name: "start"}, {Name: "end"}, {Name: "k"}, {Name: "weightProperty"}}, optional...),
+		Outputs:   []string{"index", "path", "cost"},
+		Call:      kShortestPathsProcedure,
+	})
+}
+
+// weightedSearch holds the arguments common to weighted path searches.
+type weightedSearch struct {
+	start, end Node
+	weight     WeightFunc
+	options    PathOptions
+}
+
+// weightedSearchArgs reads start and end at the given positions, the weight
+// property and the optional arguments following it.
+func weightedSearchArgs(args ProcedureArgs, start, end, weight int) (*weightedSearch, error) {
+	s := new(weightedSearch)
+
+	var err error
+	if s.start, err = args.Node(start); err != nil {
+		return nil, err
+	}
+	if s.end, err = args.Node(end); err != nil {
+		return nil, err
+	}
+
+	prop, err := args.String(weight)
+	if err != nil {
+		return nil, err
+	}
+	types, err := args.String(len(args) - 3)
+	if err != nil {
+		return nil, err
+	}
+	direction, err := args.String(len(args) - 2)
+	if err != nil {
+		return nil, err
+	}
+	defaultWeight, err := args.Float(len(args) - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	s.weight = PropertyWeight(prop, defaultWeight)
+	s.options.Direction = DirectionFromString(direction)
+	if types != "" {
+		s.options.Types = strings.Split(types, "|")
+	}
+
+	return s, nil
+}
+
+// pathRecords produces a record per found path.
+func pathRecords(paths ...*WeightedPath) iter.Seq[map[string]interface{}] {
+	return func(yield func(map[string]interface{}) bool) {
+		for i, p := range paths {
+			if p == nil {
+				continue
+			}
+			if !yield(map[string]interface{}{"index": i, "path": p.Path, "cost": p.Cost}) {
+				return
+			}
+		}
+	}
+}
+
+func dijkstraProcedure(ctx context.Context, db DatabaseService, args ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	s, err := weightedSearchArgs(args, 0, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := Dijkstra(s.start, s.end, s.weight, s.options)
+	if err != nil {
+		return nil, err
+	}
+	return pathRecords(path), nil
+}
+
+func aStarProcedure(ctx context.Context, db DatabaseService, args ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	s, err := weightedSearchArgs(args, 0, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+	xProp, err := args.String(3)
+	if err != nil {
+		return nil, err
+	}
+	yProp, err := args.String(4)
+	if err != nil {
+		return nil, err
+	}
+
+	coordinate := func(n Node, prop string) float64 {
+		f, _ := strconv.ParseFloat(n.Properties()[prop], 64)
+		return f
+	}
+	endX, endY := coordinate(s.end, xProp), coordinate(s.end, yProp)
+	euclidean := func(n Node) float64 {
+		return math.Hypot(coordinate(n, xProp)-endX, coordinate(n, yProp)-endY)
+	}
+
+	path, err := AStar(s.start, s.end, s.weight, euclidean, s.options)
+	if err != nil {
+		return nil, err
+	}
+	return pathRecords(path), nil
+}
+
+func kShortestPathsProcedure(ctx context.Context, db DatabaseService, args ProcedureArgs) (iter.Seq[map[string]interface{}], error) {
+	s, err := weightedSearchArgs(args, 0, 1, 3)
+	if err != nil {
+		return nil, err
+	}
+	k, err := args.Int(2)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := KShortestPaths(s.start, s.end, k, s.weight, s.options)
+	if err != nil {
+		return nil, err
+	}
+	return pathRecords(paths...), nil
+}
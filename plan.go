@@ -0,0 +1,243 @@
+package goneo
+
+import (
+	"bytes"
+	"fmt"
+	"iter"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// Plan describes how a query is executed as a tree of operators. Plans are
+// returned for queries prefixed with EXPLAIN or PROFILE. Only profiled plans
+// contain the measured Rows, DbHits and Time.
+type Plan struct {
+	Operator      string
+	Details       string
+	Identifiers   []string
+	EstimatedRows int
+
+	Profiled bool
+	Rows     int
+	DbHits   int
+	Time     time.Duration
+
+	Children []*Plan
+}
+
+func (p *Plan) String() string {
+	b := new(bytes.Buffer)
+
+	w := new(tabwriter.Writer)
+	w.Init(b, 0, 8, 1, ' ', 0)
+
+	if p.Profiled {
+		fmt.Fprintln(w, "Operator\tDetails\tIdentifiers\tEstimatedRows\tRows\tDbHits\tTime\t")
+	} else {
+		fmt.Fprintln(w, "Operator\tDetails\tIdentifiers\tEstimatedRows\t")
+	}
+	p.format(w, "", "")
+	w.Flush()
+
+	return b.String()
+}
+
+func (p *Plan) format(w *tabwriter.Writer, prefix, childPrefix string) {
+	fmt.Fprintf(w, "%s%s\t%s\t%s\t%d\t", prefix, p.Operator, p.Details, strings.Join(p.Identifiers, ", "), p.EstimatedRows)
+	if p.Profiled {
+		fmt.Fprintf(w, "%d\t%d\t%s\t", p.Rows, p.DbHits, p.Time)
+	}
+	fmt.Fprintln(w, "")
+
+	for i, child := range p.Children {
+		if i == len(p.Children)-1 {
+			child.format(w, childPrefix+"└─", childPrefix+"  ")
+		} else {
+			child.format(w, childPrefix+"├─", childPrefix+"│ ")
+		}
+	}
+}
+
+// operator is a single step of a query plan, producing rows from its inputs.
+type operator interface {
+	// describe returns the name and details of the operator for plans
+	describe() (name, details string)
+	// identifiers returns all variables bound after the operator ran
+	identifiers() []string
+	inputs() []operator
+
+	execute(ctx *evalContext) iter.Seq[row]
+
+	stats() *operatorStats
+}
+
+// operatorStats holds what the planner knows about an operator. Operators
+// are not changed while executing, so a plan can be executed many times.
+type operatorStats struct {
+	// rows expected by the planner
+	estimated float64
+}
+
+func (s *operatorStats) stats() *operatorStats { return s }
+
+// executionStats collects what happened during a profiled execution.
+type executionStats struct {
+	rows   int
+	dbHits int
+	time   time.Duration
+
+	// candidate pairs tested by a subgraph match
+	candidates int
+}
+
+// executed returns the statistics of an operator for this execution.
+func (ctx *evalContext) executed(op operator) *executionStats {
+	if ctx.stats == nil {
+		ctx.stats = make(map[operator]*executionStats)
+	}
+	stats, ok := ctx.stats[op]
+	if !ok {
+		stats = new(executionStats)
+		ctx.stats[op] = stats
+	}
+	return stats
+}
+
+// run executes the operator, measuring it if the query is profiled.
+func run(ctx *evalContext, op operator) iter.Seq[row] {
+	rows := op.execute(ctx)
+	if ctx.limits.MaxIntermediate > 0 {
+		rows = limitIntermediate(ctx, rows)
+	}
+
+	if !ctx.profile {
+		return rows
+	}
+
+	stats := ctx.executed(op)
+
+	return func(yield func(row) bool) {
+		started := time.Now()
+		defer func() { stats.time += time.Since(started) }()
+
+		for r := range rows {
+			stats.rows += 1
+
+			stats.time += time.Since(started)
+			cont := yield(r)
+			started = time.Now()
+
+			if !cont {
+				return
+			}
+		}
+	}
+}
+
+// limitIntermediate counts the rows produced by an operator.
+func limitIntermediate(ctx *evalContext, rows iter.Seq[row]) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		for r := range rows {
+			if !ctx.produced(1) || !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// dbFor returns the database an operator should use, counting accesses if
+// the query is profiled.
+func (ctx *evalContext) dbFor(op operator) DatabaseService {
+	if !ctx.profile {
+		return ctx.db
+	}
+	return &countingDb{ctx.db, &ctx.executed(op).dbHits}
+}
+
+// hit counts database accesses not going through DatabaseService.
+func (ctx *evalContext) hit(op operator, hits int) {
+	if ctx.profile {
+		ctx.executed(op).dbHits += hits
+	}
+}
+
+// describePlan converts operators into their description, including the
+// statistics of a profiled execution.
+func describePlan(op operator, ctx *evalContext) *Plan {
+	name, details := op.describe()
+
+	plan := &Plan{Operator: name, Details: details, Identifiers: op.identifiers(), Profiled: ctx.profile}
+	plan.EstimatedRows = int(math.Round(op.stats().estimated))
+
+	if ctx.profile {
+		stats := ctx.executed(op)
+		plan.Rows, plan.DbHits, plan.Time = stats.rows, stats.dbHits, stats.time
+
+		if stats.candidates > 0 {
+			plan.Details += fmt.Sprintf("; candidate pairs: %d", stats.candidates)
+		}
+	}
+
+	for _, input := range op.inputs() {
+		child := describePlan(input, ctx)
+		plan.Children = append(plan.Children, child)
+
+		// Time is measured including inputs, report only the own share
+		plan.Time -= child.Time + childTime(child)
+	}
+
+	return plan
+}
+
+// childTime sums the time of all transitive children.
+func childTime(plan *Plan) (t time.Duration) {
+	for _, child := range plan.Children {
+		t += child.Time + childTime(child)
+	}
+	return
+}
+
+// countingDb counts every entity read from the database.
+type countingDb struct {
+	DatabaseService
+
+	hits *int
+}
+
+func (db *countingDb) GetNode(id int) (Node, error) {
+	*db.hits += 1
+	return db.DatabaseService.GetNode(id)
+}
+
+func (db *countingDb) GetAllNodes() []Node {
+	nodes := db.DatabaseService.GetAllNodes()
+	*db.hits += len(nodes)
+	return nodes
+}
+
+func (db *countingDb) GetRelation(id int) (Relation, error) {
+	*db.hits += 1
+	return db.DatabaseService.GetRelation(id)
+}
+
+func (db *countingDb) GetAllRelations() []Relation {
+	rels := db.DatabaseService.GetAllRelations()
+	*db.hits += len(rels)
+	return rels
+}
+
+func (db *countingDb) FindNodeByProperty(prop, value string) []Node {
+	nodes := db.DatabaseService.FindNodeByProperty(prop, value)
+	*db.hits += len(nodes) + 1
+	return nodes
+}
+
+func (db *countingDb) FindNodesByLabel(label string) []Node {
+	nodes := findNodesByLabel(db.DatabaseService, label)
+	*db.hits += len(nodes) + 1
+	return nodes
+}
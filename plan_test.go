@@ -0,0 +1,104 @@
+package goneo
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "explain match (n:Tag)<-[:IS_TAGGED]-(v) return v, n")
+	NewTableTester(t, table, err).HasLen(0).HasColumns("v", "n")
+	if err != nil {
+		return
+	}
+
+	plan := table.Plan()
+	if plan == nil {
+		t.Fatal("Explained query should have a plan")
+	}
+	if plan.Profiled {
+		t.Error("Explained query should not be profiled")
+	}
+	if plan.Operator != "Projection" || len(plan.Children) != 1 {
+		t.Fatal("Unexpected plan: ", plan)
+	}
+	if expand := plan.Children[0]; expand.Operator != "Expand(All)" || expand.Details != "(n)<-[:IS_TAGGED]-(v)" {
+		t.Error("Plan should expand the pattern: ", plan)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	db := setupTestDb(t)
+
+	creators := db.FindNodeByProperty("creator", "Joss Whedon")
+
+	table, err := Evaluate(db, "profile start joss=node("+strconv.Itoa(creators[0].Id())+") match (joss)-[:CREATED]->(o) return o.series")
+	NewTableTester(t, table, err).Has("o.series", "Firefly")
+	if err != nil {
+		return
+	}
+
+	plan := table.Plan()
+	if plan == nil || !plan.Profiled {
+		t.Fatal("Profiled query should have a profiled plan")
+	}
+	if plan.Rows != 1 {
+		t.Error("Projection should have produced one row, got ", plan.Rows)
+	}
+
+	expand := plan.Children[0]
+	if expand.Operator != "Expand(All)" || expand.Details != "(joss)-[:CREATED]->(o)" {
+		t.Error("Match should expand from the start node: ", plan)
+	}
+	if expand.DbHits == 0 {
+		t.Error("Expand should have hit the db")
+	}
+
+	seek := expand.Children[0]
+	if seek.Operator != "NodeByIdSeek" || seek.Rows != 1 || seek.DbHits != 1 {
+		t.Error("Unexpected root statistics: ", plan)
+	}
+}
+
+func TestNoPlan(t *testing.T) {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "start n=node(0) return n")
+	NewTableTester(t, table, err).HasLen(1)
+	if err == nil && table.Plan() != nil {
+		t.Error("Plain queries should not report a plan")
+	}
+}
+
+func TestProfileSubgraphMatch(t *testing.T) {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "profile match (e:Episode)-[:LEADS_TO]->(e2) using approximate return count(e2) as c")
+	NewTableTester(t, table, err).HasLen(1)
+	if err != nil {
+		return
+	}
+
+	match := table.Plan().Children[0]
+	if match.Operator != "SubgraphMatch" || !strings.Contains(match.Details, "candidate pairs") {
+		t.Error("Approximate matches should be found by subgraph matching: ", table.Plan())
+	}
+}
+
+func TestProfileVarLength(t *testing.T) {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "profile match (e:Episode)-[:LEADS_TO*]->(e2) return count(e2) as c")
+	NewTableTester(t, table, err).Has("c", 91)
+	if err != nil {
+		return
+	}
+
+	expand := table.Plan().Children[0]
+	if expand.Operator != "VarLengthExpand(All)" || expand.Details != "(e)-[:LEADS_TO*]->(e2)" {
+		t.Error("Variable length relations should be expanded along paths: ", table.Plan())
+	}
+}
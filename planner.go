@@ -0,0 +1,701 @@
+package goneo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/gcy"
+	"github.com/BuJo/goneo/log"
+)
+
+// Selectivities assumed if the database does not provide Statistics.
+const (
+	defaultLabelSelectivity    = 0.1
+	defaultPropertySelectivity = 0.01
+)
+
+type (
+	// patternNode is a node of a MATCH pattern, merged by name over all
+	// paths of the pattern.
+	patternNode struct {
+		name   string
+		labels []string
+		props  map[string]propertyValue
+	}
+
+	// propertyValue is either a literal or the name of a parameter given
+	// when executing the query.
+	propertyValue struct {
+		literal string
+		param   string
+	}
+
+	// patternRel connects two pattern nodes, the direction is seen from the
+	// from node.
+	patternRel struct {
+		from, to  *patternNode
+		name      string
+		types     []string
+		direction Direction
+
+		// cardinality of variable length relations, e.g. "*1..3", with the
+		// bounds of their length, a maxHops of 0 is unbounded
+		cardinality      string
+		minHops, maxHops int
+	}
+
+	pattern struct {
+		nodes  []*patternNode
+		byName map[string]*patternNode
+		rels   []*patternRel
+	}
+
+	// planner chooses operators for a query using database statistics.
+	planner struct {
+		db    DatabaseService
+		stats Statistics
+
+		nodes float64
+	}
+
+	// candidatePlan is a partial plan together with its estimated cost, the
+	// sum of all rows produced by its operators.
+	candidatePlan struct {
+		op   operator
+		cost float64
+		rows float64
+	}
+)
+
+func newPattern(m *gcy.Match) *pattern {
+	p := &pattern{byName: make(map[string]*patternNode)}
+
+	node := func(n *gcy.Node) *patternNode {
+		name := n.Name
+		if name == "" {
+			// no variable starts with a space
+			name = fmt.Sprintf(" anon_%d", len(p.nodes))
+		}
+
+		pn, ok := p.byName[name]
+		if !ok {
+			pn = &patternNode{name: name, props: make(map[string]propertyValue)}
+			p.byName[name] = pn
+			p.nodes = append(p.nodes, pn)
+		}
+
+		for _, l := range n.Labels {
+			if !containsString(pn.labels, l) {
+				pn.labels = append(pn.labels, l)
+			}
+		}
+		for k, v := range n.Props {
+			pn.props[k] = propertyValue{literal: v}
+		}
+		for k, param := range n.Params {
+			pn.props[k] = propertyValue{param: param}
+		}
+
+		return pn
+	}
+
+	for _, path := range m.Paths {
+		prev := node(path.Start)
+		for n := path.Start; n.RightRel != nil; n = n.RightRel.RightNode {
+			rel := n.RightRel
+			next := node(rel.RightNode)
+
+			direction := Both
+			switch rel.Direction {
+			case "->":
+				direction = Outgoing
+			case "<-":
+				direction = Incoming
+			}
+
+			p.rels = append(p.rels, &patternRel{
+				from: prev, to: next,
+				name:      rel.Name,
+				types:     rel.Types,
+				direction: direction,
+
+				cardinality: rel.Cardinality,
+				minHops:     rel.MinHops,
+				maxHops:     rel.MaxHops,
+			})
+
+			prev = next
+		}
+	}
+
+	return p
+}
+
+// components splits the pattern into connected parts.
+func (p *pattern) components() [][]*patternNode {
+	component := make(map[*patternNode]int)
+	for i, n := range p.nodes {
+		component[n] = i
+	}
+
+	// naive union of components, patterns are small
+	for changed := true; changed; {
+		changed = false
+		for _, rel := range p.rels {
+			a, b := component[rel.from], component[rel.to]
+			if a == b {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			for n, c := range component {
+				if c == b {
+					component[n] = a
+				}
+			}
+			changed = true
+		}
+	}
+
+	byComponent := make(map[int][]*patternNode)
+	ids := make([]int, 0)
+	for _, n := range p.nodes {
+		c := component[n]
+		if _, ok := byComponent[c]; !ok {
+			ids = append(ids, c)
+		}
+		byComponent[c] = append(byComponent[c], n)
+	}
+
+	components := make([][]*patternNode, 0, len(ids))
+	for _, c := range ids {
+		components = append(components, byComponent[c])
+	}
+	return components
+}
+
+// relsOf returns all relations between the given nodes.
+func (p *pattern) relsOf(nodes []*patternNode) []*patternRel {
+	rels := make([]*patternRel, 0)
+	for _, rel := range p.rels {
+		for _, n := range nodes {
+			if rel.from == n {
+				rels = append(rels, rel)
+				break
+			}
+		}
+	}
+	return rels
+}
+
+func (p *pattern) names() []string {
+	names := make([]string, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		names = append(names, n.name)
+	}
+	return names
+}
+
+func newPlanner(db DatabaseService) *planner {
+	pl := &planner{db: db}
+
+	if stats, ok := db.(Statistics); ok {
+		pl.stats = stats
+		pl.nodes = float64(stats.CountNodes())
+	} else {
+		pl.nodes = float64(len(db.GetAllNodes()))
+	}
+	pl.nodes = math.Max(pl.nodes, 1)
+
+	return pl
+}
+
+// planMatch chooses the operators to find a pattern. Nodes of the pattern are
+// looked up by the most selective label or property and the remaining
+// pattern is found by expanding along relations. Independent parts are
+// combined with a CartesianProduct, parts which are cheaper to find from two
+// sides are combined by a HashJoin. Shortest paths are searched once both
+// their end nodes are found.
+func (pl *planner) planMatch(input operator, m *gcy.Match) operator {
+	pattern := &gcy.Match{Approximate: m.Approximate, Tolerance: m.Tolerance, Cost: m.Cost}
+	shortest := make([]*gcy.Path, 0)
+	for _, path := range m.Paths {
+		if path.Function == "" {
+			pattern.Paths = append(pattern.Paths, path)
+			continue
+		}
+
+		// find the end nodes like any other node of the pattern
+		start, end := *path.Start, *path.Start.RightRel.RightNode
+		start.LeftRel, start.RightRel, end.LeftRel, end.RightRel = nil, nil, nil, nil
+		pattern.Paths = append(pattern.Paths, &gcy.Path{Start: &start}, &gcy.Path{Start: &end})
+
+		shortest = append(shortest, path)
+	}
+
+	op := pl.planPattern(input, pattern)
+	for _, path := range shortest {
+		op = newShortestPathOp(op, path)
+	}
+	return op
+}
+
+// planPattern plans a pattern without shortest paths.
+func (pl *planner) planPattern(input operator, m *gcy.Match) operator {
+	p := newPattern(m)
+
+	if m.Approximate {
+		// Only the generic subgraph matcher tolerates differences
+		return newSubgraphMatchOp(input, m)
+	}
+
+	bound := make(map[string]bool)
+	for _, name := range input.identifiers() {
+		if _, ok := p.byName[name]; ok {
+			bound[name] = true
+		}
+	}
+
+	// Plan parts bound by the input first, so they expand directly from it
+	components := p.components()
+	sort.SliceStable(components, func(i, j int) bool {
+		return anyBound(components[i], bound) && !anyBound(components[j], bound)
+	})
+
+	current := candidatePlan{op: input, rows: math.Max(1, input.stats().estimated)}
+	combined := false
+	for i, component := range components {
+		if anyBound(component, bound) || i == 0 {
+			current = pl.planComponent(current, p, component, bound)
+			continue
+		}
+
+		right := pl.planComponent(candidatePlan{op: &argumentOp{}, rows: 1}, p, component, bound)
+		op := &cartesianProductOp{left: current.op, right: right.op}
+		current = candidatePlan{op: op, cost: current.cost + right.cost, rows: current.rows * right.rows}
+		op.estimated = current.rows
+		combined = true
+	}
+
+	if combined && len(p.nodes) > 1 {
+		op := &filterOp{input: current.op, distinct: p.names()}
+		op.estimated = current.rows
+		current.op = op
+	}
+
+	log.Print("planned match with estimated cost ", current.cost)
+
+	return current.op
+}
+
+// planComponent chooses the cheapest way to find a connected part of a
+// pattern, starting from the given plan.
+func (pl *planner) planComponent(input candidatePlan, p *pattern, component []*patternNode, bound map[string]bool) candidatePlan {
+	rels := p.relsOf(component)
+
+	for _, n := range component {
+		if bound[n.name] {
+			return pl.planExpansion(input, p, rels, n, bound)
+		}
+	}
+
+	var best *candidatePlan
+	for _, n := range component {
+		plan := pl.planExpansion(input, p, rels, n, bound)
+		if best == nil || plan.cost < best.cost {
+			best = &plan
+		}
+	}
+
+	// Try to meet in the middle between two selective anchors
+	for _, a := range component {
+		for _, b := range component {
+			if a == b || !pl.indexed(a) || !pl.indexed(b) {
+				continue
+			}
+			if plan, ok := pl.planHashJoin(input, p, rels, a, b, bound); ok && plan.cost < best.cost {
+				best = &plan
+			}
+		}
+	}
+
+	return *best
+}
+
+// planExpansion plans finding all given relations starting with the anchor.
+func (pl *planner) planExpansion(input candidatePlan, p *pattern, rels []*patternRel, anchor *patternNode, bound map[string]bool) candidatePlan {
+	plan := input
+	known := copyBound(bound)
+
+	if !known[anchor.name] {
+		plan = pl.planAnchor(plan, anchor, p.names(), known)
+	}
+
+	return pl.expandAll(plan, rels, p.names(), known)
+}
+
+// planHashJoin plans finding the relations from two anchors, each side
+// expanding up to the nodes in the middle, where both sides are joined.
+func (pl *planner) planHashJoin(input candidatePlan, p *pattern, rels []*patternRel, a, b *patternNode, bound map[string]bool) (candidatePlan, bool) {
+	distA, distB := distances(rels, a), distances(rels, b)
+
+	relsA, relsB := make([]*patternRel, 0), make([]*patternRel, 0)
+	for _, rel := range rels {
+		if math.Min(distA[rel.from], distA[rel.to]) <= math.Min(distB[rel.from], distB[rel.to]) {
+			relsA = append(relsA, rel)
+		} else {
+			relsB = append(relsB, rel)
+		}
+	}
+	if len(relsA) == 0 || len(relsB) == 0 {
+		return candidatePlan{}, false
+	}
+
+	nodesA := nodesOf(relsA)
+	keys := make([]string, 0)
+	for n := range nodesOf(relsB) {
+		if nodesA[n] {
+			keys = append(keys, n.name)
+		}
+	}
+	if len(keys) == 0 {
+		return candidatePlan{}, false
+	}
+	sort.Strings(keys)
+
+	left := pl.planExpansion(input, p, relsA, a, bound)
+	right := pl.planExpansion(candidatePlan{op: &argumentOp{}, rows: 1}, p, relsB, b, bound)
+
+	rows := left.rows * right.rows / math.Pow(pl.nodes, float64(len(keys)))
+	op := &hashJoinOp{left: left.op, right: right.op, keys: keys}
+	op.estimated = rows
+
+	filter := &filterOp{input: op, distinct: p.names()}
+	filter.estimated = rows
+
+	return candidatePlan{op: filter, cost: left.cost + right.cost + rows, rows: rows}, true
+}
+
+// planAnchor looks up the nodes to start expanding from.
+func (pl *planner) planAnchor(input candidatePlan, n *patternNode, patternNames []string, known map[string]bool) candidatePlan {
+	var op operator
+	var rows float64
+
+	remaining := &filterOp{variable: n.name, labels: n.labels, props: n.props}
+
+	// Prefer the most selective property, then label
+	bestProp, bestLabel := "", ""
+	for _, k := range sortedKeys(n.props) {
+		if bestProp == "" || pl.propertyCount(k, n.props[k]) < pl.propertyCount(bestProp, n.props[bestProp]) {
+			bestProp = k
+		}
+	}
+	for _, l := range n.labels {
+		if bestLabel == "" || pl.labelCount(l) < pl.labelCount(bestLabel) {
+			bestLabel = l
+		}
+	}
+
+	distinct := boundPatternNames(patternNames, known)
+
+	switch {
+	case bestProp != "" && (bestLabel == "" || pl.propertyCount(bestProp, n.props[bestProp]) <= pl.labelCount(bestLabel)):
+		rows = pl.propertyCount(bestProp, n.props[bestProp])
+		op = &nodeIndexSeekOp{input: input.op, variable: n.name, prop: bestProp, value: n.props[bestProp], distinct: distinct}
+		remaining.props = withoutKey(n.props, bestProp)
+	case bestLabel != "":
+		rows = pl.labelCount(bestLabel)
+		op = &nodeByLabelScanOp{input: input.op, variable: n.name, label: bestLabel, distinct: distinct}
+		remaining.labels = withoutString(n.labels, bestLabel)
+	default:
+		rows = pl.nodes
+		op = &allNodesScanOp{input: input.op, variable: n.name, distinct: distinct}
+	}
+	rows *= input.rows
+	op.stats().estimated = rows
+
+	plan := candidatePlan{op: op, cost: input.cost + rows, rows: rows}
+	known[n.name] = true
+
+	return pl.filter(plan, remaining)
+}
+
+// expandAll adds expansions along the relations, choosing the cheapest
+// relation reachable from the known nodes each time.
+func (pl *planner) expandAll(plan candidatePlan, rels []*patternRel, patternNames []string, known map[string]bool) candidatePlan {
+	remaining := append(make([]*patternRel, 0, len(rels)), rels...)
+
+	for len(remaining) > 0 {
+		best, bestRows, bestInto := -1, 0.0, false
+
+		for i, rel := range remaining {
+			fromKnown, toKnown := known[rel.from.name], known[rel.to.name]
+			if !fromKnown && !toKnown {
+				continue
+			}
+
+			into := fromKnown && toKnown
+			var rows float64
+			if into {
+				rows = plan.rows * pl.reach(rel) / pl.nodes
+			} else {
+				to := rel.to
+				if toKnown {
+					to = rel.from
+				}
+				rows = plan.rows * pl.reach(rel) * pl.selectivity(to)
+			}
+
+			if best == -1 || (into && !bestInto) || (into == bestInto && rows < bestRows) {
+				best, bestRows, bestInto = i, rows, into
+			}
+		}
+
+		if best == -1 {
+			// Disconnected relations can not happen within a component
+			break
+		}
+
+		rel := remaining[best]
+		remaining = append(remaining[:best:best], remaining[best+1:]...)
+
+		if bestInto {
+			op := newExpandOp(plan.op, rel, rel.from.name, rel.to.name, rel.direction)
+			op.into = true
+			op.estimated = bestRows
+			plan = candidatePlan{op: op, cost: plan.cost + bestRows, rows: bestRows}
+			continue
+		}
+
+		from, to, direction := rel.from, rel.to, rel.direction
+		if known[to.name] {
+			from, to, direction = to, from, direction.Reverse()
+		}
+
+		op := newExpandOp(plan.op, rel, from.name, to.name, direction)
+		op.distinct = boundPatternNames(patternNames, known)
+		rows := plan.rows * pl.reach(rel)
+		op.estimated = rows
+		plan = candidatePlan{op: op, cost: plan.cost + rows, rows: rows}
+		known[to.name] = true
+
+		plan = pl.filter(plan, &filterOp{variable: to.name, labels: to.labels, props: to.props})
+	}
+
+	return plan
+}
+
+// filter adds the filter if it checks anything.
+func (pl *planner) filter(plan candidatePlan, filter *filterOp) candidatePlan {
+	if len(filter.labels) == 0 && len(filter.props) == 0 {
+		return plan
+	}
+
+	filter.input = plan.op
+	rows := plan.rows * pl.selectivity(&patternNode{labels: filter.labels, props: filter.props})
+	filter.estimated = rows
+
+	return candidatePlan{op: filter, cost: plan.cost + rows, rows: rows}
+}
+
+// rootCount estimates the entities a START root refers to.
+func (pl *planner) rootCount(r *gcy.Root) float64 {
+	if r.Param != "" {
+		// usually a single id is given
+		return 1
+	}
+	if len(r.IdVars) == 1 && r.IdVars[0] == -1 {
+		if r.Typ == "node" {
+			return pl.nodes
+		}
+		return pl.degree(nil, Outgoing) * pl.nodes
+	}
+	return float64(len(r.IdVars))
+}
+
+// indexed reports whether nodes can be found without scanning all nodes.
+func (pl *planner) indexed(n *patternNode) bool {
+	return len(n.props) > 0 || len(n.labels) > 0
+}
+
+func (pl *planner) labelCount(label string) float64 {
+	if pl.stats == nil {
+		return pl.nodes * defaultLabelSelectivity
+	}
+	return float64(pl.stats.CountNodesByLabel(label))
+}
+
+func (pl *planner) propertyCount(prop string, value propertyValue) float64 {
+	if pl.stats == nil || value.param != "" {
+		return pl.nodes * defaultPropertySelectivity
+	}
+	return float64(pl.stats.CountNodesByProperty(prop, value.literal))
+}
+
+// selectivity estimates the fraction of nodes matching a pattern node.
+func (pl *planner) selectivity(n *patternNode) float64 {
+	s := 1.0
+	for _, l := range n.labels {
+		s = math.Min(s, pl.labelCount(l)/pl.nodes)
+	}
+	for k, v := range n.props {
+		s = math.Min(s, pl.propertyCount(k, v)/pl.nodes)
+	}
+	return s
+}
+
+// degree estimates the number of relations per node.
+func (pl *planner) degree(types []string, direction Direction) float64 {
+	rels := 0.0
+	if pl.stats == nil {
+		rels = float64(len(pl.db.GetAllRelations()))
+	} else if len(types) == 0 {
+		rels = float64(pl.stats.CountRelations(""))
+	} else {
+		for _, t := range types {
+			rels += float64(pl.stats.CountRelations(t))
+		}
+	}
+
+	degree := rels / pl.nodes
+	if direction == Both {
+		degree *= 2
+	}
+	return degree
+}
+
+// unboundedHops is the number of hops assumed for relations of unbounded
+// length beyond their minimal length.
+const unboundedHops = 3
+
+// reach estimates the nodes reached from a node along a relation, summing up
+// the degrees over all lengths of variable length relations.
+func (pl *planner) reach(rel *patternRel) float64 {
+	degree := pl.degree(rel.types, rel.direction)
+	if rel.cardinality == "" {
+		return degree
+	}
+
+	maxHops := rel.maxHops
+	if maxHops == 0 {
+		maxHops = rel.minHops + unboundedHops
+	}
+	reach := 0.0
+	for hops := rel.minHops; hops <= maxHops; hops++ {
+		reach += math.Pow(degree, float64(hops))
+	}
+	return reach
+}
+
+func (v propertyValue) String() string {
+	if v.param != "" {
+		return "$" + v.param
+	}
+	return strconv.Quote(v.literal)
+}
+
+// resolve returns the value, looking up parameters.
+func (v propertyValue) resolve(ctx *evalContext) string {
+	if v.param == "" {
+		return v.literal
+	}
+	return fmt.Sprint(ctx.params[v.param])
+}
+
+// distances from the start node within the relations in hops.
+func distances(rels []*patternRel, start *patternNode) map[*patternNode]float64 {
+	dist := map[*patternNode]float64{start: 0}
+	for changed := true; changed; {
+		changed = false
+		for _, rel := range rels {
+			for _, pair := range [][2]*patternNode{{rel.from, rel.to}, {rel.to, rel.from}} {
+				d, ok := dist[pair[0]]
+				if !ok {
+					continue
+				}
+				if old, ok := dist[pair[1]]; !ok || d+1 < old {
+					dist[pair[1]] = d + 1
+					changed = true
+				}
+			}
+		}
+	}
+	return dist
+}
+
+func nodesOf(rels []*patternRel) map[*patternNode]bool {
+	nodes := make(map[*patternNode]bool)
+	for _, rel := range rels {
+		nodes[rel.from], nodes[rel.to] = true, true
+	}
+	return nodes
+}
+
+func anyBound(nodes []*patternNode, bound map[string]bool) bool {
+	for _, n := range nodes {
+		if bound[n.name] {
+			return true
+		}
+	}
+	return false
+}
+
+func boundPatternNames(names []string, known map[string]bool) []string {
+	bound := make([]string, 0, len(names))
+	for _, name := range names {
+		if known[name] {
+			bound = append(bound, name)
+		}
+	}
+	return bound
+}
+
+func copyBound(bound map[string]bool) map[string]bool {
+	known := make(map[string]bool, len(bound))
+	for k, v := range bound {
+		known[k] = v
+	}
+	return known
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func withoutKey[V any](m map[string]V, key string) map[string]V {
+	rest := make(map[string]V, len(m))
+	for k, v := range m {
+		if k != key {
+			rest[k] = v
+		}
+	}
+	return rest
+}
+
+func withoutString(strs []string, str string) []string {
+	rest := make([]string, 0, len(strs))
+	for _, s := range strs {
+		if s != str {
+			rest = append(rest, s)
+		}
+	}
+	return rest
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package goneo
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/gcy"
+)
+
+// operators lists the operator names of a plan depth first.
+func operators(plan *Plan) []string {
+	ops := []string{plan.Operator}
+	for _, child := range plan.Children {
+		ops = append(ops, operators(child)...)
+	}
+	return ops
+}
+
+func explain(t *testing.T, query string) *Plan {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "explain "+query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table.Plan()
+}
+
+func TestPlanPropertyAnchor(t *testing.T) {
+	plan := explain(t, "match (e:Episode)<-[:APPEARED_IN]-(niska {character: \"Adelai Niska\"}) return e")
+
+	ops := strings.Join(operators(plan), ",")
+	if ops != "Projection,Filter,Expand(All),NodeIndexSeek,Argument" {
+		t.Error("Should start at the selective property: ", plan)
+	}
+}
+
+func TestPlanLabelAnchor(t *testing.T) {
+	plan := explain(t, "match (p:Person)-[:PLAYED]->(c:Character)-[:CREW]->(s:Ship) return c")
+
+	if ops := operators(plan); ops[len(ops)-2] != "NodeByLabelScan" || !strings.Contains(plan.String(), "s:Ship") {
+		t.Error("Should start at the rarest label: ", plan)
+	}
+}
+
+func TestPlanExpandInto(t *testing.T) {
+	plan := explain(t, "match (a)-[:ARCS_TO]->(b), (a)-[:LEADS_TO]->(b) return a")
+
+	if !strings.Contains(strings.Join(operators(plan), ","), "Expand(Into)") {
+		t.Error("Closing a cycle should expand into the bound node: ", plan)
+	}
+}
+
+func TestPlanCartesianProduct(t *testing.T) {
+	db := setupTestDb(t)
+
+	table, err := Evaluate(db, "explain match (s:Series), (m:Movie) return s, m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(operators(table.Plan()), ","), "CartesianProduct") {
+		t.Error("Independent patterns should be combined: ", table.Plan())
+	}
+
+	table, err = Evaluate(db, "match (s:Series), (m:Movie) return s.series as series, m.movie as movie")
+	NewTableTester(t, table, err).HasLen(1).Has("series", "Firefly")
+}
+
+func TestPlanHashJoin(t *testing.T) {
+	db, _ := OpenDb("mem:hashjoin")
+
+	// A dense graph where walking from one end is expensive
+	nodes := make([]Node, 200)
+	for i := range nodes {
+		nodes[i] = db.NewNode()
+		nodes[i].SetProperty("name", strconv.Itoa(i))
+	}
+	random := rand.New(rand.NewSource(1))
+	for _, n := range nodes {
+		for i := 0; i < 10; i++ {
+			n.RelateTo(nodes[random.Intn(len(nodes))], "R")
+		}
+	}
+
+	query := "match (a {name: \"0\"})-[:R]->(m)-[:R]->(x)<-[:R]-(y)<-[:R]-(b {name: \"1\"}) return count(x) as c"
+
+	table, err := Evaluate(db, "explain "+query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(operators(table.Plan()), ","), "HashJoin") {
+		t.Error("Two selective ends should be joined in the middle: ", table.Plan())
+	}
+
+	expected := 0
+	for _, r1 := range nodes[0].Relations(Outgoing) {
+		for _, r2 := range r1.End().Relations(Outgoing) {
+			for _, r3 := range r2.End().Relations(Incoming) {
+				for _, r4 := range r3.Start().Relations(Incoming) {
+					if r4.Start().Id() != nodes[1].Id() {
+						continue
+					}
+					ids := map[int]bool{0: true, 1: true}
+					for _, n := range []Node{r1.End(), r2.End(), r3.Start()} {
+						ids[n.Id()] = true
+					}
+					if len(ids) == 5 {
+						expected += 1
+					}
+				}
+			}
+		}
+	}
+
+	table, err = Evaluate(db, query)
+	NewTableTester(t, table, err).Has("c", expected)
+}
+
+func TestPlanAnonymousNodes(t *testing.T) {
+	q, err := gcy.Parse("goneo", "match (anon_1)-->(b) return anon_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Match.Paths[0].Start.RightRel.RightNode.Name = ""
+
+	if p := newPattern(q.Match); len(p.nodes) != 2 {
+		t.Error("Anonymous nodes should not take the names of variables, got ", len(p.nodes), " nodes")
+	}
+}
+
+func TestPlanMatchesSubgraphIsomorphism(t *testing.T) {
+	db := setupTestDb(t)
+
+	for _, query := range []string{
+		"match (n:Tag)<-[:IS_TAGGED]-(v) return count(v) as c",
+		"match (e1:Episode)<-[:APPEARED_IN]-(niska {character: \"Adelai Niska\"})-[:APPEARED_IN]->(e2:Episode) return count(e1) as c",
+		"match (a)-[:CREW]->(s:Ship)<-[:ENEMY]-(b) return count(a) as c",
+		"match (a)-[:SISTER]->(b)-[:BROTHER]->(a) return count(a) as c",
+	} {
+		table, err := Evaluate(db, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 0
+		switch {
+		case strings.Contains(query, "IS_TAGGED"):
+			expected = 6
+		case strings.Contains(query, "niska"):
+			expected = 2
+		case strings.Contains(query, "CREW"):
+			expected = 9 * 4
+		case strings.Contains(query, "SISTER"):
+			expected = 1
+		}
+
+		if c := table.Get(0, "c"); c != expected {
+			t.Error(query, ": expected ", expected, " got ", c)
+		}
+	}
+}
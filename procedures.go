@@ -0,0 +1,151 @@
+package goneo
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"strconv"
+	"sync"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// Procedure is a function callable from gcy queries.
+//
+// Example:
+//
+//	match (a {name: "a"}), (b {name: "b"})
+//	call db.dijkstra(a, b, "cost") yield path, cost
+//	return path, cost
+type Procedure struct {
+	// Arguments of the procedure, trailing arguments with a default may be
+	// omitted.
+	Arguments []ProcedureArgument
+	// Outputs names the values of every record produced.
+	Outputs []string
+
+	// Call produces the records for the given arguments, omitted arguments
+	// are set to their default.
+	Call func(ctx context.Context, db DatabaseService, args ProcedureArgs) (iter.Seq[map[string]interface{}], error)
+}
+
+// ProcedureArgument describes an argument of a procedure. Arguments without
+// default are required.
+type ProcedureArgument struct {
+	Name    string
+	Default interface{}
+}
+
+// ProcedureArgs are the values a procedure is called with.
+type ProcedureArgs []interface{}
+
+var (
+	proceduresMu sync.RWMutex
+	procedures   = make(map[string]*Procedure)
+)
+
+// RegisterProcedure makes a procedure callable by name. It panics if the name
+// is already taken.
+func RegisterProcedure(name string, proc *Procedure) {
+	proceduresMu.Lock()
+	defer proceduresMu.Unlock()
+
+	if _, dup := procedures[name]; dup {
+		panic("goneo: RegisterProcedure called twice for " + name)
+	}
+	procedures[name] = proc
+}
+
+// Procedures returns the names of all registered procedures.
+func Procedures() []string {
+	proceduresMu.RLock()
+	defer proceduresMu.RUnlock()
+
+	names := make([]string, 0, len(procedures))
+	for name := range procedures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupProcedure(name string) (*Procedure, bool) {
+	proceduresMu.RLock()
+	defer proceduresMu.RUnlock()
+
+	proc, ok := procedures[name]
+	return proc, ok
+}
+
+// required counts the arguments without default.
+func (proc *Procedure) required() int {
+	n := 0
+	for i, arg := range proc.Arguments {
+		if arg.Default == nil {
+			n = i + 1
+		}
+	}
+	return n
+}
+
+func (proc *Procedure) hasOutput(name string) bool {
+	for _, o := range proc.Outputs {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (args ProcedureArgs) errorf(i int, expected string) error {
+	return &SemanticError{fmt.Sprintf("argument %d must be %s, got %T", i+1, expected, args[i])}
+}
+
+// Node returns argument i as node.
+func (args ProcedureArgs) Node(i int) (Node, error) {
+	if n, ok := args[i].(Node); ok {
+		return n, nil
+	}
+	return nil, args.errorf(i, "a node")
+}
+
+// String returns argument i as string.
+func (args ProcedureArgs) String(i int) (string, error) {
+	if s, ok := args[i].(string); ok {
+		return s, nil
+	}
+	return "", args.errorf(i, "a string")
+}
+
+// Int returns argument i as integer.
+func (args ProcedureArgs) Int(i int) (int, error) {
+	switch v := args[i].(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, nil
+		}
+	}
+	return 0, args.errorf(i, "an integer")
+}
+
+// Float returns argument i as floating point number.
+func (args ProcedureArgs) Float(i int) (float64, error) {
+	switch v := args[i].(type) {
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, args.errorf(i, "a number")
+}
@@ -0,0 +1,63 @@
+package goneo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BuJo/goneo/db"
+)
+
+func TestCallDijkstra(t *testing.T) {
+	graph := setupTestDb(t)
+
+	table, err := Evaluate(graph, `match (a:Episode {episode: "1"}), (b:Episode {episode: "5"})
+		call db.dijkstra(a, b, "cost", "LEADS_TO", "out") yield path, cost as c
+		return path, c`)
+	NewTableTester(t, table, err).HasLen(1).Has("c", 4.0)
+	if path := table.Get(0, "path").(db.Path); len(path.Relations()) != 4 {
+		t.Error("Should follow the episodes, got ", path)
+	}
+
+	// There is no way back
+	table, err = Evaluate(graph, `match (a:Episode {episode: "5"}), (b:Episode {episode: "1"})
+		call db.dijkstra(a, b, "cost", "LEADS_TO", "out")
+		return path, cost`)
+	NewTableTester(t, table, err).HasLen(0)
+}
+
+func TestCallKShortestPaths(t *testing.T) {
+	graph := setupTestDb(t)
+
+	table, err := Evaluate(graph, `match (a:Episode {episode: "2"}), (b:Episode {episode: "10"})
+		call db.kShortestPaths(a, b, 3, "cost") yield index, cost
+		return index, cost`)
+	NewTableTester(t, table, err).HasLen(3)
+	for i := 1; i < table.Len(); i++ {
+		if table.Get(i-1, "cost").(float64) > table.Get(i, "cost").(float64) {
+			t.Errorf("Should order paths by cost, got %v before %v", table.Get(i-1, "cost"), table.Get(i, "cost"))
+		}
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	graph := setupTestDb(t)
+
+	for _, qry := range []string{
+		`match (a) call db.unknown(a) return a`,
+		`match (a), (b) call db.dijkstra(a) return path`,
+		`match (a), (b) call db.dijkstra(a, b, "cost") yield length return length`,
+		`match (a) call db.dijkstra(a, b, "cost") return path`,
+	} {
+		_, err := Evaluate(graph, qry)
+		var semanticErr *SemanticError
+		if !errors.As(err, &semanticErr) {
+			t.Errorf("%s: expected semantic error, got %v", qry, err)
+		}
+	}
+
+	_, err := Evaluate(graph, `match (a:Episode {episode: "1"}) call db.dijkstra(a, "b", "cost") return path`)
+	var semanticErr *SemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Error("Should reject arguments of the wrong type, got ", err)
+	}
+}
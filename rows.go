@@ -0,0 +1,132 @@
+package goneo
+
+import (
+	"iter"
+)
+
+// Rows is a cursor over the result of a query. Results are computed only as
+// far as they are consumed via Next, so a caller may stop early by calling
+// Close.
+//
+// Example:
+//
+//	rows, err := EvaluateContext(ctx, db, "match (n:Tag) return n.tag as tag")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		fmt.Println(rows.Get("tag"))
+//	}
+//	return rows.Err()
+type Rows struct {
+	eval    *evalContext
+	columns []string
+
+	plan     operator
+	withPlan bool
+
+	next     func() (row, bool)
+	stop     func()
+	current  row
+	returned int
+}
+
+func newRows(eval *evalContext, plan operator, withPlan bool, seq iter.Seq[row]) *Rows {
+	next, stop := iter.Pull(seq)
+
+	return &Rows{eval: eval, columns: plan.identifiers(), plan: plan, withPlan: withPlan, next: next, stop: stop}
+}
+
+// Next advances the cursor to the next row. It returns false when the result
+// is exhausted, the cursor is closed or an error happened.
+func (r *Rows) Next() bool {
+	if r.next == nil {
+		return false
+	}
+
+	if !r.eval.cancelled() {
+		if current, ok := r.next(); ok && !r.eval.cancelled() && r.count() {
+			r.current = current
+			return true
+		}
+	}
+
+	r.Close()
+	return false
+}
+
+// count checks a row against the MaxRows limit.
+func (r *Rows) count() bool {
+	r.returned += 1
+	if max := r.eval.limits.MaxRows; max > 0 && r.returned > max {
+		r.eval.fail(&LimitExceededError{"MaxRows", int64(max)})
+		return false
+	}
+	return true
+}
+
+// Columns returns the column names in the order of the query.
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Get returns a single field of the current row.
+func (r *Rows) Get(column string) interface{} {
+	return r.current[column]
+}
+
+// Row returns a copy of the current row.
+func (r *Rows) Row() map[string]interface{} {
+	line := make(map[string]interface{}, len(r.current))
+	for k, v := range r.current {
+		line[k] = v
+	}
+	return line
+}
+
+// Plan returns the plan of an explained or profiled query, nil otherwise.
+// Profiled statistics are complete only after all rows were consumed.
+func (r *Rows) Plan() *Plan {
+	if !r.withPlan {
+		return nil
+	}
+	return describePlan(r.plan, r.eval)
+}
+
+// Err returns the error which stopped the evaluation, if any.
+func (r *Rows) Err() error {
+	return r.eval.err
+}
+
+// Close stops the evaluation and releases its resources. It is safe to call
+// Close multiple times.
+func (r *Rows) Close() error {
+	if r.stop != nil {
+		r.stop()
+		r.eval.cancel()
+	}
+	r.next, r.stop, r.current = nil, nil, nil
+
+	return nil
+}
+
+// Table consumes all remaining rows into a TabularData.
+func (r *Rows) Table() (*TabularData, error) {
+	defer r.Close()
+
+	table := &TabularData{columns: r.columns, line: make([]map[string]interface{}, 0)}
+	for r.Next() {
+		if !r.eval.hold(r.current) {
+			break
+		}
+		table.line = append(table.line, r.current)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	table.plan = r.Plan()
+
+	return table, nil
+}
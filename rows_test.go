@@ -0,0 +1,123 @@
+package goneo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRowsIteration(t *testing.T) {
+	db := setupTestDb(t)
+
+	rows, err := EvaluateContext(context.Background(), db, "match (n:Tag)<-[:IS_TAGGED]-(v) return v, n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	if cols := rows.Columns(); len(cols) != 2 || cols[0] != "v" || cols[1] != "n" {
+		t.Error("Columns should be in query order, got ", cols)
+	}
+
+	count := 0
+	for rows.Next() {
+		if rows.Get("v") == nil || rows.Get("n") == nil {
+			t.Error("Row should contain bound values: ", rows.Row())
+		}
+		count += 1
+	}
+	if err := rows.Err(); err != nil {
+		t.Error(err)
+	}
+	if count != 6 {
+		t.Error("Expected 6 rows, got ", count)
+	}
+}
+
+func TestRowsEarlyClose(t *testing.T) {
+	db := setupTestDb(t)
+
+	rows, err := EvaluateContext(context.Background(), db, "start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rows.Next() {
+		t.Fatal("Should have a first row")
+	}
+	rows.Close()
+
+	if rows.Next() {
+		t.Error("Closed rows should not advance")
+	}
+	if err := rows.Err(); err != nil {
+		t.Error("Closing early is not an error: ", err)
+	}
+}
+
+func TestRowsCancelled(t *testing.T) {
+	db := setupTestDb(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rows, err := EvaluateContext(ctx, db, "start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() {
+		t.Error("Cancelled query should not produce rows")
+	}
+	if !errors.Is(rows.Err(), context.Canceled) {
+		t.Error("Expected cancellation error, got ", rows.Err())
+	}
+}
+
+// expiringContext passes its deadline once expire is called.
+type expiringContext struct {
+	context.Context
+	expired bool
+}
+
+func (ctx *expiringContext) expire() { ctx.expired = true }
+
+func (ctx *expiringContext) Err() error {
+	if ctx.expired {
+		return context.DeadlineExceeded
+	}
+	return ctx.Context.Err()
+}
+
+func TestRowsDeadline(t *testing.T) {
+	db := setupTestDb(t)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	rows, err := EvaluateContext(ctx, db, "match (a)--(b) return count(a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rows.Table(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected deadline error, got ", err)
+	}
+
+	// The deadline passes while iterating
+	expiring := &expiringContext{Context: context.Background()}
+	rows, err = EvaluateContext(expiring, db, "start n=node(*) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatal("Should have a first row before the deadline, got ", rows.Err())
+	}
+	expiring.expire()
+	if rows.Next() {
+		t.Error("Should stop after the deadline")
+	}
+	if !errors.Is(rows.Err(), context.DeadlineExceeded) {
+		t.Error("Expected deadline error, got ", rows.Err())
+	}
+}
@@ -0,0 +1,429 @@
+package sgi
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ApproximateOptions configure FindApproximateMappings. Zero values select
+// the defaults.
+type ApproximateOptions struct {
+	// MaxDifferences is the number of missing edges and mismatched nodes
+	// tolerated, zero finds exact matches only
+	MaxDifferences int
+	// MissingEdgeCost is the cost of a query edge without a target edge,
+	// defaults to 1
+	MissingEdgeCost float64
+	// MismatchCost is the cost of a query node matched to a node failing the
+	// SemFeasFunc, defaults to 1
+	MismatchCost float64
+
+	// Required restricts the pairs of nodes which may be matched at all,
+	// nil allows all pairs
+	Required SemFeasFunc
+}
+
+// ApproximateMapping is an embedding of the query missing some of its edges
+// or mismatching some of its nodes.
+type ApproximateMapping struct {
+	Embedding
+
+	// Mismatches lists the query nodes failing the SemFeasFunc
+	Mismatches []int
+	// Missing lists the query edges without a target edge
+	Missing []int
+
+	Cost float64
+}
+
+// Differences tolerated by the mapping.
+func (m ApproximateMapping) Differences() int { return len(m.Mismatches) + len(m.Missing) }
+
+// FindApproximateMappings finds the injective mappings of the query nodes to
+// the target for which at most opts.MaxDifferences query edges are missing or
+// query nodes fail fsem. The SemFeasFunc is only called for nodes on their
+// own. Every mapping is found once per way to match as many of its query
+// edges as possible to distinct target edges, so a tolerance of zero finds
+// the embeddings of FindEmbeddings. The cheapest mappings come first.
+func FindApproximateMappings(query, target Graph, fsem SemFeasFunc, efeas EdgeFeasFunc, opts ApproximateOptions) []ApproximateMapping {
+	mappings := make([]ApproximateMapping, 0)
+
+	FindApproximateMappingsFunc(query, target, fsem, efeas, opts, func(m ApproximateMapping) bool {
+		mappings = append(mappings, m)
+		return true
+	})
+
+	sort.SliceStable(mappings, func(i, j int) bool {
+		if mappings[i].Cost != mappings[j].Cost {
+			return mappings[i].Cost < mappings[j].Cost
+		}
+		return mappings[i].Differences() < mappings[j].Differences()
+	})
+
+	return mappings
+}
+
+// FindApproximateMappingsFunc works like FindApproximateMappings but hands
+// each mapping to visit as soon as it is found, unranked. Returning false
+// from visit stops the search.
+func FindApproximateMappingsFunc(query, target Graph, fsem SemFeasFunc, efeas EdgeFeasFunc, opts ApproximateOptions, visit func(m ApproximateMapping) bool) {
+	if opts.MissingEdgeCost == 0 {
+		opts.MissingEdgeCost = 1
+	}
+	if opts.MismatchCost == 0 {
+		opts.MismatchCost = 1
+	}
+	if fsem == nil {
+		fsem = func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool { return true }
+	}
+	if efeas == nil {
+		efeas = func(q, t int) bool { return true }
+	}
+	if opts.Required == nil {
+		opts.Required = func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool { return true }
+	}
+
+	state := newApproximateState(query, target, fsem, efeas, opts)
+
+	FindIsomorphismFunc(state, func(mapping map[int]int) bool {
+		return state.approximateMappings(mapping, visit)
+	})
+}
+
+// approximateMatcher is the search shared by all states of an approximate
+// matching. Query nodes are matched along their relations, a node needs to
+// be next to the match of a neighbour only if it could not miss all edges to
+// its matched neighbours.
+type approximateMatcher struct {
+	query, target Graph
+
+	fsem  SemFeasFunc
+	efeas EdgeFeasFunc
+	opts  ApproximateOptions
+
+	order            []int
+	queryNeighbours  [][]int
+	targetNeighbours *neighbourhood
+
+	// q ~> t and t ~> q
+	mapping map[int]int
+	reverse map[int]int
+}
+
+type approximateState struct {
+	*approximateMatcher
+
+	depth       int
+	differences int
+	candidates  []int
+
+	// differences of the last pair found feasable
+	pair      [2]int
+	tolerated int
+}
+
+func newApproximateState(query, target Graph, fsem SemFeasFunc, efeas EdgeFeasFunc, opts ApproximateOptions) *approximateState {
+	m := &approximateMatcher{
+		query:            query,
+		target:           target,
+		fsem:             fsem,
+		efeas:            efeas,
+		opts:             opts,
+		queryNeighbours:  make([][]int, query.Order()),
+		targetNeighbours: newNeighbourhood(target),
+		mapping:          make(map[int]int),
+		reverse:          make(map[int]int),
+	}
+	for q := range m.queryNeighbours {
+		for _, n := range distinct(query.Relations(q)) {
+			if n != q {
+				m.queryNeighbours[q] = append(m.queryNeighbours[q], n)
+			}
+		}
+	}
+	m.orderQuery()
+
+	state := &approximateState{approximateMatcher: m, pair: [2]int{NullNode, NullNode}}
+	state.loadCandidates()
+	return state
+}
+
+// orderQuery matches the nodes with the most matched neighbours first, then
+// the nodes with the most neighbours.
+func (m *approximateMatcher) orderQuery() {
+	ordered := make([]bool, len(m.queryNeighbours))
+	for len(m.order) < len(m.queryNeighbours) {
+		best, bestScore := NullNode, [2]int{}
+		for u, neighbours := range m.queryNeighbours {
+			if ordered[u] {
+				continue
+			}
+			score := [2]int{0, len(neighbours)}
+			for _, v := range neighbours {
+				if ordered[v] {
+					score[0] += 1
+				}
+			}
+			if best == NullNode || score[0] > bestScore[0] || score[0] == bestScore[0] && score[1] > bestScore[1] {
+				best, bestScore = u, score
+			}
+		}
+		ordered[best] = true
+		m.order = append(m.order, best)
+	}
+}
+
+func (state *approximateState) GetGraph() Graph    { return state.target }
+func (state *approximateState) GetSubgraph() Graph { return state.query }
+
+func (state *approximateState) uniqueMappings() bool { return true }
+
+func (state *approximateState) NextPair() (int, int) {
+	if len(state.candidates) == 0 {
+		return NullNode, NullNode
+	}
+
+	candidate := state.candidates[len(state.candidates)-1]
+	state.candidates = state.candidates[:len(state.candidates)-1]
+
+	return state.order[state.depth], candidate
+}
+
+// BackTrack undoes the pair which led to this state.
+func (state *approximateState) BackTrack() {
+	if state.depth == 0 {
+		return
+	}
+	q := state.order[state.depth-1]
+	delete(state.reverse, state.mapping[q])
+	delete(state.mapping, q)
+}
+
+// IsFeasablePair checks whether the differences of the pair stay within
+// the tolerance.
+func (state *approximateState) IsFeasablePair(queryNode, targetNode int) bool {
+	if _, ok := state.reverse[targetNode]; ok {
+		return false
+	}
+	if !state.opts.Required(state, NullNode, NullNode, queryNode, targetNode) {
+		return false
+	}
+	for _, q := range state.queryNeighbours[queryNode] {
+		if t, ok := state.mapping[q]; ok && !state.opts.Required(state, q, t, queryNode, targetNode) {
+			return false
+		}
+	}
+
+	tolerated := 0
+	if !state.fsem(state, NullNode, NullNode, queryNode, targetNode) {
+		tolerated += 1
+	}
+	tolerated += len(state.missingEdges(queryNode, targetNode, queryNode, targetNode))
+	for _, q := range state.queryNeighbours[queryNode] {
+		if t, ok := state.mapping[q]; ok {
+			tolerated += len(state.missingEdges(q, t, queryNode, targetNode))
+			tolerated += len(state.missingEdges(queryNode, targetNode, q, t))
+		}
+	}
+
+	if state.differences+tolerated > state.opts.MaxDifferences {
+		return false
+	}
+	state.pair, state.tolerated = [2]int{queryNode, targetNode}, tolerated
+	return true
+}
+
+func (state *approximateState) IsGoal() bool { return state.depth == len(state.order) }
+
+func (state *approximateState) IsDead() bool {
+	return len(state.order) > state.target.Order()
+}
+
+func (state *approximateState) GetMapping() map[int]int { return state.mapping }
+
+func (state *approximateState) NextState(queryNode, targetNode int) State {
+	if state.pair != [2]int{queryNode, targetNode} {
+		state.IsFeasablePair(queryNode, targetNode)
+	}
+	differences := state.differences + state.tolerated
+
+	state.mapping[queryNode] = targetNode
+	state.reverse[targetNode] = queryNode
+
+	next := &approximateState{
+		approximateMatcher: state.approximateMatcher,
+		depth:              state.depth + 1,
+		differences:        differences,
+		pair:               [2]int{NullNode, NullNode},
+	}
+	next.loadCandidates()
+
+	return next
+}
+
+func (state *approximateState) String() string {
+	str := ""
+
+	for _, q := range state.order[:state.depth] {
+		str += fmt.Sprintf("->(%d~>%d)", q, state.mapping[q])
+	}
+
+	return fmt.Sprintf("%s (%d differences)", str, state.differences)
+}
+
+// loadCandidates collects the target nodes for the query node at the depth
+// of the state. A node with more edges to matched neighbours than
+// differences left has to keep one of them, so it is looked for next to the
+// matches of its neighbours.
+func (state *approximateState) loadCandidates() {
+	state.candidates = nil
+	if state.depth == len(state.order) {
+		return
+	}
+
+	queryNode := state.order[state.depth]
+	edges := 0
+	for _, q := range state.queryNeighbours[queryNode] {
+		if _, ok := state.mapping[q]; ok {
+			edges += len(state.query.Edges(q, queryNode)) + len(state.query.Edges(queryNode, q))
+		}
+	}
+
+	if edges > state.opts.MaxDifferences-state.differences {
+		near := make(map[int]bool)
+		for _, q := range state.queryNeighbours[queryNode] {
+			if t, ok := state.mapping[q]; ok {
+				for _, n := range state.targetNeighbours.of(t) {
+					near[n] = true
+				}
+			}
+		}
+		for t := state.target.Order() - 1; t >= 0; t-- {
+			if _, ok := state.reverse[t]; !ok && near[t] {
+				state.candidates = append(state.candidates, t)
+			}
+		}
+		return
+	}
+
+	for t := state.target.Order() - 1; t >= 0; t-- {
+		if _, ok := state.reverse[t]; !ok {
+			state.candidates = append(state.candidates, t)
+		}
+	}
+}
+
+// missingEdges lists the query edges between the query nodes left over
+// after matching as many of them as possible to distinct target edges
+// between the target nodes.
+func (m *approximateMatcher) missingEdges(fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) []int {
+	queryEdges := m.query.Edges(fromQueryNode, toQueryNode)
+	if len(queryEdges) == 0 {
+		return nil
+	}
+	matched := matchEdges(queryEdges, m.target.Edges(fromTargetNode, toTargetNode), m.efeas)
+
+	missing := make([]int, 0)
+	for _, q := range queryEdges {
+		if _, ok := matched[q]; !ok {
+			missing = append(missing, q)
+		}
+	}
+	return missing
+}
+
+// approximateMappings completes a mapping by its matched edges, its
+// differences and their cost, once for every way to match as many query
+// edges as possible. Returns false if visit stopped the search.
+func (state *approximateState) approximateMappings(mapping map[int]int, visit func(m ApproximateMapping) bool) bool {
+	mismatches := make([]int, 0)
+	edges := make([]queryEdge, 0)
+	missing := 0
+	for _, a := range state.order {
+		if !state.fsem(state, NullNode, NullNode, a, mapping[a]) {
+			mismatches = append(mismatches, a)
+		}
+		for _, b := range distinct(state.query.Successors(a)) {
+			for _, id := range state.query.Edges(a, b) {
+				edges = append(edges, queryEdge{id, a, b})
+			}
+			missing += len(state.missingEdges(a, mapping[a], b, mapping[b]))
+		}
+	}
+	sort.Ints(mismatches)
+
+	matched := make(map[int]int, len(edges))
+	used := make(map[int]bool, len(edges))
+	unmatched := make([]int, 0, missing)
+
+	var embed func(i int) bool
+	embed = func(i int) bool {
+		if len(unmatched) > missing {
+			return true
+		}
+		if i == len(edges) {
+			m := ApproximateMapping{
+				Embedding:  Embedding{Nodes: mapping, Edges: make(map[int]int, len(matched))},
+				Mismatches: mismatches,
+				Missing:    slices.Sorted(slices.Values(unmatched)),
+			}
+			for q, t := range matched {
+				m.Edges[q] = t
+			}
+			m.Cost = float64(len(m.Mismatches))*state.opts.MismatchCost + float64(len(m.Missing))*state.opts.MissingEdgeCost
+			return visit(m)
+		}
+
+		e := edges[i]
+		for _, t := range state.target.Edges(mapping[e.from], mapping[e.to]) {
+			if used[t] || !state.efeas(e.id, t) {
+				continue
+			}
+
+			matched[e.id], used[t] = t, true
+			cont := embed(i + 1)
+			delete(matched, e.id)
+			used[t] = false
+
+			if !cont {
+				return false
+			}
+		}
+
+		unmatched = append(unmatched, e.id)
+		cont := embed(i + 1)
+		unmatched = unmatched[:len(unmatched)-1]
+		return cont
+	}
+
+	return embed(0)
+}
+
+// matchEdges matches as many query edges as possible to distinct target
+// edges by augmenting paths.
+func matchEdges(queryEdges, targetEdges []int, efeas EdgeFeasFunc) map[int]int {
+	matched := make(map[int]int, len(queryEdges))
+	owner := make(map[int]int, len(targetEdges))
+
+	var augment func(q int, visited map[int]bool) bool
+	augment = func(q int, visited map[int]bool) bool {
+		for _, t := range targetEdges {
+			if visited[t] || !efeas(q, t) {
+				continue
+			}
+			visited[t] = true
+
+			if other, ok := owner[t]; !ok || augment(other, visited) {
+				matched[q], owner[t] = t, q
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, q := range queryEdges {
+		augment(q, make(map[int]bool))
+	}
+	return matched
+}
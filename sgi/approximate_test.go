@@ -0,0 +1,209 @@
+package sgi
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// bruteForceApproximate counts the differences of every injective mapping of
+// the query into the target.
+func bruteForceApproximate(query, target *dbGraphMock, fsem SemFeasFunc, k int) map[string]int {
+	found := make(map[string]int)
+
+	mapping := make(map[int]int)
+	used := make(map[int]bool)
+	var assign func(q int)
+	assign = func(q int) {
+		if q == query.Order() {
+			differences := 0
+			for a := range query.edges {
+				if !fsem(nil, NullNode, NullNode, a, mapping[a]) {
+					differences += 1
+				}
+				for b := range query.edges[a] {
+					if query.edges[a][b] && !target.edges[mapping[a]][mapping[b]] {
+						differences += 1
+					}
+				}
+			}
+			if differences <= k {
+				found[mappingKey(mapping)] = differences
+			}
+			return
+		}
+		for t := 0; t < target.Order(); t++ {
+			if !used[t] {
+				mapping[q], used[t] = t, true
+				assign(q + 1)
+				delete(mapping, q)
+				used[t] = false
+			}
+		}
+	}
+	assign(0)
+
+	return found
+}
+
+func TestApproximateAgreesWithBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		query, target := randomMock(r, 1+r.Intn(4), 0.4), randomMock(r, 1+r.Intn(6), 0.3)
+		sameValues := func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+			return query.nodes[toQueryNode] == target.nodes[toTargetNode]
+		}
+		k := r.Intn(3)
+
+		expected := bruteForceApproximate(query, target, sameValues, k)
+		mappings := FindApproximateMappings(query, target, sameValues, nil, ApproximateOptions{MaxDifferences: k})
+
+		if len(mappings) != len(expected) {
+			t.Fatalf("Expected %d mappings of %v into %v with %d differences, got %d", len(expected), query.edges, target.edges, k, len(mappings))
+		}
+		for j, m := range mappings {
+			if differences, ok := expected[mappingKey(m.Nodes)]; !ok || differences != m.Differences() {
+				t.Fatalf("Expected %v to have %d differences, got %d", m.Nodes, differences, m.Differences())
+			}
+			if j > 0 && mappings[j-1].Cost > m.Cost {
+				t.Fatal("Expected the mappings ranked by cost, got ", mappings)
+			}
+		}
+	}
+}
+
+func TestApproximateExact(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 100; i++ {
+		query, target := randomMock(r, 1+r.Intn(4), 0.4), randomMock(r, 1+r.Intn(6), 0.4)
+
+		exact := FindMappings(query, target, nil, Monomorphism)
+		approximate := FindApproximateMappings(query, target, nil, nil, ApproximateOptions{})
+
+		mappings := make([]map[int]int, 0)
+		for _, m := range approximate {
+			mappings = append(mappings, m.Nodes)
+		}
+		if !sameMappings(exact, mappings) {
+			t.Fatalf("Expected no differences to match exactly, got %v for %v", mappings, exact)
+		}
+	}
+}
+
+func TestApproximateMissingEdges(t *testing.T) {
+	triangle := newMock(3, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0})
+	path := newMock(3, [2]int{0, 1}, [2]int{1, 2})
+
+	if mappings := FindApproximateMappings(triangle, path, nil, nil, ApproximateOptions{}); len(mappings) != 0 {
+		t.Error("Expected a path to be no triangle, got ", mappings)
+	}
+
+	mappings := FindApproximateMappings(triangle, path, nil, nil, ApproximateOptions{MaxDifferences: 1, MissingEdgeCost: 0.5})
+	if len(mappings) != 3 {
+		t.Fatal("Expected every rotation of the triangle to miss a relation, got ", mappings)
+	}
+	for _, m := range mappings {
+		if len(m.Missing) != 1 || len(m.Edges) != 2 || m.Cost != 0.5 {
+			t.Error("Expected a single relation to be missing, got ", m)
+		}
+		for q, e := range m.Edges {
+			if _, ok := m.Nodes[q/3]; !ok || path.Edges(m.Nodes[q/3], m.Nodes[q%3])[0] != e {
+				t.Error("Expected the relations between the matched nodes, got ", m)
+			}
+		}
+	}
+	if m := mappings[0]; m.Nodes[0] != 0 || m.Missing[0] != triangle.Edges(2, 0)[0] {
+		t.Error("Expected the closing relation of the path to be missing first, got ", m)
+	}
+}
+
+func TestApproximateRanking(t *testing.T) {
+	query := newMock(2, [2]int{0, 1})
+	query.nodes[0], query.nodes[1] = 1, 2
+
+	target := newMock(4, [2]int{0, 1}, [2]int{2, 3})
+	target.nodes = []int{1, 3, 1, 2}
+
+	sameValues := func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		return query.nodes[toQueryNode] == target.nodes[toTargetNode]
+	}
+
+	mappings := FindApproximateMappings(query, target, sameValues, nil, ApproximateOptions{MaxDifferences: 1, MismatchCost: 2})
+	if len(mappings) != 3 {
+		t.Fatal("Expected the exact, the unrelated and the mismatched nodes, got ", mappings)
+	}
+	if mappings[0].Nodes[0] != 2 || mappings[0].Cost != 0 {
+		t.Error("Expected the exact match first, got ", mappings[0])
+	}
+	if mappings[1].Nodes[1] != 3 || len(mappings[1].Missing) != 1 || mappings[1].Cost != 1 {
+		t.Error("Expected the missing relation next, got ", mappings[1])
+	}
+	if mappings[2].Nodes[1] != 1 || len(mappings[2].Mismatches) != 1 || mappings[2].Mismatches[0] != 1 || mappings[2].Cost != 2 {
+		t.Error("Expected the second node to mismatch last, got ", mappings[2])
+	}
+
+	required := func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		return fromQueryNode != NullNode || toQueryNode != 0 || toTargetNode == 0
+	}
+	mappings = FindApproximateMappings(query, target, sameValues, nil, ApproximateOptions{MaxDifferences: 1, Required: required})
+	if len(mappings) != 2 {
+		t.Fatal("Expected the required node with either partner, got ", mappings)
+	}
+	for _, m := range mappings {
+		if m.Nodes[0] != 0 {
+			t.Error("Expected only the required node, got ", m)
+		}
+	}
+}
+
+func TestApproximateParallelEdges(t *testing.T) {
+	target := &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}, {0, 1, "LIKES"}}}
+
+	query := &multiGraphMock{2, []typedEdge{{0, 1, ""}, {0, 1, "KNOWS"}, {0, 1, "KNOWS"}}}
+	mappings := FindApproximateMappings(query, target, nil, sameType(query, target), ApproximateOptions{MaxDifferences: 1})
+	if len(mappings) != 2 {
+		t.Fatal("Expected either of the typed relations to be missing, got ", mappings)
+	}
+	for _, m := range mappings {
+		if len(m.Missing) != 1 || m.Missing[0] == 0 || len(m.Edges) != 2 || m.Edges[0] != 1 {
+			t.Error("Expected the untyped relation to take the other type, got ", m)
+		}
+	}
+
+	// Without differences every embedding of the relations is found
+	query = &multiGraphMock{2, []typedEdge{{0, 1, ""}}}
+	exact := FindEmbeddings(query, target, nil, nil, Monomorphism)
+	mappings = FindApproximateMappings(query, target, nil, nil, ApproximateOptions{})
+	if len(mappings) != len(exact) || len(mappings) != 2 {
+		t.Fatalf("Expected the embeddings %v, got %v", exact, mappings)
+	}
+	for i, m := range mappings {
+		if len(m.Missing) != 0 || m.Edges[0] != exact[i].Edges[0] {
+			t.Errorf("Expected the embedding %v, got %v", exact[i], m)
+		}
+	}
+}
+
+func TestApproximateMappingsFuncStops(t *testing.T) {
+	query := newMock(2, [2]int{0, 1})
+	target := newMock(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3})
+
+	visited := 0
+	FindApproximateMappingsFunc(query, target, nil, nil, ApproximateOptions{MaxDifferences: 1}, func(m ApproximateMapping) bool {
+		visited += 1
+		return false
+	})
+	if visited != 1 {
+		t.Error("Expected the search to stop after the first mapping, got ", visited)
+	}
+
+	all := 0
+	FindApproximateMappingsFunc(query, target, nil, nil, ApproximateOptions{MaxDifferences: 1}, func(m ApproximateMapping) bool {
+		all += 1
+		return true
+	})
+	if ranked := FindApproximateMappings(query, target, nil, nil, ApproximateOptions{MaxDifferences: 1}); len(ranked) != all {
+		t.Errorf("Expected to rank all %d mappings, got %d", all, len(ranked))
+	}
+}
@@ -2,7 +2,10 @@
 package sgi
 
 import (
-	"reflect"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
 
 	"github.com/BuJo/goneo/log"
 )
@@ -18,6 +21,9 @@ type Graph interface {
 	Successors(n int) []int
 	Predecessors(n int) []int
 	Relations(n int) []int
+
+	// Edges from node a to b, parallel edges are told apart by their ids
+	Edges(a, b int) []int
 }
 
 type Edge interface {
@@ -47,7 +53,8 @@ type State interface {
 // SemFeasFunc to describe if it semantically feasable to traverse from one
 // node to another by looking at the describing query nodes and the actual
 // nodes in the graph.
-// This is called if a raw edge between the two nodes exist.
+// This is called if a raw edge between the two nodes exist. With NullNode as
+// the from nodes it is called to compare the nodes on their own.
 type SemFeasFunc func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool
 
 // FindIsomorphism using the given state machine.
@@ -55,36 +62,74 @@ func FindIsomorphism(initialState State) []map[int]int {
 
 	isoMappings := make([]map[int]int, 0)
 
-	match(initialState, &isoMappings)
+	FindIsomorphismFunc(initialState, func(mapping map[int]int) bool {
+		isoMappings = append(isoMappings, mapping)
+		return true
+	})
 
 	return isoMappings
 
 }
 
-func match(state State, isoMappings *[]map[int]int) {
+// FindIsomorphismFunc uses the given state machine and calls visit for every
+// distinct mapping as soon as it is found. The search stops when visit
+// returns false. The mapping handed to visit is owned by the caller.
+func FindIsomorphismFunc(initialState State, visit func(mapping map[int]int) bool) {
+	if unique, ok := initialState.(uniqueState); ok && unique.uniqueMappings() {
+		match(initialState, visit)
+		return
+	}
+
+	seen := make(map[string]bool)
+
+	match(initialState, func(mapping map[int]int) bool {
+		key := mappingKey(mapping)
+		if seen[key] {
+			log.Print("in mapping")
+			return true
+		}
+		log.Print("not in mapping")
+		seen[key] = true
+
+		return visit(mapping)
+	})
+}
+
+// Isomorphisms uses the given state machine to produce every distinct
+// mapping as soon as it is found. The search stops when the caller stops
+// iterating.
+func Isomorphisms(initialState State) iter.Seq[map[int]int] {
+	return func(yield func(map[int]int) bool) {
+		FindIsomorphismFunc(initialState, yield)
+	}
+}
+
+// uniqueState is implemented by states reaching every mapping only once,
+// their mappings need not be remembered to skip duplicates. This holds for
+// states extending the mapping by a single query node at a time, trying each
+// of its candidates once.
+type uniqueState interface {
+	uniqueMappings() bool
+}
+
+// match walks the state space, returns false if the walk was stopped.
+func match(state State, visit func(mapping map[int]int) bool) bool {
 	log.Print("Start Match")
 
 	if state.IsGoal() {
-		log.Print("Match Goal reached, len:", len(*isoMappings))
+		log.Print("Match Goal reached")
 		isoMapping := make(map[int]int)
 
 		for k, v := range state.GetMapping() {
 			isoMapping[k] = v
 		}
 
-		if !alreadyInMappings(*isoMappings, isoMapping) {
-			log.Print("not in mapping")
-			*isoMappings = append(*isoMappings, isoMapping)
-		} else {
-			log.Print("in mapping")
-		}
-
-		return
+		return visit(isoMapping)
 	}
 
 	if state.IsDead() {
 		log.Print("Match is dead")
-		return
+		return true
 	}
 
 	n1, n2 := state.NextPair()
@@ -97,22 +142,62 @@ func match(state State, isoMappings *[]map[int]int) {
 
 			next := state.NextState(n1, n2)
 
-			match(next, isoMappings)
+			cont := match(next, visit)
 
 			next.BackTrack()
+
+			if !cont {
+				return false
+			}
 		}
 	}
+
+	return true
 }
 
-func alreadyInMappings(mappings []map[int]int, mapping map[int]int) bool {
-	log.Print("in mapping? ", mappings, mapping)
+// mappingKey renders a mapping in a stable form for detecting duplicates.
+func mappingKey(mapping map[int]int) string {
+	keys := make([]int, 0, len(mapping))
+	for q := range mapping {
+		keys = append(keys, q)
+	}
+	sort.Ints(keys)
 
-	for _, m0 := range mappings {
-		eq := reflect.DeepEqual(m0, mapping)
-		if eq {
-			return true
+	b := new(strings.Builder)
+	for _, q := range keys {
+		fmt.Fprintf(b, "%d:%d,", q, mapping[q])
+	}
+
+	return b.String()
+}
+
+// distinct nodes in ascending order.
+func distinct(nodes []int) []int {
+	sort.Ints(nodes)
+	ns := make([]int, 0, len(nodes))
+	for i, n := range nodes {
+		if i == 0 || n != nodes[i-1] {
+			ns = append(ns, n)
 		}
 	}
+	return ns
+}
+
+// neighbourhood looks up the distinct neighbours of graph nodes once.
+type neighbourhood struct {
+	g     Graph
+	cache map[int][]int
+}
+
+func newNeighbourhood(g Graph) *neighbourhood {
+	return &neighbourhood{g, make(map[int][]int)}
+}
 
-	return false
+func (n *neighbourhood) of(node int) []int {
+	if ns, ok := n.cache[node]; ok {
+		return ns
+	}
+	ns := distinct(n.g.Relations(node))
+	n.cache[node] = ns
+	return ns
 }
@@ -0,0 +1,351 @@
+package sgi
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Colors tell nodes and edges apart beyond the structure of a graph. A nil
+// function colors all nodes or edges alike.
+type Colors struct {
+	Node func(node int) string
+	Edge func(edge int) string
+}
+
+// CanonicalForm of a graph, graphs are isomorphic respecting their colors
+// exactly if their certificates are equal.
+type CanonicalForm struct {
+	Certificate string
+	// Labeling maps every node to its position in the canonical order
+	Labeling []int
+}
+
+// Canonical computes the canonical form of a graph by partition refinement:
+// nodes are colored by their neighbourhoods until the colors are stable, then
+// nodes sharing a color are individualized one by one. Of all orders found
+// this way the one with the least relabeled graph is canonical. Branches
+// mapped onto each other by automorphisms found on the way are skipped.
+func Canonical(g Graph, colors Colors) CanonicalForm {
+	c := newCanonizer(g, colors)
+
+	if c.n > 0 {
+		c.search(c.nodeColors, nil)
+	} else {
+		c.best = []int{}
+	}
+
+	var str strings.Builder
+	str.WriteString(strconv.Itoa(c.n))
+	for _, names := range [][]string{c.nodeNames, c.pairNames} {
+		str.WriteString(";")
+		for i, name := range names {
+			if i > 0 {
+				str.WriteString(",")
+			}
+			str.WriteString(strconv.Quote(name))
+		}
+	}
+	str.WriteString(";")
+	for i, v := range c.bestCertificate {
+		if i > 0 {
+			str.WriteString(",")
+		}
+		str.WriteString(strconv.Itoa(v))
+	}
+
+	return CanonicalForm{str.String(), c.best}
+}
+
+// Certificate of a graph, see Canonical.
+func Certificate(g Graph, colors Colors) string {
+	return Canonical(g, colors).Certificate
+}
+
+// Isomorphic checks whether two graphs are the same respecting their colors
+// and returns a mapping of the nodes of a onto the nodes of b if so.
+func Isomorphic(a Graph, aColors Colors, b Graph, bColors Colors) (map[int]int, bool) {
+	if a.Order() != b.Order() {
+		return nil, false
+	}
+
+	ca, cb := Canonical(a, aColors), Canonical(b, bColors)
+	if ca.Certificate != cb.Certificate {
+		return nil, false
+	}
+
+	nodes := inverse(cb.Labeling)
+	mapping := make(map[int]int, len(ca.Labeling))
+	for v, pos := range ca.Labeling {
+		mapping[v] = nodes[pos]
+	}
+	return mapping, true
+}
+
+type canonizer struct {
+	n int
+
+	// colors ranked by their names
+	nodeColors []int
+	nodeNames  []string
+	pairColors map[[2]int]int
+	pairNames  []string
+
+	successors, predecessors [][]int
+
+	best            []int
+	bestCertificate []int
+	bestPrefix      []int
+	automorphisms   [][]int
+}
+
+func newCanonizer(g Graph, colors Colors) *canonizer {
+	c := &canonizer{
+		n:            g.Order(),
+		pairColors:   make(map[[2]int]int),
+		successors:   make([][]int, g.Order()),
+		predecessors: make([][]int, g.Order()),
+	}
+
+	names := make([]string, c.n)
+	for v := 0; v < c.n; v++ {
+		if colors.Node != nil {
+			names[v] = colors.Node(v)
+		}
+	}
+	c.nodeColors, c.nodeNames = rank(names)
+
+	// parallel edges are colored together
+	pairs := make([][2]int, 0)
+	pairNames := make([]string, 0)
+	for a := 0; a < c.n; a++ {
+		c.successors[a] = distinct(g.Successors(a))
+		for _, b := range c.successors[a] {
+			c.predecessors[b] = append(c.predecessors[b], a)
+
+			edges := make([]string, 0)
+			for _, e := range g.Edges(a, b) {
+				name := ""
+				if colors.Edge != nil {
+					name = colors.Edge(e)
+				}
+				edges = append(edges, strconv.Quote(name))
+			}
+			sort.Strings(edges)
+
+			pairs = append(pairs, [2]int{a, b})
+			pairNames = append(pairNames, strings.Join(edges, " "))
+		}
+	}
+	ranks, names := rank(pairNames)
+	for i, pair := range pairs {
+		c.pairColors[pair] = ranks[i]
+	}
+	c.pairNames = names
+
+	return c
+}
+
+// rank numbers the names in their sorted order.
+func rank(names []string) ([]int, []string) {
+	sorted := slices.Clone(names)
+	sort.Strings(sorted)
+	sorted = slices.Compact(sorted)
+
+	ranks := make([]int, len(names))
+	for i, name := range names {
+		ranks[i], _ = slices.BinarySearch(sorted, name)
+	}
+	return ranks, sorted
+}
+
+// refine colors nodes by their color and the colors of their neighbours until
+// no color class splits any further. Colors are numbered by the order of
+// their signatures, so classes split in place.
+func (c *canonizer) refine(colors []int) []int {
+	count := countColors(colors)
+
+	for {
+		signatures := make([][]int, c.n)
+		for v := 0; v < c.n; v++ {
+			out := make([][2]int, 0, len(c.successors[v]))
+			for _, w := range c.successors[v] {
+				out = append(out, [2]int{colors[w], c.pairColors[[2]int{v, w}]})
+			}
+			in := make([][2]int, 0, len(c.predecessors[v]))
+			for _, w := range c.predecessors[v] {
+				in = append(in, [2]int{colors[w], c.pairColors[[2]int{w, v}]})
+			}
+
+			signature := []int{colors[v], len(out)}
+			for _, neighbours := range [][][2]int{out, in} {
+				slices.SortFunc(neighbours, func(a, b [2]int) int { return slices.Compare(a[:], b[:]) })
+				for _, neighbour := range neighbours {
+					signature = append(signature, neighbour[0], neighbour[1])
+				}
+			}
+			signatures[v] = signature
+		}
+
+		order := make([]int, c.n)
+		for v := range order {
+			order[v] = v
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return slices.Compare(signatures[order[i]], signatures[order[j]]) < 0
+		})
+
+		refined := make([]int, c.n)
+		color := 0
+		for i, v := range order {
+			if i > 0 && slices.Compare(signatures[order[i-1]], signatures[v]) != 0 {
+				color += 1
+			}
+			refined[v] = color
+		}
+
+		if refinedCount := color + 1; refinedCount == count {
+			return refined
+		} else {
+			count = refinedCount
+			colors = refined
+		}
+	}
+}
+
+func countColors(colors []int) int {
+	seen := make(map[int]bool)
+	for _, color := range colors {
+		seen[color] = true
+	}
+	return len(seen)
+}
+
+// search individualizes the nodes of the first color class with more than
+// one node, leaves with a node per color are labelings of the graph. It
+// returns the depth to continue the search at.
+func (c *canonizer) search(colors []int, prefix []int) int {
+	colors = c.refine(colors)
+
+	cells := make(map[int][]int)
+	target := NullNode
+	for v, color := range colors {
+		cells[color] = append(cells[color], v)
+		if len(cells[color]) > 1 && (target == NullNode || color < target) {
+			target = color
+		}
+	}
+
+	if target == NullNode {
+		return c.leaf(colors, prefix)
+	}
+
+	tried := make([]int, 0)
+	for _, v := range cells[target] {
+		if c.sameOrbit(v, tried, prefix) {
+			continue
+		}
+		tried = append(tried, v)
+
+		individualized := make([]int, c.n)
+		for w, color := range colors {
+			individualized[w] = 2*color + 1
+		}
+		individualized[v] = 2 * colors[v]
+
+		if depth := c.search(individualized, append(prefix, v)); depth < len(prefix) {
+			return depth
+		}
+	}
+	return len(prefix)
+}
+
+// sameOrbit checks whether an automorphism fixing the prefix maps the node
+// onto one of the tried nodes, its branch looks the same then.
+func (c *canonizer) sameOrbit(v int, tried []int, prefix []int) bool {
+	orbits := make([]int, c.n)
+	for w := range orbits {
+		orbits[w] = w
+	}
+	var find func(w int) int
+	find = func(w int) int {
+		if orbits[w] != w {
+			orbits[w] = find(orbits[w])
+		}
+		return orbits[w]
+	}
+
+	for _, automorphism := range c.automorphisms {
+		fixes := true
+		for _, p := range prefix {
+			if automorphism[p] != p {
+				fixes = false
+				break
+			}
+		}
+		if !fixes {
+			continue
+		}
+		for w, image := range automorphism {
+			orbits[find(w)] = find(image)
+		}
+	}
+
+	for _, w := range tried {
+		if find(w) == find(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// leaf compares the graph relabeled by a discrete coloring to the least one
+// found so far, equal ones reveal an automorphism. The automorphism maps the
+// branch the leaf is on to the explored branch of the least leaf, so the
+// search continues where the two part.
+func (c *canonizer) leaf(labeling []int, prefix []int) int {
+	certificate := make([]int, 0, c.n+3*len(c.pairColors))
+
+	for _, v := range inverse(labeling) {
+		certificate = append(certificate, c.nodeColors[v])
+	}
+
+	edges := make([][3]int, 0, len(c.pairColors))
+	for pair, color := range c.pairColors {
+		edges = append(edges, [3]int{labeling[pair[0]], labeling[pair[1]], color})
+	}
+	slices.SortFunc(edges, func(a, b [3]int) int { return slices.Compare(a[:], b[:]) })
+	for _, edge := range edges {
+		certificate = append(certificate, edge[0], edge[1], edge[2])
+	}
+
+	switch cmp := slices.Compare(certificate, c.bestCertificate); {
+	case c.best == nil || cmp < 0:
+		c.best, c.bestCertificate = slices.Clone(labeling), certificate
+		c.bestPrefix = slices.Clone(prefix)
+	case cmp == 0:
+		nodes := inverse(c.best)
+		automorphism := make([]int, c.n)
+		for v, pos := range labeling {
+			automorphism[v] = nodes[pos]
+		}
+		c.automorphisms = append(c.automorphisms, automorphism)
+
+		depth := 0
+		for depth < len(prefix) && depth < len(c.bestPrefix) && prefix[depth] == c.bestPrefix[depth] {
+			depth += 1
+		}
+		return depth
+	}
+	return len(prefix)
+}
+
+// inverse of a labeling, the node at every position.
+func inverse(labeling []int) []int {
+	nodes := make([]int, len(labeling))
+	for v, pos := range labeling {
+		nodes[pos] = v
+	}
+	return nodes
+}
@@ -0,0 +1,195 @@
+package sgi
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/db/mem"
+)
+
+// randomMock relates every ordered pair of nodes with the given probability.
+func randomMock(r *rand.Rand, order int, p float64) *dbGraphMock {
+	edges := make([][2]int, 0)
+	for a := 0; a < order; a++ {
+		for b := 0; b < order; b++ {
+			if r.Float64() < p {
+				edges = append(edges, [2]int{a, b})
+			}
+		}
+	}
+	g := newMock(order, edges...)
+	for v := range g.nodes {
+		g.nodes[v] = r.Intn(2)
+	}
+	return g
+}
+
+// permuted relabels the nodes of a graph.
+func permuted(r *rand.Rand, g *dbGraphMock) *dbGraphMock {
+	perm := r.Perm(g.Order())
+	edges := make([][2]int, 0)
+	for a := range g.edges {
+		for b, ok := range g.edges[a] {
+			if ok {
+				edges = append(edges, [2]int{perm[a], perm[b]})
+			}
+		}
+	}
+	h := newMock(g.Order(), edges...)
+	for v, value := range g.nodes {
+		h.nodes[perm[v]] = value
+	}
+	return h
+}
+
+func valued(g *dbGraphMock) Colors {
+	return Colors{Node: func(node int) string { return strconv.Itoa(g.nodes[node]) }}
+}
+
+func isIsomorphism(a, b *dbGraphMock, mapping map[int]int) bool {
+	for u := range a.edges {
+		if a.nodes[u] != b.nodes[mapping[u]] {
+			return false
+		}
+		for v := range a.edges[u] {
+			if a.edges[u][v] != b.edges[mapping[u]][mapping[v]] {
+				return false
+			}
+		}
+	}
+	return len(mapping) == a.Order()
+}
+
+func TestCanonicalPermuted(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		g := randomMock(r, r.Intn(8), r.Float64())
+		h := permuted(r, g)
+
+		mapping, ok := Isomorphic(g, valued(g), h, valued(h))
+		if !ok || !isIsomorphism(g, h, mapping) {
+			t.Fatalf("Expected %v to be isomorphic to %v, got %v", g.edges, h.edges, mapping)
+		}
+		if Certificate(g, valued(g)) != Certificate(h, valued(h)) {
+			t.Fatal("Expected the certificates to be equal for ", g.edges)
+		}
+	}
+}
+
+func TestCanonicalAgreesWithMatching(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 300; i++ {
+		n := 1 + r.Intn(5)
+		g, h := randomMock(r, n, 0.3), randomMock(r, n, 0.3)
+
+		sameValues := func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+			return g.nodes[toQueryNode] == h.nodes[toTargetNode]
+		}
+		expected := len(FindMappings(g, h, sameValues, GraphIsomorphism)) > 0
+
+		if _, ok := Isomorphic(g, valued(g), h, valued(h)); ok != expected {
+			t.Fatalf("Expected isomorphism of %v and %v to be %v", g.edges, h.edges, expected)
+		}
+	}
+}
+
+func TestCanonicalSymmetric(t *testing.T) {
+	cycle := func(order int, offset int) [][2]int {
+		edges := make([][2]int, 0)
+		for v := 0; v < order; v++ {
+			edges = append(edges, [2]int{offset + v, offset + (v+1)%order})
+		}
+		return edges
+	}
+	complete := make([][2]int, 0)
+	for a := 0; a < 8; a++ {
+		for b := 0; b < 8; b++ {
+			if a != b {
+				complete = append(complete, [2]int{a, b})
+			}
+		}
+	}
+
+	hexagon := newMock(6, cycle(6, 0)...)
+	triangles := newMock(6, append(cycle(3, 0), cycle(3, 3)...)...)
+
+	// Refinement alone can not tell the cycles apart, every node looks alike
+	if _, ok := Isomorphic(hexagon, Colors{}, triangles, Colors{}); ok {
+		t.Error("Expected a hexagon to differ from two triangles")
+	}
+
+	r := rand.New(rand.NewSource(3))
+	for name, g := range map[string]*dbGraphMock{
+		"empty":     newMock(40),
+		"complete":  newMock(8, complete...),
+		"cycle":     newMock(16, cycle(16, 0)...),
+		"triangles": newMock(12, append(append(cycle(3, 0), cycle(3, 3)...), append(cycle(3, 6), cycle(3, 9)...)...)...),
+	} {
+		if _, ok := Isomorphic(g, Colors{}, permuted(r, g), Colors{}); !ok {
+			t.Errorf("%s: expected the permuted graph to be isomorphic", name)
+		}
+	}
+}
+
+func TestCanonicalColors(t *testing.T) {
+	g := newMock(2, [2]int{0, 1})
+	h := newMock(2, [2]int{0, 1})
+	g.nodes[0], h.nodes[1] = 1, 1
+
+	if _, ok := Isomorphic(g, valued(g), h, valued(h)); ok {
+		t.Error("Expected the values to tell the graphs apart")
+	}
+	if _, ok := Isomorphic(g, Colors{}, h, Colors{}); !ok {
+		t.Error("Expected the structures to be isomorphic")
+	}
+
+	typed := &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}, {1, 0, "LIKES"}}}
+	colors := Colors{Edge: func(edge int) string { return typed.edges[edge].typ }}
+	swapped := &multiGraphMock{2, []typedEdge{{0, 1, "LIKES"}, {1, 0, "KNOWS"}}}
+	swappedColors := Colors{Edge: func(edge int) string { return swapped.edges[edge].typ }}
+	if mapping, ok := Isomorphic(typed, colors, swapped, swappedColors); !ok || mapping[0] != 1 {
+		t.Error("Expected the nodes to be swapped, got ", mapping)
+	}
+
+	parallel := &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}, {0, 1, "KNOWS"}}}
+	parallelColors := Colors{Edge: func(edge int) string { return parallel.edges[edge].typ }}
+	single := &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}}}
+	singleColors := Colors{Edge: func(edge int) string { return single.edges[edge].typ }}
+	if _, ok := Isomorphic(parallel, parallelColors, single, singleColors); ok {
+		t.Error("Expected parallel relations to count")
+	}
+}
+
+func TestSubgraphCertificate(t *testing.T) {
+	graph, _ := mem.NewDb("subgraphs", nil)
+
+	// the same pattern created in different orders
+	a1, b1 := graph.NewNode("Person"), graph.NewNode("Person", "Actor")
+	a1.RelateTo(b1, "KNOWS")
+	b2, a2 := graph.NewNode("Actor", "Person"), graph.NewNode("Person")
+	a2.RelateTo(b2, "KNOWS")
+	a3, b3 := graph.NewNode("Person"), graph.NewNode("Person", "Actor")
+	a3.RelateTo(b3, "LIKES")
+
+	first, second, third := NewSubgraph([]Node{a1, b1}), NewSubgraph([]Node{b2, a2}), NewSubgraph([]Node{a3, b3})
+
+	if Certificate(first, first.Colors()) != Certificate(second, second.Colors()) {
+		t.Error("Expected the same certificate for the same pattern")
+	}
+	if Certificate(first, first.Colors()) == Certificate(third, third.Colors()) {
+		t.Error("Expected relation types to tell the patterns apart")
+	}
+	if mapping, ok := Isomorphic(first, first.Colors(), second, second.Colors()); !ok || second.Node(mapping[0]) != a2 {
+		t.Error("Expected the nodes to be mapped onto their copies, got ", mapping)
+	}
+
+	// Relations to nodes outside the subgraph are left out
+	a1.RelateTo(a3, "KNOWS")
+	if Certificate(NewSubgraph([]Node{a1, b1}), first.Colors()) != Certificate(first, first.Colors()) {
+		t.Error("Expected only the relations within the subgraph")
+	}
+}
@@ -0,0 +1,132 @@
+package sgi
+
+import "iter"
+
+// EdgeFeasFunc describes if a query edge may be matched to a target edge,
+// both given by their ids.
+type EdgeFeasFunc func(queryEdge, targetEdge int) bool
+
+// Embedding maps the nodes and edges of a query to the target.
+type Embedding struct {
+	// Nodes maps query nodes to target nodes
+	Nodes map[int]int
+	// Edges maps query edges to target edges
+	Edges map[int]int
+}
+
+type queryEdge struct {
+	id, from, to int
+}
+
+// FindEmbeddings finds the mappings of the given mode together with the
+// target edges each query edge is matched to. Every query edge is matched to
+// a distinct target edge passing the EdgeFeasFunc, mappings with several ways
+// to match the edges are found once per way.
+func FindEmbeddings(query, target Graph, fsem SemFeasFunc, efeas EdgeFeasFunc, mode Mode) []Embedding {
+	embeddings := make([]Embedding, 0)
+
+	FindEmbeddingsFunc(query, target, fsem, efeas, mode, func(embedding Embedding) bool {
+		embeddings = append(embeddings, embedding)
+		return true
+	})
+
+	return embeddings
+}
+
+// FindEmbeddingsFunc works like FindEmbeddings but hands each embedding to
+// visit as soon as it is found. Returning false from visit stops the search.
+func FindEmbeddingsFunc(query, target Graph, fsem SemFeasFunc, efeas EdgeFeasFunc, mode Mode, visit func(embedding Embedding) bool) {
+	if efeas == nil {
+		efeas = func(q, t int) bool { return true }
+	}
+
+	edges := make([]queryEdge, 0)
+	between := make(map[[2]int][]int)
+	for a := 0; a < query.Order(); a++ {
+		for _, b := range distinct(query.Successors(a)) {
+			for _, id := range query.Edges(a, b) {
+				edges = append(edges, queryEdge{id, a, b})
+				between[[2]int{a, b}] = append(between[[2]int{a, b}], id)
+			}
+		}
+	}
+
+	// Prune node pairs without feasable edges while matching the nodes
+	feasable := func(queryEdges, targetEdges []int) bool {
+		for _, q := range queryEdges {
+			found := false
+			for _, t := range targetEdges {
+				if efeas(q, t) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	nodeFeas := func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		if fsem != nil && !fsem(state, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode) {
+			return false
+		}
+		if fromQueryNode == NullNode {
+			return true
+		}
+		return feasable(between[[2]int{fromQueryNode, toQueryNode}], target.Edges(fromTargetNode, toTargetNode)) &&
+			feasable(between[[2]int{toQueryNode, fromQueryNode}], target.Edges(toTargetNode, fromTargetNode))
+	}
+
+	FindMappingsFunc(query, target, nodeFeas, mode, func(mapping map[int]int) bool {
+		return embedEdges(target, edges, efeas, mapping, visit)
+	})
+}
+
+// Embeddings produces the embeddings of FindEmbeddings as they are found, the
+// search stops when the caller stops iterating.
+func Embeddings(query, target Graph, fsem SemFeasFunc, efeas EdgeFeasFunc, mode Mode) iter.Seq[Embedding] {
+	return func(yield func(Embedding) bool) {
+		FindEmbeddingsFunc(query, target, fsem, efeas, mode, yield)
+	}
+}
+
+// embedEdges tries all ways to match the query edges to distinct target
+// edges, returns false if visit stopped the search.
+func embedEdges(target Graph, edges []queryEdge, efeas EdgeFeasFunc, mapping map[int]int, visit func(embedding Embedding) bool) bool {
+	matched := make(map[int]int, len(edges))
+	used := make(map[int]bool, len(edges))
+
+	var embed func(i int) bool
+	embed = func(i int) bool {
+		if i == len(edges) {
+			embedding := Embedding{Nodes: make(map[int]int, len(mapping)), Edges: make(map[int]int, len(matched))}
+			for q, t := range mapping {
+				embedding.Nodes[q] = t
+			}
+			for q, t := range matched {
+				embedding.Edges[q] = t
+			}
+			return visit(embedding)
+		}
+
+		e := edges[i]
+		for _, t := range target.Edges(mapping[e.from], mapping[e.to]) {
+			if used[t] || !efeas(e.id, t) {
+				continue
+			}
+
+			matched[e.id], used[t] = t, true
+			cont := embed(i + 1)
+			delete(matched, e.id)
+			used[t] = false
+
+			if !cont {
+				return false
+			}
+		}
+		return true
+	}
+
+	return embed(0)
+}
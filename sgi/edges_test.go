@@ -0,0 +1,148 @@
+package sgi
+
+import (
+	"testing"
+)
+
+// multiGraphMock is a graph with typed and parallel edges, edges are
+// identified by their index.
+type multiGraphMock struct {
+	order int
+	edges []typedEdge
+}
+
+type typedEdge struct {
+	from, to int
+	typ      string
+}
+
+func (g *multiGraphMock) Order() int             { return g.order }
+func (g *multiGraphMock) Contains(a, b int) bool { return len(g.Edges(a, b)) > 0 }
+func (g *multiGraphMock) Successors(a int) []int {
+	ids := make([]int, 0)
+	for _, e := range g.edges {
+		if e.from == a {
+			ids = append(ids, e.to)
+		}
+	}
+	return ids
+}
+func (g *multiGraphMock) Predecessors(a int) []int {
+	ids := make([]int, 0)
+	for _, e := range g.edges {
+		if e.to == a {
+			ids = append(ids, e.from)
+		}
+	}
+	return ids
+}
+func (g *multiGraphMock) Relations(a int) []int {
+	return append(g.Successors(a), g.Predecessors(a)...)
+}
+func (g *multiGraphMock) Edges(a, b int) []int {
+	ids := make([]int, 0)
+	for i, e := range g.edges {
+		if e.from == a && e.to == b {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// sameType matches edges of the same type, untyped query edges match all.
+func sameType(query, target *multiGraphMock) EdgeFeasFunc {
+	return func(q, t int) bool {
+		return query.edges[q].typ == "" || query.edges[q].typ == target.edges[t].typ
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	target := &multiGraphMock{3, []typedEdge{{0, 1, "LIKES"}, {0, 1, "KNOWS"}, {1, 2, "KNOWS"}}}
+
+	query := &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}}}
+	embeddings := FindEmbeddings(query, target, nil, sameType(query, target), Monomorphism)
+	if len(embeddings) != 2 {
+		t.Fatal("Expected both known pairs, got ", embeddings)
+	}
+	for _, embedding := range embeddings {
+		e := target.edges[embedding.Edges[0]]
+		if e.typ != "KNOWS" || e.from != embedding.Nodes[0] || e.to != embedding.Nodes[1] {
+			t.Error("Expected the matched relation between the matched nodes, got ", embedding)
+		}
+	}
+
+	query = &multiGraphMock{2, []typedEdge{{0, 1, "LIKES"}}}
+	embeddings = FindEmbeddings(query, target, nil, sameType(query, target), Monomorphism)
+	if len(embeddings) != 1 || embeddings[0].Edges[0] != 0 {
+		t.Error("Expected the parallel relation of the type, got ", embeddings)
+	}
+
+	query = &multiGraphMock{2, []typedEdge{{0, 1, "HATES"}}}
+	if embeddings = FindEmbeddings(query, target, nil, sameType(query, target), Monomorphism); len(embeddings) != 0 {
+		t.Error("Expected no relation of the type, got ", embeddings)
+	}
+}
+
+func TestParallelEmbeddings(t *testing.T) {
+	target := &multiGraphMock{3, []typedEdge{{0, 1, "LIKES"}, {0, 1, "KNOWS"}, {1, 2, "KNOWS"}}}
+
+	// Two relations match the parallel relations either way round
+	query := &multiGraphMock{2, []typedEdge{{0, 1, ""}, {0, 1, ""}}}
+	embeddings := FindEmbeddings(query, target, nil, nil, Monomorphism)
+	if len(embeddings) != 2 || embeddings[0].Edges[0] == embeddings[1].Edges[0] {
+		t.Error("Expected both ways to match the parallel relations, got ", embeddings)
+	}
+
+	query = &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}, {0, 1, "KNOWS"}}}
+	if embeddings = FindEmbeddings(query, target, nil, sameType(query, target), Monomorphism); len(embeddings) != 0 {
+		t.Error("Expected relations to be matched once, got ", embeddings)
+	}
+}
+
+func TestHomomorphicEmbeddings(t *testing.T) {
+	mutual := &multiGraphMock{2, []typedEdge{{0, 1, ""}, {1, 0, ""}}}
+
+	// Nodes may be revisited, relations not
+	path := &multiGraphMock{3, []typedEdge{{0, 1, ""}, {1, 2, ""}}}
+	if embeddings := FindEmbeddings(path, mutual, nil, nil, Homomorphism); len(embeddings) != 2 {
+		t.Error("Expected the path to walk back, got ", embeddings)
+	}
+
+	longer := &multiGraphMock{4, []typedEdge{{0, 1, ""}, {1, 2, ""}, {2, 3, ""}}}
+	if embeddings := FindEmbeddings(longer, mutual, nil, nil, Homomorphism); len(embeddings) != 0 {
+		t.Error("Expected the path to run out of relations, got ", embeddings)
+	}
+	if mappings := FindMappings(longer, mutual, nil, Homomorphism); len(mappings) != 2 {
+		t.Error("Expected the nodes alone to be mapped, got ", mappings)
+	}
+}
+
+func TestStoppingEmbeddings(t *testing.T) {
+	target := &multiGraphMock{2, []typedEdge{{0, 1, ""}, {0, 1, ""}, {0, 1, ""}}}
+	query := &multiGraphMock{2, []typedEdge{{0, 1, ""}}}
+
+	visited := 0
+	FindEmbeddingsFunc(query, target, nil, nil, Monomorphism, func(embedding Embedding) bool {
+		visited += 1
+		return false
+	})
+	if visited != 1 {
+		t.Error("Search should stop after the first embedding, visited ", visited)
+	}
+}
+
+func TestEmbeddingsStream(t *testing.T) {
+	target := &multiGraphMock{2, []typedEdge{{0, 1, ""}, {0, 1, ""}, {0, 1, ""}}}
+	query := &multiGraphMock{2, []typedEdge{{0, 1, ""}}}
+
+	edges := make(map[int]bool)
+	for embedding := range Embeddings(query, target, nil, nil, Monomorphism) {
+		edges[embedding.Edges[0]] = true
+		if len(edges) == 2 {
+			break
+		}
+	}
+	if len(edges) != 2 {
+		t.Error("Expected to stop after two distinct relations, got ", edges)
+	}
+}
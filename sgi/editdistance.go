@@ -0,0 +1,476 @@
+package sgi
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// EditCosts price the operations turning one graph into another. Nil
+// substitutions cost nothing, nil insertions and deletions cost 1. Costs must
+// not be negative.
+type EditCosts struct {
+	// NodeSubstitution costs replacing node a of the first graph by node b
+	// of the second
+	NodeSubstitution func(a, b int) float64
+	NodeInsertion    func(b int) float64
+	NodeDeletion     func(a int) float64
+
+	// EdgeSubstitution costs replacing edge a of the first graph by edge b of
+	// the second, both given by their ids
+	EdgeSubstitution func(a, b int) float64
+	EdgeInsertion    func(b int) float64
+	EdgeDeletion     func(a int) float64
+}
+
+// EditPath turns the first graph into the second. Nodes and edges of the
+// second graph nothing is mapped onto are inserted.
+type EditPath struct {
+	// Nodes maps the nodes of the first graph onto the second, deleted nodes
+	// onto NullNode
+	Nodes map[int]int
+	// Edges maps the edges of the first graph onto the second, deleted edges
+	// onto NullNode
+	Edges map[int]int
+
+	Cost float64
+}
+
+// EditDistance finds the cheapest edit path between two graphs by A*
+// search over the mappings of the nodes of a. The remaining cost is
+// estimated by an optimal assignment of the remaining nodes and the number
+// of edges left over. The search is exponential in the worst case, it suits
+// small graphs only, see ApproximateEditDistance.
+func EditDistance(a, b Graph, costs EditCosts) EditPath {
+	e := newEditor(a, b, costs)
+
+	queue := &editQueue{{mapping: []int{}, priority: e.estimate(nil)}}
+	for queue.Len() > 0 {
+		s := heap.Pop(queue).(editSearchState)
+		if s.complete {
+			return e.editPath(s.mapping)
+		}
+
+		if len(s.mapping) == len(e.order) {
+			cost := s.cost + e.completion(s.mapping)
+			heap.Push(queue, editSearchState{mapping: s.mapping, cost: cost, priority: cost, complete: true})
+			continue
+		}
+
+		used := make(map[int]bool, len(s.mapping))
+		for _, v := range s.mapping {
+			used[v] = true
+		}
+		for v := NullNode; v < b.Order(); v++ {
+			if v != NullNode && used[v] {
+				continue
+			}
+			mapping := append(s.mapping[:len(s.mapping):len(s.mapping)], v)
+			cost := s.cost + e.extension(mapping)
+			heap.Push(queue, editSearchState{mapping: mapping, cost: cost, priority: cost + e.estimate(mapping)})
+		}
+	}
+
+	return EditPath{}
+}
+
+// ApproximateEditDistance finds an edit path by assigning the nodes of both
+// graphs at once, each pair priced by the nodes and an assignment of their
+// edges. The cost of the path found is an upper bound of the edit distance,
+// computed in polynomial time.
+func ApproximateEditDistance(a, b Graph, costs EditCosts) EditPath {
+	e := newEditor(a, b, costs)
+
+	// edges of a node, each edge counts half at both of its nodes
+	type incident struct{ out, in []int }
+	incidents := func(g Graph, u int) incident {
+		inc := incident{}
+		for _, w := range distinct(g.Successors(u)) {
+			inc.out = append(inc.out, g.Edges(u, w)...)
+		}
+		for _, w := range distinct(g.Predecessors(u)) {
+			inc.in = append(inc.in, g.Edges(w, u)...)
+		}
+		return inc
+	}
+	aIncident, bIncident := make([]incident, a.Order()), make([]incident, b.Order())
+	for u := range aIncident {
+		aIncident[u] = incidents(a, u)
+	}
+	for v := range bIncident {
+		bIncident[v] = incidents(b, v)
+	}
+
+	half := func(as, bs []int) float64 {
+		_, cost := e.assignEdges(as, bs)
+		return cost / 2
+	}
+	nodes, _ := assign(a.Order(), b.Order(),
+		func(u, v int) float64 {
+			return e.costs.NodeSubstitution(u, v) + half(aIncident[u].out, bIncident[v].out) + half(aIncident[u].in, bIncident[v].in)
+		},
+		func(u int) float64 {
+			return e.costs.NodeDeletion(u) + half(aIncident[u].out, nil) + half(aIncident[u].in, nil)
+		},
+		func(v int) float64 {
+			return e.costs.NodeInsertion(v) + half(nil, bIncident[v].out) + half(nil, bIncident[v].in)
+		},
+	)
+
+	mapping := make([]int, len(e.order))
+	for i, u := range e.order {
+		mapping[i] = nodes[u]
+	}
+	return e.editPath(mapping)
+}
+
+// editor prices the edits between two graphs. Mappings are given in the
+// order the nodes of a are mapped in.
+type editor struct {
+	a, b  Graph
+	costs EditCosts
+
+	order []int
+
+	minEdgeDeletion, minEdgeInsertion float64
+}
+
+func newEditor(a, b Graph, costs EditCosts) *editor {
+	if costs.NodeSubstitution == nil {
+		costs.NodeSubstitution = func(a, b int) float64 { return 0 }
+	}
+	if costs.NodeInsertion == nil {
+		costs.NodeInsertion = func(b int) float64 { return 1 }
+	}
+	if costs.NodeDeletion == nil {
+		costs.NodeDeletion = func(a int) float64 { return 1 }
+	}
+	if costs.EdgeSubstitution == nil {
+		costs.EdgeSubstitution = func(a, b int) float64 { return 0 }
+	}
+	if costs.EdgeInsertion == nil {
+		costs.EdgeInsertion = func(b int) float64 { return 1 }
+	}
+	if costs.EdgeDeletion == nil {
+		costs.EdgeDeletion = func(a int) float64 { return 1 }
+	}
+
+	e := &editor{a: a, b: b, costs: costs, minEdgeDeletion: math.Inf(1), minEdgeInsertion: math.Inf(1)}
+
+	// nodes with many relations constrain the search the most
+	degrees := make([]int, a.Order())
+	for u := range degrees {
+		e.order = append(e.order, u)
+		degrees[u] = len(a.Relations(u))
+		for _, w := range distinct(a.Successors(u)) {
+			for _, edge := range a.Edges(u, w) {
+				e.minEdgeDeletion = math.Min(e.minEdgeDeletion, costs.EdgeDeletion(edge))
+			}
+		}
+	}
+	sort.SliceStable(e.order, func(i, j int) bool { return degrees[e.order[i]] > degrees[e.order[j]] })
+
+	for v := 0; v < b.Order(); v++ {
+		for _, w := range distinct(b.Successors(v)) {
+			for _, edge := range b.Edges(v, w) {
+				e.minEdgeInsertion = math.Min(e.minEdgeInsertion, costs.EdgeInsertion(edge))
+			}
+		}
+	}
+
+	return e
+}
+
+func (e *editor) nodeCost(u, v int) float64 {
+	switch {
+	case v == NullNode:
+		return e.costs.NodeDeletion(u)
+	case u == NullNode:
+		return e.costs.NodeInsertion(v)
+	}
+	return e.costs.NodeSubstitution(u, v)
+}
+
+// edgesBetween a and b, none if either is deleted or inserted.
+func edgesBetween(g Graph, a, b int) []int {
+	if a == NullNode || b == NullNode {
+		return nil
+	}
+	return g.Edges(a, b)
+}
+
+// assignEdges maps parallel edges of a onto parallel edges of b as cheap as
+// possible.
+func (e *editor) assignEdges(as, bs []int) ([]int, float64) {
+	if len(as) == 0 {
+		cost := 0.0
+		for _, edge := range bs {
+			cost += e.costs.EdgeInsertion(edge)
+		}
+		return nil, cost
+	}
+
+	assigned, cost := assign(len(as), len(bs),
+		func(i, j int) float64 { return e.costs.EdgeSubstitution(as[i], bs[j]) },
+		func(i int) float64 { return e.costs.EdgeDeletion(as[i]) },
+		func(j int) float64 { return e.costs.EdgeInsertion(bs[j]) },
+	)
+	for i, j := range assigned {
+		if j != NullNode {
+			assigned[i] = bs[j]
+		}
+	}
+	return assigned, cost
+}
+
+// extension prices mapping the last node of the mapping, together with the
+// edges to all nodes mapped before.
+func (e *editor) extension(mapping []int) float64 {
+	k := len(mapping) - 1
+	u, v := e.order[k], mapping[k]
+
+	cost := e.nodeCost(u, v)
+	for i := 0; i <= k; i++ {
+		w, x := e.order[i], mapping[i]
+		_, out := e.assignEdges(edgesBetween(e.a, u, w), edgesBetween(e.b, v, x))
+		cost += out
+		if i < k {
+			_, in := e.assignEdges(edgesBetween(e.a, w, u), edgesBetween(e.b, x, v))
+			cost += in
+		}
+	}
+	return cost
+}
+
+// completion prices inserting the nodes of b left over and their edges.
+func (e *editor) completion(mapping []int) float64 {
+	used := make(map[int]bool, len(mapping))
+	for _, v := range mapping {
+		used[v] = true
+	}
+
+	cost := 0.0
+	for v := 0; v < e.b.Order(); v++ {
+		if !used[v] {
+			cost += e.costs.NodeInsertion(v)
+		}
+		for _, w := range distinct(e.b.Successors(v)) {
+			if !used[v] || !used[w] {
+				for _, edge := range e.b.Edges(v, w) {
+					cost += e.costs.EdgeInsertion(edge)
+				}
+			}
+		}
+	}
+	return cost
+}
+
+// estimate bounds the cost of mapping the remaining nodes from below, by an
+// optimal assignment of the nodes alone and by the edges which can not be
+// substituted.
+func (e *editor) estimate(mapping []int) float64 {
+	used := make(map[int]bool, len(mapping))
+	for _, v := range mapping {
+		used[v] = true
+	}
+	rest := e.order[len(mapping):]
+	free := make([]int, 0, e.b.Order())
+	for v := 0; v < e.b.Order(); v++ {
+		if !used[v] {
+			free = append(free, v)
+		}
+	}
+
+	_, cost := assign(len(rest), len(free),
+		func(i, j int) float64 { return e.costs.NodeSubstitution(rest[i], free[j]) },
+		func(i int) float64 { return e.costs.NodeDeletion(rest[i]) },
+		func(j int) float64 { return e.costs.NodeInsertion(free[j]) },
+	)
+
+	// Edges to remaining nodes of a can only be mapped to edges to free
+	// nodes of b
+	remaining := make(map[int]bool, len(rest))
+	for _, u := range rest {
+		remaining[u] = true
+	}
+	aEdges, bEdges := 0, 0
+	for u := 0; u < e.a.Order(); u++ {
+		for _, w := range distinct(e.a.Successors(u)) {
+			if remaining[u] || remaining[w] {
+				aEdges += len(e.a.Edges(u, w))
+			}
+		}
+	}
+	for v := 0; v < e.b.Order(); v++ {
+		for _, w := range distinct(e.b.Successors(v)) {
+			if !used[v] || !used[w] {
+				bEdges += len(e.b.Edges(v, w))
+			}
+		}
+	}
+	switch {
+	case aEdges > bEdges:
+		cost += float64(aEdges-bEdges) * e.minEdgeDeletion
+	case bEdges > aEdges:
+		cost += float64(bEdges-aEdges) * e.minEdgeInsertion
+	}
+
+	return cost
+}
+
+// editPath spells out the edits of a complete mapping.
+func (e *editor) editPath(mapping []int) EditPath {
+	path := EditPath{Nodes: make(map[int]int, len(mapping)), Edges: make(map[int]int)}
+	for i, v := range mapping {
+		path.Nodes[e.order[i]] = v
+	}
+
+	for k := range mapping {
+		path.Cost += e.nodeCost(e.order[k], mapping[k])
+
+		for i := 0; i <= k; i++ {
+			pairs := [][2]int{{k, i}, {i, k}}
+			if i == k {
+				pairs = pairs[:1]
+			}
+			for _, pair := range pairs {
+				as := edgesBetween(e.a, e.order[pair[0]], e.order[pair[1]])
+				assigned, cost := e.assignEdges(as, edgesBetween(e.b, mapping[pair[0]], mapping[pair[1]]))
+				for j, edge := range as {
+					path.Edges[edge] = assigned[j]
+				}
+				path.Cost += cost
+			}
+		}
+	}
+	path.Cost += e.completion(mapping)
+
+	return path
+}
+
+// assign solves the assignment of n items to m items, each either
+// substituted, deleted or inserted, by the Hungarian method. It returns the
+// item each of the n items is assigned to, NullNode for deleted ones.
+func assign(n, m int, substitution func(i, j int) float64, deletion func(i int) float64, insertion func(j int) float64) ([]int, float64) {
+	size := n + m
+	if size == 0 {
+		return []int{}, 0
+	}
+
+	// Substitutions on the upper left, deletions and insertions on the
+	// diagonals next to them
+	cost := make([][]float64, size)
+	forbidden := 1.0
+	for i := range cost {
+		cost[i] = make([]float64, size)
+		for j := range cost[i] {
+			switch {
+			case i < n && j < m:
+				cost[i][j] = substitution(i, j)
+			case i < n && j-m == i:
+				cost[i][j] = deletion(i)
+			case i >= n && j < m && i-n == j:
+				cost[i][j] = insertion(j)
+			case i < n || j < m:
+				cost[i][j] = math.Inf(1)
+				continue
+			}
+			forbidden += cost[i][j]
+		}
+	}
+	for i := range cost {
+		for j := range cost[i] {
+			if math.IsInf(cost[i][j], 1) {
+				cost[i][j] = forbidden
+			}
+		}
+	}
+
+	// potentials of rows and columns, the row assigned to every column
+	u, v := make([]float64, size+1), make([]float64, size+1)
+	p, way := make([]int, size+1), make([]int, size+1)
+	for i := 1; i <= size; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, size+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		used := make([]bool, size+1)
+
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= size; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost[i0-1][j-1] - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= size; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	assigned := make([]int, n)
+	total := 0.0
+	for j := 1; j <= size; j++ {
+		i := p[j] - 1
+		total += cost[i][j-1]
+		if i < n {
+			assigned[i] = NullNode
+			if j-1 < m {
+				assigned[i] = j - 1
+			}
+		}
+	}
+	return assigned, total
+}
+
+type editSearchState struct {
+	// nodes of b mapped to by the nodes of a in the order of the search
+	mapping  []int
+	cost     float64
+	priority float64
+	complete bool
+}
+
+// editQueue is a priority queue of search states, lowest priority first and
+// the most complete of equal ones.
+type editQueue []editSearchState
+
+func (q editQueue) Len() int { return len(q) }
+func (q editQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return len(q[i].mapping) > len(q[j].mapping) || q[i].complete && !q[j].complete
+}
+func (q editQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *editQueue) Push(x interface{}) { *q = append(*q, x.(editSearchState)) }
+func (q *editQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,234 @@
+package sgi
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/db/mem"
+)
+
+// bruteForceEditDistance prices every way to map, delete or insert the nodes
+// of simple graphs, adding up the edits of every node and relation.
+func bruteForceEditDistance(a, b *dbGraphMock, costs EditCosts) float64 {
+	substitute, insert, remove := costs.NodeSubstitution, costs.NodeInsertion, costs.NodeDeletion
+	if substitute == nil {
+		substitute = func(u, v int) float64 { return 0 }
+	}
+	if insert == nil {
+		insert = func(v int) float64 { return 1 }
+	}
+	if remove == nil {
+		remove = func(u int) float64 { return 1 }
+	}
+	substituteEdge, insertEdge, removeEdge := costs.EdgeSubstitution, costs.EdgeInsertion, costs.EdgeDeletion
+	if substituteEdge == nil {
+		substituteEdge = func(e, f int) float64 { return 0 }
+	}
+	if insertEdge == nil {
+		insertEdge = func(f int) float64 { return 1 }
+	}
+	if removeEdge == nil {
+		removeEdge = func(e int) float64 { return 1 }
+	}
+
+	price := func(mapping []int) float64 {
+		cost := 0.0
+		mapped := make(map[int]bool)
+		for u, v := range mapping {
+			if v == NullNode {
+				cost += remove(u)
+				continue
+			}
+			cost += substitute(u, v)
+			mapped[v] = true
+		}
+		for v := 0; v < b.Order(); v++ {
+			if !mapped[v] {
+				cost += insert(v)
+			}
+		}
+
+		// relations between mapped nodes are substituted unless replacing
+		// them costs less
+		kept := make(map[int]bool)
+		for u := range a.edges {
+			for w, ok := range a.edges[u] {
+				if !ok {
+					continue
+				}
+				e := u*a.Order() + w
+				v, x := mapping[u], mapping[w]
+				if v == NullNode || x == NullNode || !b.edges[v][x] {
+					cost += removeEdge(e)
+					continue
+				}
+				f := v*b.Order() + x
+				cost += math.Min(substituteEdge(e, f), removeEdge(e)+insertEdge(f))
+				kept[f] = true
+			}
+		}
+		for v := range b.edges {
+			for x, ok := range b.edges[v] {
+				if f := v*b.Order() + x; ok && !kept[f] {
+					cost += insertEdge(f)
+				}
+			}
+		}
+		return cost
+	}
+
+	best := math.Inf(1)
+	mapping := make([]int, 0, a.Order())
+	used := make(map[int]bool)
+	var choose func()
+	choose = func() {
+		if len(mapping) == a.Order() {
+			best = math.Min(best, price(mapping))
+			return
+		}
+		for v := NullNode; v < b.Order(); v++ {
+			if v != NullNode && used[v] {
+				continue
+			}
+			mapping, used[v] = append(mapping, v), v != NullNode
+			choose()
+			mapping, used[v] = mapping[:len(mapping)-1], false
+		}
+	}
+	choose()
+
+	return best
+}
+
+func valueCosts(a, b *dbGraphMock) EditCosts {
+	return EditCosts{NodeSubstitution: func(u, v int) float64 {
+		if a.nodes[u] != b.nodes[v] {
+			return 0.5
+		}
+		return 0
+	}}
+}
+
+func TestEditDistanceAgreesWithBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		a, b := randomMock(r, r.Intn(5), 0.3), randomMock(r, r.Intn(5), 0.3)
+		costs := valueCosts(a, b)
+
+		expected := bruteForceEditDistance(a, b, costs)
+		path := EditDistance(a, b, costs)
+		if math.Abs(path.Cost-expected) > 1e-9 {
+			t.Fatalf("Expected the distance of %v and %v to be %g, got %g", a.edges, b.edges, expected, path.Cost)
+		}
+		if len(path.Nodes) != a.Order() {
+			t.Fatal("Expected every node to be edited, got ", path.Nodes)
+		}
+
+		approximate := ApproximateEditDistance(a, b, costs)
+		if approximate.Cost < path.Cost-1e-9 {
+			t.Fatalf("Expected the approximation %g to bound %g from above", approximate.Cost, path.Cost)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	triangle := newMock(3, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0})
+	path := newMock(3, [2]int{0, 1}, [2]int{1, 2})
+
+	d := EditDistance(triangle, path, EditCosts{})
+	if d.Cost != 1 {
+		t.Error("Expected to delete a single relation, got ", d)
+	}
+	deleted := 0
+	for e, f := range d.Edges {
+		if f == NullNode {
+			deleted += 1
+			continue
+		}
+		if u, v := e/3, e%3; path.Edges(d.Nodes[u], d.Nodes[v])[0] != f {
+			t.Error("Expected relations between the mapped nodes, got ", d)
+		}
+	}
+	if deleted != 1 {
+		t.Error("Expected a deleted relation, got ", d.Edges)
+	}
+
+	for name, distance := range map[string]func(a, b Graph, costs EditCosts) EditPath{
+		"exact":       EditDistance,
+		"approximate": ApproximateEditDistance,
+	} {
+		if d := distance(triangle, triangle, EditCosts{}); d.Cost != 0 {
+			t.Errorf("%s: expected no edits between equal graphs, got %v", name, d)
+		}
+		if d := distance(newMock(0), path, EditCosts{}); d.Cost != 5 {
+			t.Errorf("%s: expected to insert all nodes and relations, got %v", name, d)
+		}
+		if d := distance(path, newMock(0), EditCosts{EdgeDeletion: func(e int) float64 { return 2 }}); d.Cost != 7 {
+			t.Errorf("%s: expected to delete all nodes and relations, got %v", name, d)
+		}
+	}
+}
+
+func TestEditDistanceSymmetric(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 50; i++ {
+		a, b := randomMock(r, r.Intn(6), 0.3), randomMock(r, r.Intn(6), 0.3)
+
+		if ab, ba := EditDistance(a, b, valueCosts(a, b)), EditDistance(b, a, valueCosts(b, a)); ab.Cost != ba.Cost {
+			t.Fatalf("Expected the distance of %v and %v to be symmetric, got %g and %g", a.edges, b.edges, ab.Cost, ba.Cost)
+		}
+	}
+}
+
+func TestEditDistanceParallelEdges(t *testing.T) {
+	a := &multiGraphMock{2, []typedEdge{{0, 1, "KNOWS"}, {0, 1, "LIKES"}}}
+	b := &multiGraphMock{2, []typedEdge{{0, 1, "LIKES"}, {0, 1, "HATES"}, {0, 1, "KNOWS"}}}
+
+	costs := EditCosts{EdgeSubstitution: func(e, f int) float64 {
+		if a.edges[e].typ != b.edges[f].typ {
+			return 1.5
+		}
+		return 0
+	}}
+	d := EditDistance(a, b, costs)
+	if d.Cost != 1 || d.Edges[0] != 2 || d.Edges[1] != 0 {
+		t.Error("Expected the relations substituted by their types, got ", d)
+	}
+}
+
+func TestSubgraphEditCosts(t *testing.T) {
+	graph, _ := mem.NewDb("versions", nil)
+
+	// Two versions of a subgraph, the second one renames and relates anew
+	a1, b1 := graph.NewNode("Person"), graph.NewNode("Person")
+	a1.SetProperty("name", "alice")
+	b1.SetProperty("name", "bob")
+	a1.RelateTo(b1, "KNOWS")
+
+	b2, a2 := graph.NewNode("Person"), graph.NewNode("Person")
+	a2.SetProperty("name", "alice")
+	b2.SetProperty("name", "robert")
+	a2.RelateTo(b2, "LIKES")
+
+	first, second := NewSubgraph([]Node{a1, b1}), NewSubgraph([]Node{b2, a2})
+
+	if d := EditDistance(first, first, first.EditCosts(first)); d.Cost != 0 {
+		t.Error("Expected no edits of the same version, got ", d)
+	}
+
+	d := EditDistance(first, second, first.EditCosts(second))
+	if second.Node(d.Nodes[0]) != a2 || second.Node(d.Nodes[1]) != b2 {
+		t.Error("Expected the nodes mapped onto their versions, got ", d.Nodes)
+	}
+	// half of the features of bob changed, all of the relation
+	if d.Cost != 1.5 {
+		t.Error("Expected the renaming and the new type to count, got ", d.Cost)
+	}
+	if d := ApproximateEditDistance(first, second, first.EditCosts(second)); d.Cost != 1.5 {
+		t.Error("Expected the approximation to find the versions as well, got ", d)
+	}
+}
@@ -0,0 +1,341 @@
+package sgi
+
+import (
+	"io"
+	stdlog "log"
+	"slices"
+	"testing"
+
+	"github.com/BuJo/goneo/data"
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/db/mem"
+	"github.com/BuJo/goneo/log"
+)
+
+// matchers all have to find the same mappings.
+var matchers = map[string]func(query, target Graph, fsem SemFeasFunc) State{
+	"VF2++":   NewVF2PPState,
+	"Ullmann": NewUllmannState,
+	"RI":      NewRIState,
+	"VF2":     NewVF2State,
+}
+
+// knownBugs lists the queries a matcher still gets wrong, they are skipped
+// until the matcher is fixed. VF2 follows relations regardless of their
+// direction and never reaches nodes disconnected from the first one matched,
+// see the BUG note in vf2.go.
+var knownBugs = map[string][]string{
+	"VF2": {"edge", "mutual", "path", "triangle", "star", "disjoint", "loop", "bowtie"},
+}
+
+func knownBug(matcher, query string) bool {
+	return slices.Contains(knownBugs[matcher], query)
+}
+
+// bruteForce finds all mappings of a mode by trying every assignment of query
+// to target nodes.
+func bruteForce(query, target Graph, fsem SemFeasFunc, mode Mode) []map[int]int {
+	mappings := make([]map[int]int, 0)
+	mapping := make(map[int]int)
+	used := make(map[int]bool)
+
+	if mode == GraphIsomorphism && query.Order() != target.Order() {
+		return mappings
+	}
+
+	var assign func(q int)
+	assign = func(q int) {
+		if q == query.Order() {
+			found := make(map[int]int)
+			for k, v := range mapping {
+				found[k] = v
+			}
+			mappings = append(mappings, found)
+			return
+		}
+		for t := 0; t < target.Order(); t++ {
+			if used[t] && mode != Homomorphism {
+				continue
+			}
+			mapping[q] = t
+			feasable := fsem == nil || fsem(nil, NullNode, NullNode, q, t)
+			for p := 0; p <= q; p++ {
+				if query.Contains(p, q) && !target.Contains(mapping[p], t) || query.Contains(q, p) && !target.Contains(t, mapping[p]) {
+					feasable = false
+				}
+				induced := mode == InducedSubgraphIsomorphism || mode == GraphIsomorphism
+				if induced && (target.Contains(mapping[p], t) && !query.Contains(p, q) || target.Contains(t, mapping[p]) && !query.Contains(q, p)) {
+					feasable = false
+				}
+				if feasable && fsem != nil && p < q && (query.Contains(p, q) || query.Contains(q, p)) {
+					feasable = fsem(nil, p, mapping[p], q, t)
+				}
+			}
+			if feasable {
+				wasUsed := used[t]
+				used[t] = true
+				assign(q + 1)
+				used[t] = wasUsed
+			}
+			delete(mapping, q)
+		}
+	}
+	assign(0)
+
+	return mappings
+}
+
+func newMock(order int, edges ...[2]int) *dbGraphMock {
+	g := &dbGraphMock{nodes: make([]int, order), edges: make([][]bool, order)}
+	for i := range g.edges {
+		g.edges[i] = make([]bool, order)
+	}
+	for _, e := range edges {
+		g.edges[e[0]][e[1]] = true
+	}
+	return g
+}
+
+func sameMappings(a, b []map[int]int) bool {
+	keys := make(map[string]int)
+	for _, m := range a {
+		keys[mappingKey(m)] += 1
+	}
+	for _, m := range b {
+		keys[mappingKey(m)] -= 1
+	}
+	for _, count := range keys {
+		if count != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// two triangles sharing a node, one with a tail ending in a loop
+var bowtie = newMock(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 2}, [2]int{4, 5}, [2]int{5, 5})
+
+var queries = map[string]*dbGraphMock{
+	"edge":       newMock(2, [2]int{0, 1}),
+	"mutual":     newMock(2, [2]int{0, 1}, [2]int{1, 0}),
+	"path":       newMock(3, [2]int{0, 1}, [2]int{1, 2}),
+	"triangle":   newMock(3, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}),
+	"star":       newMock(3, [2]int{0, 1}, [2]int{0, 2}),
+	"disjoint":   newMock(3, [2]int{0, 1}),
+	"loop":       newMock(2, [2]int{0, 1}, [2]int{1, 1}),
+	"isolated":   newMock(1),
+	"empty":      newMock(0),
+	"bowtie":     newMock(5, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 2}),
+	"impossible": newMock(7),
+}
+
+func TestMatchersAgree(t *testing.T) {
+	for name, query := range queries {
+		expected := bruteForce(query, bowtie, nil, Monomorphism)
+		for matcher, newState := range matchers {
+			if knownBug(matcher, name) {
+				t.Logf("%s %s: skipped, known bug", matcher, name)
+				continue
+			}
+			if mappings := FindIsomorphism(newState(query, bowtie, nil)); !sameMappings(mappings, expected) {
+				t.Errorf("%s %s: expected %d mappings %v, got %d %v", matcher, name, len(expected), expected, len(mappings), mappings)
+			}
+		}
+	}
+}
+
+func TestMatchersAgreeSemantically(t *testing.T) {
+	target := newMock(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 2}, [2]int{4, 5}, [2]int{5, 5})
+	target.nodes = []int{1, 2, 1, 2, 1, 2}
+
+	semantics := map[string]func(query *dbGraphMock) SemFeasFunc{
+		// nodes have to carry the same values
+		"nodes": func(query *dbGraphMock) SemFeasFunc {
+			return func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+				return query.nodes[toQueryNode] == 0 || query.nodes[toQueryNode] == target.nodes[toTargetNode]
+			}
+		},
+		// related nodes have to carry different values
+		"relations": func(query *dbGraphMock) SemFeasFunc {
+			return func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+				return fromQueryNode == NullNode || target.nodes[fromTargetNode] != target.nodes[toTargetNode]
+			}
+		},
+	}
+
+	for name, query := range queries {
+		if query.Order() > 0 {
+			query.nodes[0] = 2
+		}
+		for semantic, fsem := range semantics {
+			expected := bruteForce(query, target, fsem(query), Monomorphism)
+			for matcher, newState := range matchers {
+				if knownBug(matcher, name) {
+					t.Logf("%s %s by %s: skipped, known bug", matcher, name, semantic)
+					continue
+				}
+				if mappings := FindIsomorphism(newState(query, target, fsem(query))); !sameMappings(mappings, expected) {
+					t.Errorf("%s %s by %s: expected %d mappings %v, got %d %v", matcher, name, semantic, len(expected), expected, len(mappings), mappings)
+				}
+			}
+		}
+		if query.Order() > 0 {
+			query.nodes[0] = 0
+		}
+	}
+}
+
+func TestMatchersStateInterface(t *testing.T) {
+	query := queries["path"]
+	for matcher, newState := range matchers {
+		state := newState(query, bowtie, nil)
+		if state.GetSubgraph() != query || state.GetGraph() != bowtie {
+			t.Errorf("%s: expected the graphs of the state", matcher)
+		}
+		if state.IsGoal() || state.IsDead() {
+			t.Errorf("%s: expected the initial state to be searched", matcher)
+		}
+	}
+}
+
+func TestMatchersStream(t *testing.T) {
+	query := queries["path"]
+	for matcher, newState := range matchers {
+		all := FindIsomorphism(newState(query, bowtie, nil))
+
+		streamed := make([]map[int]int, 0)
+		for mapping := range Isomorphisms(newState(query, bowtie, nil)) {
+			streamed = append(streamed, mapping)
+		}
+		if !sameMappings(streamed, all) {
+			t.Errorf("%s: expected to stream %v, got %v", matcher, all, streamed)
+		}
+
+		first := make([]map[int]int, 0)
+		for mapping := range Isomorphisms(newState(query, bowtie, nil)) {
+			if first = append(first, mapping); len(first) == 2 {
+				break
+			}
+		}
+		if !sameMappings(first, all[:2]) {
+			t.Errorf("%s: expected to stop after %v, got %v", matcher, all[:2], first)
+		}
+	}
+}
+
+// opaqueState hides that the matchers reach each mapping once.
+type opaqueState struct {
+	State
+}
+
+func (state opaqueState) NextState(queryNode, targetNode int) State {
+	return opaqueState{state.State.NextState(queryNode, targetNode)}
+}
+
+func TestStreamDistinct(t *testing.T) {
+	query := queries["triangle"]
+	for matcher, newState := range matchers {
+		expected := FindIsomorphism(newState(query, bowtie, nil))
+		if mappings := FindIsomorphism(opaqueState{newState(query, bowtie, nil)}); !sameMappings(mappings, expected) {
+			t.Errorf("%s: expected other states to find %v, got %v", matcher, expected, mappings)
+		}
+	}
+}
+
+// benchGraph adapts a database for the benchmarks.
+type benchGraph struct {
+	db DatabaseService
+}
+
+func (g *benchGraph) Order() int { return len(g.db.GetAllNodes()) }
+func (g *benchGraph) Contains(a, b int) bool {
+	for _, n := range g.Successors(a) {
+		if n == b {
+			return true
+		}
+	}
+	return false
+}
+func (g *benchGraph) Successors(a int) []int   { return g.related(a, Outgoing) }
+func (g *benchGraph) Predecessors(a int) []int { return g.related(a, Incoming) }
+func (g *benchGraph) Relations(a int) []int    { return g.related(a, Both) }
+func (g *benchGraph) Edges(a, b int) []int {
+	node, _ := g.db.GetNode(a)
+	ids := make([]int, 0)
+	for _, rel := range node.Relations(Outgoing) {
+		if rel.End().Id() == b {
+			ids = append(ids, rel.Id())
+		}
+	}
+	return ids
+}
+func (g *benchGraph) related(a int, dir Direction) []int {
+	node, _ := g.db.GetNode(a)
+	ids := make([]int, 0)
+	for _, rel := range node.Relations(dir) {
+		if rel.Start().Id() == a {
+			ids = append(ids, rel.End().Id())
+		} else {
+			ids = append(ids, rel.Start().Id())
+		}
+	}
+	return ids
+}
+
+// labelled passes nodes carrying the labels of the query nodes.
+func labelled(query, target DatabaseService) SemFeasFunc {
+	return func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		q, _ := query.GetNode(toQueryNode)
+		t, _ := target.GetNode(toTargetNode)
+		return t.HasLabel(q.Labels()...)
+	}
+}
+
+func benchmarkUniverse(b *testing.B, newState func(query, target Graph, fsem SemFeasFunc) State) {
+	log.SetDefault(stdlog.New(io.Discard, "", 0))
+
+	db, _ := mem.NewDb("universe", nil)
+	data.NewUniverseGenerator(db).Generate()
+
+	// an actor playing a character in an episode
+	pattern, _ := mem.NewDb("pattern", nil)
+	actor, character, episode := pattern.NewNode("Actor"), pattern.NewNode("Character"), pattern.NewNode("Episode")
+	actor.RelateTo(character, "PLAYED")
+	character.RelateTo(episode, "APPEARED")
+
+	query, target, fsem := &benchGraph{pattern}, &benchGraph{db}, labelled(pattern, db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindIsomorphism(newState(query, target, fsem))
+	}
+}
+
+func benchmarkRandom(b *testing.B, newState func(query, target Graph, fsem SemFeasFunc) State) {
+	log.SetDefault(stdlog.New(io.Discard, "", 0))
+
+	db, _ := mem.NewDb("random", nil)
+	data.NewLargeRandomGenerator(db).Generate()
+
+	// two nodes attached to a third
+	pattern, _ := mem.NewDb("pattern", nil)
+	a, b1, b2 := pattern.NewNode(), pattern.NewNode(), pattern.NewNode()
+	b1.RelateTo(a, "HAS")
+	b2.RelateTo(a, "HAS")
+
+	query, target := &benchGraph{pattern}, &benchGraph{db}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindIsomorphism(newState(query, target, nil))
+	}
+}
+
+func BenchmarkVF2Universe(b *testing.B)     { benchmarkUniverse(b, NewVF2State) }
+func BenchmarkVF2PPUniverse(b *testing.B)   { benchmarkUniverse(b, NewVF2PPState) }
+func BenchmarkUllmannUniverse(b *testing.B) { benchmarkUniverse(b, NewUllmannState) }
+func BenchmarkRIUniverse(b *testing.B)      { benchmarkUniverse(b, NewRIState) }
+func BenchmarkVF2Random(b *testing.B)       { benchmarkRandom(b, NewVF2State) }
+func BenchmarkVF2PPRandom(b *testing.B)     { benchmarkRandom(b, NewVF2PPState) }
+func BenchmarkUllmannRandom(b *testing.B)   { benchmarkRandom(b, NewUllmannState) }
+func BenchmarkRIRandom(b *testing.B)        { benchmarkRandom(b, NewRIState) }
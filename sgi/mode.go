@@ -0,0 +1,120 @@
+package sgi
+
+import "iter"
+
+// Mode selects which mappings of a query into a target are searched.
+type Mode int
+
+const (
+	// Monomorphism maps query nodes to distinct target nodes, keeping the
+	// relations of the query. The target may relate the nodes further. This
+	// is what the matchers find by default.
+	Monomorphism Mode = iota
+	// InducedSubgraphIsomorphism is a monomorphism where target nodes are
+	// only related as their query nodes are.
+	InducedSubgraphIsomorphism
+	// GraphIsomorphism is an induced subgraph isomorphism covering all target
+	// nodes, the query and target are the same graph.
+	GraphIsomorphism
+	// Homomorphism keeps the relations of the query but may map several
+	// query nodes to the same target node.
+	Homomorphism
+)
+
+func (mode Mode) String() string {
+	switch mode {
+	case Monomorphism:
+		return "monomorphism"
+	case InducedSubgraphIsomorphism:
+		return "induced subgraph isomorphism"
+	case GraphIsomorphism:
+		return "graph isomorphism"
+	case Homomorphism:
+		return "homomorphism"
+	}
+	return "unknown mode"
+}
+
+// modeState restricts the monomorphisms found by a state to the induced ones.
+type modeState struct {
+	State
+
+	dead bool
+}
+
+// WithMode restricts a state of one of the matchers to the mappings of an
+// injective mode. Homomorphisms need their own state, see
+// NewHomomorphismState.
+func WithMode(state State, mode Mode) State {
+	switch mode {
+	case InducedSubgraphIsomorphism:
+		return &modeState{State: state}
+	case GraphIsomorphism:
+		return &modeState{State: state, dead: state.GetSubgraph().Order() != state.GetGraph().Order()}
+	}
+	return state
+}
+
+func (state *modeState) uniqueMappings() bool {
+	unique, ok := state.State.(uniqueState)
+	return ok && unique.uniqueMappings()
+}
+
+func (state *modeState) IsGoal() bool { return !state.dead && state.State.IsGoal() }
+func (state *modeState) IsDead() bool { return state.dead || state.State.IsDead() }
+
+func (state *modeState) IsFeasablePair(queryNode, targetNode int) bool {
+	query, target := state.GetSubgraph(), state.GetGraph()
+
+	// Target relations have to be queried
+	if target.Contains(targetNode, targetNode) && !query.Contains(queryNode, queryNode) {
+		return false
+	}
+	for q, t := range state.GetMapping() {
+		if target.Contains(t, targetNode) && !query.Contains(q, queryNode) {
+			return false
+		}
+		if target.Contains(targetNode, t) && !query.Contains(queryNode, q) {
+			return false
+		}
+	}
+
+	return state.State.IsFeasablePair(queryNode, targetNode)
+}
+
+func (state *modeState) NextState(queryNode, targetNode int) State {
+	return &modeState{State: state.State.NextState(queryNode, targetNode)}
+}
+
+// FindMappings finds the mappings of the given mode from a query into a
+// target graph, using VF2++ for the injective modes.
+func FindMappings(query, target Graph, fsem SemFeasFunc, mode Mode) []map[int]int {
+	mappings := make([]map[int]int, 0)
+
+	FindMappingsFunc(query, target, fsem, mode, func(mapping map[int]int) bool {
+		mappings = append(mappings, mapping)
+		return true
+	})
+
+	return mappings
+}
+
+// FindMappingsFunc works like FindMappings but hands each mapping to visit as
+// soon as it is found. Returning false from visit stops the search.
+func FindMappingsFunc(query, target Graph, fsem SemFeasFunc, mode Mode, visit func(mapping map[int]int) bool) {
+	if mode == Homomorphism {
+		match(NewHomomorphismState(query, target, fsem), visit)
+		return
+	}
+
+	// Every mapping is reached exactly once, there are no duplicates to skip
+	match(WithMode(newVF2PPState(query, target, fsem), mode), visit)
+}
+
+// Mappings produces the mappings of the given mode as they are found, the
+// search stops when the caller stops iterating.
+func Mappings(query, target Graph, fsem SemFeasFunc, mode Mode) iter.Seq[map[int]int] {
+	return func(yield func(map[int]int) bool) {
+		FindMappingsFunc(query, target, fsem, mode, yield)
+	}
+}
@@ -0,0 +1,101 @@
+package sgi
+
+import (
+	"testing"
+)
+
+func TestModes(t *testing.T) {
+	path := newMock(3, [2]int{0, 1}, [2]int{1, 2})
+	triangle := newMock(3, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0})
+	mutual := newMock(2, [2]int{0, 1}, [2]int{1, 0})
+	// a triangle with a tail
+	tailed := newMock(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 0}, [2]int{2, 3})
+
+	tests := []struct {
+		name          string
+		query, target Graph
+		counts        map[Mode]int
+	}{
+		// Every path around the triangle is closed by a third relation
+		{"path in triangle", path, triangle, map[Mode]int{Monomorphism: 3, InducedSubgraphIsomorphism: 0, GraphIsomorphism: 0, Homomorphism: 3}},
+		// Only the tail 1->2->3 leaves the triangle
+		{"path in tailed", path, tailed, map[Mode]int{Monomorphism: 4, InducedSubgraphIsomorphism: 1, GraphIsomorphism: 0, Homomorphism: 4}},
+		{"triangle in triangle", triangle, triangle, map[Mode]int{Monomorphism: 3, InducedSubgraphIsomorphism: 3, GraphIsomorphism: 3, Homomorphism: 3}},
+		{"triangle in tailed", triangle, tailed, map[Mode]int{Monomorphism: 3, InducedSubgraphIsomorphism: 3, GraphIsomorphism: 0, Homomorphism: 3}},
+		// Paths may walk back and forth between two nodes
+		{"path in mutual", path, mutual, map[Mode]int{Monomorphism: 0, InducedSubgraphIsomorphism: 0, GraphIsomorphism: 0, Homomorphism: 2}},
+		// A triangle folds onto a loop
+		{"triangle on loop", triangle, newMock(1, [2]int{0, 0}), map[Mode]int{Monomorphism: 0, Homomorphism: 1}},
+	}
+
+	for _, test := range tests {
+		for mode, count := range test.counts {
+			mappings := FindMappings(test.query, test.target, nil, mode)
+			if len(mappings) != count {
+				t.Errorf("%s: expected %d mappings by %s, got %v", test.name, count, mode, mappings)
+			}
+			if expected := bruteForce(test.query, test.target, nil, mode); !sameMappings(mappings, expected) {
+				t.Errorf("%s: expected the mappings by %s to be %v, got %v", test.name, mode, expected, mappings)
+			}
+		}
+	}
+}
+
+func TestMatchersAgreeOnModes(t *testing.T) {
+	for _, mode := range []Mode{InducedSubgraphIsomorphism, GraphIsomorphism} {
+		for name, query := range queries {
+			expected := bruteForce(query, bowtie, nil, mode)
+			for matcher, newState := range matchers {
+				if knownBug(matcher, name) {
+					t.Logf("%s %s by %s: skipped, known bug", matcher, name, mode)
+					continue
+				}
+				if mappings := FindIsomorphism(WithMode(newState(query, bowtie, nil), mode)); !sameMappings(mappings, expected) {
+					t.Errorf("%s %s by %s: expected %v, got %v", matcher, name, mode, expected, mappings)
+				}
+			}
+		}
+	}
+
+	for name, query := range queries {
+		if name == "impossible" {
+			// seven isolated nodes fold onto the target in too many ways
+			continue
+		}
+		expected := bruteForce(query, bowtie, nil, Homomorphism)
+		if mappings := FindMappings(query, bowtie, nil, Homomorphism); !sameMappings(mappings, expected) {
+			t.Errorf("%s by %s: expected %d mappings, got %d", name, Homomorphism, len(expected), len(mappings))
+		}
+	}
+}
+
+func TestHomomorphismSemantics(t *testing.T) {
+	path := newMock(3, [2]int{0, 1}, [2]int{1, 2})
+	mutual := newMock(2, [2]int{0, 1}, [2]int{1, 0})
+
+	// the ends of the path have to be different nodes
+	mappings := FindMappings(path, mutual, func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		return toQueryNode != 2 || state.GetMapping()[0] != toTargetNode
+	}, Homomorphism)
+
+	if len(mappings) != 0 {
+		t.Error("Expected Fsem to limit the mappings, got ", mappings)
+	}
+}
+
+func TestMappingsStream(t *testing.T) {
+	all := FindMappings(queries["path"], bowtie, nil, InducedSubgraphIsomorphism)
+	if len(all) < 2 {
+		t.Fatal("Expected several mappings, got ", all)
+	}
+
+	visited := 0
+	for range Mappings(queries["path"], bowtie, nil, InducedSubgraphIsomorphism) {
+		if visited += 1; visited == 2 {
+			break
+		}
+	}
+	if visited != 2 {
+		t.Error("Expected to stop after two mappings, visited ", visited)
+	}
+}
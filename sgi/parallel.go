@@ -0,0 +1,189 @@
+package sgi
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions configures the parallel search, zero values select the
+// defaults.
+type ParallelOptions struct {
+	// Workers is the number of concurrent searches, GOMAXPROCS by default.
+	Workers int
+	// Ordered hands out the mappings in the order of the sequential search.
+	// Workers finding mappings ahead of the ones handed out wait for them.
+	Ordered bool
+}
+
+// orderedBuffer bounds the mappings a worker finds ahead of the ones handed
+// out in an ordered search.
+const orderedBuffer = 256
+
+// rootTask is the search below one pair of the first query node.
+type rootTask struct {
+	queryNode, targetNode int
+
+	out chan<- map[int]int
+}
+
+// cancelState ends the search of a state once the context is done.
+type cancelState struct {
+	State
+
+	ctx context.Context
+}
+
+func (state cancelState) IsDead() bool { return state.ctx.Err() != nil || state.State.IsDead() }
+
+func (state cancelState) NextState(queryNode, targetNode int) State {
+	return cancelState{state.State.NextState(queryNode, targetNode), state.ctx}
+}
+
+// FindIsomorphismParallel finds the mappings like FindIsomorphism, splitting
+// the search across workers as FindIsomorphismParallelFunc does.
+func FindIsomorphismParallel(ctx context.Context, newState func() State, opts ParallelOptions) ([]map[int]int, error) {
+	mappings := make([]map[int]int, 0)
+
+	err := FindIsomorphismParallelFunc(ctx, newState, opts, func(mapping map[int]int) bool {
+		mappings = append(mappings, mapping)
+		return true
+	})
+
+	return mappings, err
+}
+
+// FindIsomorphismParallelFunc finds the mappings like FindIsomorphismFunc but
+// splits the search by the pairs of the first query node across a bounded
+// pool of workers. Every worker searches with a state of its own created by
+// newState, which has to create the same initial state on every call. The
+// graphs and SemFeasFunc of the states have to be safe for concurrent use,
+// visit is only called from the calling goroutine.
+// The search stops when visit returns false or the context is done, the
+// error of the context is returned then.
+func FindIsomorphismParallelFunc(ctx context.Context, newState func() State, opts ParallelOptions, visit func(mapping map[int]int) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	root := newState()
+	if root.IsGoal() || root.IsDead() {
+		FindIsomorphismFunc(root, visit)
+		return ctx.Err()
+	}
+
+	pairs := make([][2]int, 0)
+	for q, t := root.NextPair(); q != NullNode; q, t = root.NextPair() {
+		if root.IsFeasablePair(q, t) {
+			pairs = append(pairs, [2]int{q, t})
+		}
+	}
+
+	var seen map[string]bool
+	if unique, ok := root.(uniqueState); !ok || !unique.uniqueMappings() {
+		seen = make(map[string]bool)
+	}
+
+	search, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// outs hands the channels of the tasks to the caller in the order of the
+	// pairs, unordered searches share one channel
+	tasks := make(chan rootTask)
+	outs := make(chan chan map[int]int, opts.Workers)
+	var shared chan map[int]int
+	if !opts.Ordered {
+		shared = make(chan map[int]int, opts.Workers)
+		outs <- shared
+	}
+
+	go func() {
+		defer close(tasks)
+		defer close(outs)
+
+		for _, pair := range pairs {
+			out := shared
+			if opts.Ordered {
+				out = make(chan map[int]int, orderedBuffer)
+			}
+
+			select {
+			case tasks <- rootTask{pair[0], pair[1], out}:
+			case <-search.Done():
+				return
+			}
+
+			if opts.Ordered {
+				select {
+				case outs <- out:
+				case <-search.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers && w < len(pairs); w++ {
+		state := root
+		if w > 0 {
+			state = newState()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for task := range tasks {
+				next := state.NextState(task.queryNode, task.targetNode)
+				match(cancelState{next, search}, func(mapping map[int]int) bool {
+					select {
+					case task.out <- mapping:
+						return true
+					case <-search.Done():
+						return false
+					}
+				})
+				next.BackTrack()
+
+				if opts.Ordered {
+					close(task.out)
+				}
+			}
+		}()
+	}
+	if shared != nil {
+		go func() {
+			wg.Wait()
+			close(shared)
+		}()
+	}
+
+consume:
+	for out := range outs {
+		for mapping := range out {
+			if search.Err() != nil {
+				break consume
+			}
+			if seen != nil {
+				key := mappingKey(mapping)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+
+			if !visit(mapping) {
+				break consume
+			}
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	return ctx.Err()
+}
@@ -0,0 +1,140 @@
+package sgi
+
+import (
+	"context"
+	"errors"
+	"io"
+	stdlog "log"
+	"reflect"
+	"testing"
+
+	"github.com/BuJo/goneo/data"
+	"github.com/BuJo/goneo/db/mem"
+	"github.com/BuJo/goneo/log"
+)
+
+func TestParallelAgrees(t *testing.T) {
+	for name, query := range queries {
+		for matcher, newState := range matchers {
+			states := func() State { return newState(query, bowtie, nil) }
+			expected := FindIsomorphism(states())
+
+			mappings, err := FindIsomorphismParallel(context.Background(), states, ParallelOptions{Workers: 3, Ordered: true})
+			if err != nil || !reflect.DeepEqual(mappings, expected) {
+				t.Errorf("%s %s: expected %v in order, got %v %v", matcher, name, expected, mappings, err)
+			}
+
+			mappings, err = FindIsomorphismParallel(context.Background(), states, ParallelOptions{})
+			if err != nil || !sameMappings(mappings, expected) {
+				t.Errorf("%s %s: expected %v, got %v %v", matcher, name, expected, mappings, err)
+			}
+		}
+	}
+}
+
+func TestParallelModes(t *testing.T) {
+	query := queries["path"]
+	for _, ordered := range []bool{false, true} {
+		expected := FindMappings(query, bowtie, nil, InducedSubgraphIsomorphism)
+		mappings, _ := FindIsomorphismParallel(context.Background(), func() State {
+			return WithMode(NewVF2PPState(query, bowtie, nil), InducedSubgraphIsomorphism)
+		}, ParallelOptions{Workers: 2, Ordered: ordered})
+		if !sameMappings(mappings, expected) {
+			t.Errorf("ordered %v: expected %v, got %v", ordered, expected, mappings)
+		}
+
+		// States which may reach mappings twice are deduplicated
+		mappings, _ = FindIsomorphismParallel(context.Background(), func() State {
+			return opaqueState{NewRIState(query, bowtie, nil)}
+		}, ParallelOptions{Workers: 2, Ordered: ordered})
+		if expected = FindMappings(query, bowtie, nil, Monomorphism); !sameMappings(mappings, expected) {
+			t.Errorf("ordered %v: expected %v, got %v", ordered, expected, mappings)
+		}
+	}
+}
+
+func TestParallelStopping(t *testing.T) {
+	states := func() State { return NewVF2PPState(queries["edge"], bowtie, nil) }
+
+	for _, ordered := range []bool{false, true} {
+		visited := 0
+		err := FindIsomorphismParallelFunc(context.Background(), states, ParallelOptions{Workers: 4, Ordered: ordered}, func(mapping map[int]int) bool {
+			visited += 1
+			return visited < 2
+		})
+		if err != nil || visited != 2 {
+			t.Errorf("ordered %v: search should stop after two mappings, visited %d: %v", ordered, visited, err)
+		}
+	}
+}
+
+func TestParallelCancel(t *testing.T) {
+	// four isolated nodes are placed in hundreds of ways
+	query := newMock(4)
+	states := func() State { return NewVF2PPState(query, bowtie, nil) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	visited := 0
+	err := FindIsomorphismParallelFunc(ctx, states, ParallelOptions{Workers: 4}, func(mapping map[int]int) bool {
+		if visited += 1; visited == 3 {
+			cancel()
+		}
+		return true
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected the search to be cancelled, got ", err)
+	}
+	if visited != 3 {
+		t.Error("Expected the search to stop with the cancellation, visited ", visited)
+	}
+
+	mappings, err := FindIsomorphismParallel(ctx, states, ParallelOptions{})
+	if !errors.Is(err, context.Canceled) || len(mappings) != 0 {
+		t.Error("Expected a cancelled search to find nothing, got ", mappings, err)
+	}
+}
+
+func benchmarkParallel(b *testing.B, matcher string, opts ParallelOptions) {
+	log.SetDefault(stdlog.New(io.Discard, "", 0))
+
+	db, _ := mem.NewDb("random", nil)
+	data.NewLargeRandomGenerator(db).Generate()
+
+	// two nodes attached to a third
+	pattern, _ := mem.NewDb("pattern", nil)
+	a, b1, b2 := pattern.NewNode(), pattern.NewNode(), pattern.NewNode()
+	b1.RelateTo(a, "HAS")
+	b2.RelateTo(a, "HAS")
+
+	query, target := &benchGraph{pattern}, &benchGraph{db}
+	states := func() State { return matchers[matcher](query, target, nil) }
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = FindIsomorphismParallel(context.Background(), states, opts)
+	}
+}
+
+func BenchmarkVF2PPRandomSequential(b *testing.B) {
+	benchmarkParallel(b, "VF2++", ParallelOptions{Workers: 1})
+}
+
+func BenchmarkVF2PPRandomParallel(b *testing.B) {
+	benchmarkParallel(b, "VF2++", ParallelOptions{})
+}
+
+func BenchmarkVF2PPRandomOrdered(b *testing.B) {
+	benchmarkParallel(b, "VF2++", ParallelOptions{Ordered: true})
+}
+
+func BenchmarkRIRandomSequential(b *testing.B) {
+	benchmarkParallel(b, "RI", ParallelOptions{Workers: 1})
+}
+
+func BenchmarkRIRandomParallel(b *testing.B) {
+	benchmarkParallel(b, "RI", ParallelOptions{})
+}
+
+func BenchmarkRIRandomOrdered(b *testing.B) {
+	benchmarkParallel(b, "RI", ParallelOptions{Ordered: true})
+}
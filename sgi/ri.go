@@ -0,0 +1,263 @@
+package sgi
+
+import (
+	"fmt"
+)
+
+// riMatcher is the search shared by all states of an RI matching. RI orders
+// the query nodes by their structure alone and keeps the checks per pair
+// cheap, which pays off for large and sparse targets.
+type riMatcher struct {
+	query, target Graph
+
+	isSemanticallyFeasable SemFeasFunc
+
+	// order in which query nodes are matched, with the first neighbour
+	// matched before each node or NullNode
+	order  []int
+	parent []int
+
+	queryNeighbours  [][]int
+	targetNeighbours *neighbourhood
+	targetOrder      int
+
+	// q ~> t and t ~> q
+	mapping map[int]int
+	reverse map[int]int
+
+	// homomorphic matchings map query nodes to target nodes freely
+	homomorphic bool
+}
+
+type riState struct {
+	*riMatcher
+
+	depth      int
+	candidates []int
+}
+
+func (state *riState) GetGraph() Graph    { return state.target }
+func (state *riState) GetSubgraph() Graph { return state.query }
+
+func (state *riState) uniqueMappings() bool { return true }
+
+func (state *riState) NextPair() (int, int) {
+	if len(state.candidates) == 0 {
+		return NullNode, NullNode
+	}
+
+	candidate := state.candidates[len(state.candidates)-1]
+	state.candidates = state.candidates[:len(state.candidates)-1]
+
+	return state.order[state.depth], candidate
+}
+
+// BackTrack undoes the pair which led to this state.
+func (state *riState) BackTrack() {
+	if state.depth == 0 {
+		return
+	}
+	q := state.order[state.depth-1]
+	if !state.homomorphic {
+		delete(state.reverse, state.mapping[q])
+	}
+	delete(state.mapping, q)
+}
+
+func (state *riState) IsFeasablePair(queryNode, targetNode int) bool {
+	if !state.homomorphic {
+		if _, ok := state.reverse[targetNode]; ok {
+			return false
+		}
+		if len(state.queryNeighbours[queryNode]) > len(state.targetNeighbours.of(targetNode)) {
+			return false
+		}
+	}
+	if state.query.Contains(queryNode, queryNode) && !state.target.Contains(targetNode, targetNode) {
+		return false
+	}
+
+	for _, q := range state.queryNeighbours[queryNode] {
+		t, ok := state.mapping[q]
+		if !ok {
+			continue
+		}
+		if state.query.Contains(q, queryNode) && !state.target.Contains(t, targetNode) {
+			return false
+		}
+		if state.query.Contains(queryNode, q) && !state.target.Contains(targetNode, t) {
+			return false
+		}
+	}
+
+	if !state.isSemanticallyFeasable(state, NullNode, NullNode, queryNode, targetNode) {
+		return false
+	}
+	for _, q := range state.queryNeighbours[queryNode] {
+		if t, ok := state.mapping[q]; ok && !state.isSemanticallyFeasable(state, q, t, queryNode, targetNode) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (state *riState) IsGoal() bool { return state.depth == len(state.order) }
+
+func (state *riState) IsDead() bool {
+	return !state.homomorphic && len(state.order) > state.targetOrder || len(state.order) > 0 && state.targetOrder == 0
+}
+
+func (state *riState) GetMapping() map[int]int { return state.mapping }
+
+func (state *riState) NextState(queryNode, targetNode int) State {
+	state.mapping[queryNode] = targetNode
+	if !state.homomorphic {
+		state.reverse[targetNode] = queryNode
+	}
+
+	next := &riState{riMatcher: state.riMatcher, depth: state.depth + 1}
+	next.loadCandidates()
+
+	return next
+}
+
+func (state *riState) String() string {
+	str := ""
+
+	for _, q := range state.order[:state.depth] {
+		str += fmt.Sprintf("->(%d~>%d)", q, state.mapping[q])
+	}
+
+	return str
+}
+
+// loadCandidates collects the target nodes for the query node at the depth
+// of the state, the neighbours of the parent's match or all target nodes.
+func (state *riState) loadCandidates() {
+	state.candidates = nil
+	if state.depth == len(state.order) {
+		return
+	}
+
+	queryNode := state.order[state.depth]
+	if p := state.parent[queryNode]; p != NullNode {
+		ns := state.targetNeighbours.of(state.mapping[p])
+		for i := len(ns) - 1; i >= 0; i-- {
+			if _, ok := state.reverse[ns[i]]; !ok {
+				state.candidates = append(state.candidates, ns[i])
+			}
+		}
+		return
+	}
+
+	for t := state.targetOrder - 1; t >= 0; t-- {
+		if _, ok := state.reverse[t]; !ok {
+			state.candidates = append(state.candidates, t)
+		}
+	}
+}
+
+// orderQuery orders the query nodes by greatest constraint first: the next
+// node has the most neighbours already ordered, then the most neighbours next
+// to ordered nodes, then the most other neighbours.
+func (m *riMatcher) orderQuery() {
+	n := len(m.queryNeighbours)
+	ordered := make([]bool, n)
+	// number of ordered neighbours
+	visited := make([]int, n)
+
+	for len(m.order) < n {
+		best, bestScore := NullNode, [3]int{}
+		for u := 0; u < n; u++ {
+			if ordered[u] {
+				continue
+			}
+
+			score := [3]int{}
+			for _, v := range m.queryNeighbours[u] {
+				switch {
+				case ordered[v]:
+					score[0] += 1
+				case visited[v] > 0:
+					score[1] += 1
+				default:
+					score[2] += 1
+				}
+			}
+
+			if best == NullNode || score[0] > bestScore[0] ||
+				score[0] == bestScore[0] && (score[1] > bestScore[1] ||
+					score[1] == bestScore[1] && score[2] > bestScore[2]) {
+				best, bestScore = u, score
+			}
+		}
+
+		m.parent[best] = NullNode
+		for _, q := range m.order {
+			if m.parent[best] == NullNode && contains(m.queryNeighbours[best], q) {
+				m.parent[best] = q
+			}
+		}
+
+		ordered[best] = true
+		m.order = append(m.order, best)
+		for _, v := range m.queryNeighbours[best] {
+			visited[v] += 1
+		}
+	}
+}
+
+func contains(nodes []int, n int) bool {
+	for _, m := range nodes {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRIState creates the initial state of the RI algorithm for use with
+// FindIsomorphism.
+func NewRIState(query, target Graph, fsem SemFeasFunc) State {
+	return newRIState(query, target, fsem, false)
+}
+
+// NewHomomorphismState creates the initial state of a search for
+// homomorphisms, mappings preserving relations which may map several query
+// nodes to the same target node. The search follows the RI algorithm.
+func NewHomomorphismState(query, target Graph, fsem SemFeasFunc) State {
+	return newRIState(query, target, fsem, true)
+}
+
+func newRIState(query, target Graph, fsem SemFeasFunc, homomorphic bool) State {
+	n := query.Order()
+
+	m := &riMatcher{
+		query:            query,
+		target:           target,
+		parent:           make([]int, n),
+		queryNeighbours:  make([][]int, n),
+		targetNeighbours: newNeighbourhood(target),
+		targetOrder:      target.Order(),
+		mapping:          make(map[int]int, n),
+		reverse:          make(map[int]int, n),
+		homomorphic:      homomorphic,
+	}
+
+	if fsem != nil {
+		m.isSemanticallyFeasable = fsem
+	} else {
+		m.isSemanticallyFeasable = func(s State, a, b, c, d int) bool { return true }
+	}
+
+	for q := 0; q < n; q++ {
+		m.queryNeighbours[q] = distinct(query.Relations(q))
+	}
+	m.orderQuery()
+
+	state := &riState{riMatcher: m}
+	state.loadCandidates()
+
+	return state
+}
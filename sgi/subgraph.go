@@ -0,0 +1,135 @@
+package sgi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/BuJo/goneo/db"
+)
+
+// Subgraph is the part of a database spanned by some of its nodes and the
+// relations between them. Nodes and edges are identified by their position,
+// see Node and Relation.
+type Subgraph struct {
+	nodes     []Node
+	relations []Relation
+
+	successors, predecessors [][]int
+	edges                    map[[2]int][]int
+}
+
+// NewSubgraph extracts the subgraph of the given database nodes.
+func NewSubgraph(nodes []Node) *Subgraph {
+	g := &Subgraph{
+		nodes:        nodes,
+		relations:    make([]Relation, 0),
+		successors:   make([][]int, len(nodes)),
+		predecessors: make([][]int, len(nodes)),
+		edges:        make(map[[2]int][]int),
+	}
+
+	index := make(map[int]int, len(nodes))
+	for i, node := range nodes {
+		index[node.Id()] = i
+	}
+
+	for a, node := range nodes {
+		for _, rel := range node.Relations(Outgoing) {
+			b, ok := index[rel.End().Id()]
+			if !ok {
+				continue
+			}
+
+			g.successors[a] = append(g.successors[a], b)
+			g.predecessors[b] = append(g.predecessors[b], a)
+			g.edges[[2]int{a, b}] = append(g.edges[[2]int{a, b}], len(g.relations))
+			g.relations = append(g.relations, rel)
+		}
+	}
+
+	return g
+}
+
+func (g *Subgraph) Order() int               { return len(g.nodes) }
+func (g *Subgraph) Contains(a, b int) bool   { return len(g.edges[[2]int{a, b}]) > 0 }
+func (g *Subgraph) Successors(a int) []int   { return g.successors[a] }
+func (g *Subgraph) Predecessors(a int) []int { return g.predecessors[a] }
+func (g *Subgraph) Relations(a int) []int {
+	return append(append([]int{}, g.successors[a]...), g.predecessors[a]...)
+}
+func (g *Subgraph) Edges(a, b int) []int { return g.edges[[2]int{a, b}] }
+
+// Node of the database at the position.
+func (g *Subgraph) Node(node int) Node { return g.nodes[node] }
+
+// Relation of the database at the position.
+func (g *Subgraph) Relation(edge int) Relation { return g.relations[edge] }
+
+// Colors tell the nodes apart by their labels and the edges by the types of
+// the relations.
+func (g *Subgraph) Colors() Colors {
+	return Colors{
+		Node: func(node int) string {
+			labels := append([]string{}, g.nodes[node].Labels()...)
+			sort.Strings(labels)
+			return strings.Join(labels, ":")
+		},
+		Edge: func(edge int) string { return g.relations[edge].Type() },
+	}
+}
+
+// EditCosts compare the nodes of two subgraphs by their labels and
+// properties and the relations by their types and properties. Substitutions
+// cost the share of those which differ, so replacing a node or relation is
+// never more expensive than deleting and inserting it.
+func (g *Subgraph) EditCosts(other *Subgraph) EditCosts {
+	return EditCosts{
+		NodeSubstitution: func(a, b int) float64 {
+			return featureDistance(nodeFeatures(g.nodes[a]), nodeFeatures(other.nodes[b]))
+		},
+		EdgeSubstitution: func(a, b int) float64 {
+			return featureDistance(relationFeatures(g.relations[a]), relationFeatures(other.relations[b]))
+		},
+	}
+}
+
+// nodeFeatures names labels like ":Label" and properties like ".name".
+func nodeFeatures(n Node) map[string]string {
+	features := make(map[string]string)
+	for _, label := range n.Labels() {
+		features[":"+label] = ""
+	}
+	for k, v := range n.Properties() {
+		features["."+k] = v
+	}
+	return features
+}
+
+func relationFeatures(r Relation) map[string]string {
+	features := map[string]string{":" + r.Type(): ""}
+	for k, v := range r.Properties() {
+		features["."+k] = fmt.Sprint(v)
+	}
+	return features
+}
+
+// featureDistance is the share of features missing in either or differing.
+func featureDistance(a, b map[string]string) float64 {
+	union, differing := len(a), 0
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			differing += 1
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			union += 1
+			differing += 1
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(differing) / float64(union)
+}
@@ -0,0 +1,230 @@
+package sgi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ullmannState matches the query nodes in the order of their ids. Every state
+// keeps a candidate matrix: the target nodes each query node can still be
+// matched to, refined after each match.
+type ullmannState struct {
+	query, target Graph
+
+	isSemanticallyFeasable SemFeasFunc
+
+	queryNeighbours  [][]int
+	targetNeighbours *neighbourhood
+
+	// rows of the candidate matrix
+	matrix []map[int]bool
+
+	// q ~> t
+	mapping map[int]int
+	used    map[int]bool
+
+	// targets left for the query node with the id of depth
+	depth      int
+	candidates []int
+}
+
+func (state *ullmannState) GetGraph() Graph    { return state.target }
+func (state *ullmannState) GetSubgraph() Graph { return state.query }
+
+func (state *ullmannState) uniqueMappings() bool { return true }
+
+func (state *ullmannState) NextPair() (int, int) {
+	if len(state.candidates) == 0 {
+		return NullNode, NullNode
+	}
+
+	candidate := state.candidates[len(state.candidates)-1]
+	state.candidates = state.candidates[:len(state.candidates)-1]
+
+	return state.depth, candidate
+}
+
+// BackTrack has nothing to undo, every state holds its own matrix.
+func (state *ullmannState) BackTrack() {}
+
+func (state *ullmannState) IsFeasablePair(queryNode, targetNode int) bool {
+	if state.used[targetNode] || !state.matrix[queryNode][targetNode] {
+		return false
+	}
+
+	for _, q := range state.queryNeighbours[queryNode] {
+		t, ok := state.mapping[q]
+		if !ok {
+			continue
+		}
+		if state.query.Contains(q, queryNode) && !state.target.Contains(t, targetNode) {
+			return false
+		}
+		if state.query.Contains(queryNode, q) && !state.target.Contains(targetNode, t) {
+			return false
+		}
+		if !state.isSemanticallyFeasable(state, q, t, queryNode, targetNode) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (state *ullmannState) IsGoal() bool { return state.depth == len(state.matrix) }
+
+func (state *ullmannState) IsDead() bool {
+	for _, row := range state.matrix {
+		if len(row) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (state *ullmannState) GetMapping() map[int]int { return state.mapping }
+
+func (state *ullmannState) NextState(queryNode, targetNode int) State {
+	next := &ullmannState{
+		query:                  state.query,
+		target:                 state.target,
+		isSemanticallyFeasable: state.isSemanticallyFeasable,
+		queryNeighbours:        state.queryNeighbours,
+		targetNeighbours:       state.targetNeighbours,
+		matrix:                 make([]map[int]bool, len(state.matrix)),
+		mapping:                make(map[int]int, len(state.matrix)),
+		used:                   make(map[int]bool, len(state.matrix)),
+		depth:                  state.depth + 1,
+	}
+
+	for q, t := range state.mapping {
+		next.mapping[q] = t
+		next.used[t] = true
+	}
+	next.mapping[queryNode] = targetNode
+	next.used[targetNode] = true
+
+	for q, row := range state.matrix {
+		next.matrix[q] = make(map[int]bool, len(row))
+		for t := range row {
+			if q == queryNode && t == targetNode || q != queryNode && t != targetNode {
+				next.matrix[q][t] = true
+			}
+		}
+	}
+
+	next.refine()
+	next.loadCandidates()
+
+	return next
+}
+
+func (state *ullmannState) String() string {
+	str := ""
+
+	for q := 0; q < state.depth; q++ {
+		str += fmt.Sprintf("->(%d~>%d)", q, state.mapping[q])
+	}
+
+	return str
+}
+
+// refine drops candidates until every query neighbour of a query node has a
+// candidate among the target neighbours of each of its candidates.
+func (state *ullmannState) refine() {
+	for changed := true; changed; {
+		changed = false
+
+		for q, row := range state.matrix {
+			for t := range row {
+				if !state.supported(q, t) {
+					delete(row, t)
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func (state *ullmannState) supported(queryNode, targetNode int) bool {
+	for _, q := range state.queryNeighbours[queryNode] {
+		found := false
+		for _, t := range state.targetNeighbours.of(targetNode) {
+			if !state.matrix[q][t] {
+				continue
+			}
+			if state.query.Contains(queryNode, q) && !state.target.Contains(targetNode, t) {
+				continue
+			}
+			if state.query.Contains(q, queryNode) && !state.target.Contains(t, targetNode) {
+				continue
+			}
+			found = true
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (state *ullmannState) loadCandidates() {
+	state.candidates = nil
+	if state.depth == len(state.matrix) {
+		return
+	}
+
+	for t := range state.matrix[state.depth] {
+		state.candidates = append(state.candidates, t)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(state.candidates)))
+}
+
+// NewUllmannState creates the initial state of Ullmann's algorithm for use
+// with FindIsomorphism.
+func NewUllmannState(query, target Graph, fsem SemFeasFunc) State {
+	n := query.Order()
+
+	state := &ullmannState{
+		query:            query,
+		target:           target,
+		queryNeighbours:  make([][]int, n),
+		targetNeighbours: newNeighbourhood(target),
+		matrix:           make([]map[int]bool, n),
+		mapping:          make(map[int]int, n),
+		used:             make(map[int]bool, n),
+	}
+
+	if fsem != nil {
+		state.isSemanticallyFeasable = fsem
+	} else {
+		state.isSemanticallyFeasable = func(s State, a, b, c, d int) bool { return true }
+	}
+
+	for q := 0; q < n; q++ {
+		state.queryNeighbours[q] = distinct(query.Relations(q))
+		state.matrix[q] = make(map[int]bool)
+	}
+
+	// Candidates have at least as many neighbours and pass Fsem
+	for t, order := 0, target.Order(); t < order; t++ {
+		degree := len(state.targetNeighbours.of(t))
+		for q := 0; q < n; q++ {
+			if len(state.queryNeighbours[q]) > degree {
+				continue
+			}
+			if query.Contains(q, q) && !target.Contains(t, t) {
+				continue
+			}
+			if state.isSemanticallyFeasable(state, NullNode, NullNode, q, t) {
+				state.matrix[q][t] = true
+			}
+		}
+	}
+
+	state.refine()
+	state.loadCandidates()
+
+	return state
+}
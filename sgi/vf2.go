@@ -225,7 +225,12 @@ func (state *vf2State) loadCandidates(queryNode, targetNode int) {
 	log.Print("loaded new candidates: ", state.candidates, " from ", targetNeighbours, queryNeighbours)
 }
 
-func newVF2State(query, target Graph, fsem SemFeasFunc) State {
+// BUG(Jo): VF2 follows relations regardless of their direction and never
+// reaches query nodes disconnected from the first one matched.
+
+// NewVF2State creates the initial state of the VF2 algorithm for use with
+// FindIsomorphism.
+func NewVF2State(query, target Graph, fsem SemFeasFunc) State {
 	state := new(vf2State)
 
 	state.mapping = make(map[int]int, query.Order())
@@ -254,7 +259,16 @@ func newVF2State(query, target Graph, fsem SemFeasFunc) State {
 // between the query and target graphs. Use the SemFeasFunc to further
 // limit the algorithm to the nodes which really should be related.
 func FindVF2SubgraphIsomorphism(query, target Graph, fsem SemFeasFunc) []map[int]int {
-	state := newVF2State(query, target, fsem)
+	state := NewVF2State(query, target, fsem)
 
 	return FindIsomorphism(state)
 }
+
+// FindVF2SubgraphIsomorphismFunc works like FindVF2SubgraphIsomorphism but
+// hands each mapping to visit as soon as it is found instead of collecting
+// them. Returning false from visit stops the search.
+func FindVF2SubgraphIsomorphismFunc(query, target Graph, fsem SemFeasFunc, visit func(mapping map[int]int) bool) {
+	state := NewVF2State(query, target, fsem)
+
+	FindIsomorphismFunc(state, visit)
+}
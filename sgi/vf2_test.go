@@ -32,6 +32,12 @@ func (g *dbGraphMock) Predecessors(a int) []int {
 func (g *dbGraphMock) Relations(a int) []int {
 	return append(g.Successors(a), g.Predecessors(a)...)
 }
+func (g *dbGraphMock) Edges(a, b int) []int {
+	if g.edges[a][b] {
+		return []int{a*len(g.nodes) + b}
+	}
+	return nil
+}
 
 func isSemanticallyFeasable(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
 	return true
@@ -149,3 +155,28 @@ func TestSemanticallyFailingIsomorphism(t *testing.T) {
 		t.Errorf("Should not find isomorphism")
 	}
 }
+
+func TestStoppingIsomorphism(t *testing.T) {
+	subgraph := &dbGraphMock{
+		nodes: []int{100, 102},
+		edges: [][]bool{
+			{false, true},
+			{true, false},
+		},
+	}
+
+	all := FindVF2SubgraphIsomorphism(subgraph, testgraph, nil)
+	if len(all) < 2 {
+		t.Fatal("Should find more than one mapping, found ", len(all))
+	}
+
+	visited := 0
+	FindVF2SubgraphIsomorphismFunc(subgraph, testgraph, nil, func(mapping map[int]int) bool {
+		visited += 1
+		return false
+	})
+
+	if visited != 1 {
+		t.Error("Search should stop after the first mapping, visited ", visited)
+	}
+}
@@ -0,0 +1,386 @@
+package sgi
+
+import (
+	"fmt"
+)
+
+// vf2ppMatcher is the search shared by all states of a VF2++ matching. The
+// query nodes are matched in a fixed order, so a state only needs its depth
+// and the target candidates left for the query node at that depth.
+type vf2ppMatcher struct {
+	query, target Graph
+
+	isSemanticallyFeasable SemFeasFunc
+
+	// order in which query nodes are matched
+	order []int
+	// parent is a query neighbour matched before the node, NullNode for
+	// nodes starting a new component
+	parent []int
+
+	// domains of the query nodes, the target nodes passing Fsem on their own
+	domains [][]int
+	// inDomain marks the target nodes of the domains
+	inDomain []map[int]bool
+
+	// distinct neighbours of query and target nodes
+	queryNeighbours  [][]int
+	targetNeighbours *neighbourhood
+
+	// q ~> t and t ~> q
+	mapping  map[int]int
+	reverse  map[int]int
+	queryMap []int
+
+	// number of mapped neighbours, non-zero for nodes in the terminal sets
+	queryTerminal  []int
+	targetTerminal map[int]int
+}
+
+type vf2ppState struct {
+	*vf2ppMatcher
+
+	depth      int
+	candidates []int
+}
+
+func (state *vf2ppState) GetGraph() Graph    { return state.target }
+func (state *vf2ppState) GetSubgraph() Graph { return state.query }
+
+func (state *vf2ppState) uniqueMappings() bool { return true }
+
+func (state *vf2ppState) NextPair() (int, int) {
+	if len(state.candidates) == 0 {
+		return NullNode, NullNode
+	}
+
+	candidate := state.candidates[len(state.candidates)-1]
+	state.candidates = state.candidates[:len(state.candidates)-1]
+
+	return state.order[state.depth], candidate
+}
+
+// BackTrack undoes the pair which led to this state.
+func (state *vf2ppState) BackTrack() {
+	if state.depth == 0 {
+		return
+	}
+	state.unmap(state.order[state.depth-1])
+}
+
+func (state *vf2ppState) IsFeasablePair(queryNode, targetNode int) bool {
+	if _, ok := state.reverse[targetNode]; ok {
+		return false
+	}
+	if !state.inDomain[queryNode][targetNode] {
+		return false
+	}
+
+	// Edges to matched query nodes have to exist in the target
+	if state.query.Contains(queryNode, queryNode) && !state.target.Contains(targetNode, targetNode) {
+		return false
+	}
+	for _, q := range state.queryNeighbours[queryNode] {
+		t := state.queryMap[q]
+		if t == NullNode {
+			continue
+		}
+		if state.query.Contains(q, queryNode) && !state.target.Contains(t, targetNode) {
+			return false
+		}
+		if state.query.Contains(queryNode, q) && !state.target.Contains(targetNode, t) {
+			return false
+		}
+	}
+
+	// Terminal sets: unmatched neighbours of the query node need distinct
+	// unmatched neighbours in the target, those next to the matched part
+	// need ones next to the matched part as well.
+	queryFree, queryTerminal := 0, 0
+	for _, q := range state.queryNeighbours[queryNode] {
+		if state.queryMap[q] == NullNode {
+			queryFree += 1
+			if state.queryTerminal[q] > 0 {
+				queryTerminal += 1
+			}
+		}
+	}
+	targetFree, targetTerminal := 0, 0
+	for _, t := range state.targetNeighbours.of(targetNode) {
+		if _, ok := state.reverse[t]; !ok {
+			targetFree += 1
+			if state.targetTerminal[t] > 0 {
+				targetTerminal += 1
+			}
+		}
+	}
+	if queryFree > targetFree || queryTerminal > targetTerminal {
+		return false
+	}
+
+	for _, q := range state.queryNeighbours[queryNode] {
+		if t := state.queryMap[q]; t != NullNode && !state.isSemanticallyFeasable(state, q, t, queryNode, targetNode) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (state *vf2ppState) IsGoal() bool { return state.depth == len(state.queryMap) }
+
+func (state *vf2ppState) IsDead() bool {
+	if len(state.order) < len(state.queryMap) {
+		return true
+	}
+	for _, domain := range state.domains {
+		if len(domain) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (state *vf2ppState) GetMapping() map[int]int { return state.mapping }
+
+func (state *vf2ppState) NextState(queryNode, targetNode int) State {
+	state.assign(queryNode, targetNode)
+
+	next := &vf2ppState{vf2ppMatcher: state.vf2ppMatcher, depth: state.depth + 1}
+	if next.depth < len(state.order) {
+		next.loadCandidates()
+	}
+
+	return next
+}
+
+func (state *vf2ppState) String() string {
+	str := ""
+
+	for _, q := range state.order[:state.depth] {
+		str += fmt.Sprintf("->(%d~>%d)", q, state.queryMap[q])
+	}
+
+	return str
+}
+
+// loadCandidates collects the target nodes for the query node at the depth
+// of the state. Nodes with a matched parent can only be matched to
+// neighbours of the parent's match.
+func (state *vf2ppState) loadCandidates() {
+	queryNode := state.order[state.depth]
+
+	var candidates []int
+	if p := state.parent[queryNode]; p != NullNode {
+		candidates = state.targetNeighbours.of(state.queryMap[p])
+	} else {
+		candidates = state.domains[queryNode]
+	}
+
+	state.candidates = make([]int, 0, len(candidates))
+	for i := len(candidates) - 1; i >= 0; i-- {
+		t := candidates[i]
+		if _, ok := state.reverse[t]; !ok && state.inDomain[queryNode][t] {
+			state.candidates = append(state.candidates, t)
+		}
+	}
+}
+
+func (m *vf2ppMatcher) assign(queryNode, targetNode int) {
+	m.mapping[queryNode] = targetNode
+	m.reverse[targetNode] = queryNode
+	m.queryMap[queryNode] = targetNode
+
+	for _, q := range m.queryNeighbours[queryNode] {
+		m.queryTerminal[q] += 1
+	}
+	for _, t := range m.targetNeighbours.of(targetNode) {
+		m.targetTerminal[t] += 1
+	}
+}
+
+func (m *vf2ppMatcher) unmap(queryNode int) {
+	targetNode := m.queryMap[queryNode]
+
+	delete(m.mapping, queryNode)
+	delete(m.reverse, targetNode)
+	m.queryMap[queryNode] = NullNode
+
+	for _, q := range m.queryNeighbours[queryNode] {
+		m.queryTerminal[q] -= 1
+	}
+	for _, t := range m.targetNeighbours.of(targetNode) {
+		if m.targetTerminal[t] -= 1; m.targetTerminal[t] == 0 {
+			delete(m.targetTerminal, t)
+		}
+	}
+}
+
+// loadDomains finds the target nodes every query node could be matched to on
+// its own: nodes with at least as many neighbours which pass Fsem.
+func (state *vf2ppState) loadDomains() {
+	for q := range state.domains {
+		state.inDomain[q] = make(map[int]bool)
+	}
+
+	for t, order := 0, state.target.Order(); t < order; t++ {
+		degree := len(state.targetNeighbours.of(t))
+		for q := range state.domains {
+			if len(state.queryNeighbours[q]) > degree {
+				continue
+			}
+			if state.isSemanticallyFeasable(state, NullNode, NullNode, q, t) {
+				state.domains[q] = append(state.domains[q], t)
+				state.inDomain[q][t] = true
+			}
+		}
+	}
+}
+
+// orderQuery orders the query nodes the VF2++ way: every component is
+// traversed breadth first from its rarest node, the node with the fewest
+// candidates in the target. Within a level nodes with the most matched
+// neighbours come first, then nodes of high degree, then rare nodes.
+func (m *vf2ppMatcher) orderQuery() {
+	n := m.query.Order()
+	ordered := make([]bool, n)
+	connections := make([]int, n)
+
+	rarer := func(a, b int) bool {
+		if len(m.domains[a]) != len(m.domains[b]) {
+			return len(m.domains[a]) < len(m.domains[b])
+		}
+		if len(m.queryNeighbours[a]) != len(m.queryNeighbours[b]) {
+			return len(m.queryNeighbours[a]) > len(m.queryNeighbours[b])
+		}
+		return a < b
+	}
+
+	for len(m.order) < n {
+		root := NullNode
+		for q := 0; q < n; q++ {
+			if !ordered[q] && (root == NullNode || rarer(q, root)) {
+				root = q
+			}
+		}
+
+		ordered[root] = true
+		m.parent[root] = NullNode
+		level := []int{root}
+		for len(level) > 0 {
+			// order the level, nodes are placed one by one as connections
+			// to placed nodes change
+			for placed := 0; placed < len(level); placed++ {
+				best := placed
+				for i := placed + 1; i < len(level); i++ {
+					a, b := level[i], level[best]
+					switch {
+					case connections[a] != connections[b]:
+						if connections[a] > connections[b] {
+							best = i
+						}
+					case len(m.queryNeighbours[a]) != len(m.queryNeighbours[b]):
+						if len(m.queryNeighbours[a]) > len(m.queryNeighbours[b]) {
+							best = i
+						}
+					case rarer(a, b):
+						best = i
+					}
+				}
+				level[placed], level[best] = level[best], level[placed]
+
+				m.order = append(m.order, level[placed])
+				for _, q := range m.queryNeighbours[level[placed]] {
+					connections[q] += 1
+				}
+			}
+
+			next := make([]int, 0)
+			for _, p := range level {
+				for _, q := range m.queryNeighbours[p] {
+					if !ordered[q] {
+						ordered[q] = true
+						m.parent[q] = p
+						next = append(next, q)
+					}
+				}
+			}
+			level = next
+		}
+	}
+}
+
+func newVF2PPState(query, target Graph, fsem SemFeasFunc) *vf2ppState {
+	n := query.Order()
+
+	m := &vf2ppMatcher{
+		query:            query,
+		target:           target,
+		parent:           make([]int, n),
+		domains:          make([][]int, n),
+		inDomain:         make([]map[int]bool, n),
+		queryNeighbours:  make([][]int, n),
+		targetNeighbours: newNeighbourhood(target),
+		mapping:          make(map[int]int, n),
+		reverse:          make(map[int]int, n),
+		queryMap:         make([]int, n),
+		queryTerminal:    make([]int, n),
+		targetTerminal:   make(map[int]int),
+	}
+
+	if fsem != nil {
+		m.isSemanticallyFeasable = fsem
+	} else {
+		m.isSemanticallyFeasable = func(s State, a, b, c, d int) bool { return true }
+	}
+
+	for q := 0; q < n; q++ {
+		m.queryMap[q] = NullNode
+		m.queryNeighbours[q] = distinct(query.Relations(q))
+	}
+
+	state := &vf2ppState{vf2ppMatcher: m}
+	if n > target.Order() {
+		return state
+	}
+
+	state.loadDomains()
+	m.orderQuery()
+	if n > 0 {
+		state.loadCandidates()
+	}
+
+	return state
+}
+
+// NewVF2PPState creates the initial state of the VF2++ algorithm for use with
+// FindIsomorphism.
+func NewVF2PPState(query, target Graph, fsem SemFeasFunc) State {
+	return newVF2PPState(query, target, fsem)
+}
+
+// FindVF2PPSubgraphIsomorphism finds a graph within another using the VF2++
+// algorithm. Unlike FindVF2SubgraphIsomorphism it respects the direction of
+// relations and finds all monomorphisms, matching the query nodes in an
+// order which prunes the search early: rare and well connected nodes first,
+// each next to the nodes matched before.
+func FindVF2PPSubgraphIsomorphism(query, target Graph, fsem SemFeasFunc) []map[int]int {
+	mappings := make([]map[int]int, 0)
+
+	FindVF2PPSubgraphIsomorphismFunc(query, target, fsem, func(mapping map[int]int) bool {
+		mappings = append(mappings, mapping)
+		return true
+	})
+
+	return mappings
+}
+
+// FindVF2PPSubgraphIsomorphismFunc works like FindVF2PPSubgraphIsomorphism
+// but hands each mapping to visit as soon as it is found. Returning false
+// from visit stops the search.
+func FindVF2PPSubgraphIsomorphismFunc(query, target Graph, fsem SemFeasFunc, visit func(mapping map[int]int) bool) {
+	state := newVF2PPState(query, target, fsem)
+
+	// Every mapping is reached exactly once, there are no duplicates to skip
+	match(state, visit)
+}
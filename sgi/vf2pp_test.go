@@ -0,0 +1,75 @@
+package sgi
+
+import (
+	"testing"
+)
+
+func TestVF2PPSemanticIsomorphism(t *testing.T) {
+	subgraph := &dbGraphMock{
+		nodes: []int{100, 102},
+		edges: [][]bool{
+			{false, true},
+			{true, false},
+		},
+	}
+
+	mappings := FindVF2PPSubgraphIsomorphism(subgraph, testgraph, func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		return subgraph.nodes[toQueryNode] == testgraph.nodes[toTargetNode]
+	})
+
+	if len(mappings) != 1 || mappings[0][0] != 0 || mappings[0][1] != 1 {
+		t.Error("Should find the node values, found ", mappings)
+	}
+
+	edges := 0
+	FindVF2PPSubgraphIsomorphism(subgraph, testgraph, func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		if fromQueryNode != NullNode {
+			edges += 1
+			if !subgraph.Contains(fromQueryNode, toQueryNode) || !testgraph.Contains(fromTargetNode, toTargetNode) {
+				t.Errorf("Expected Fsem for related nodes only, got (%d,%d)~>(%d,%d)", fromQueryNode, fromTargetNode, toQueryNode, toTargetNode)
+			}
+		}
+		return true
+	})
+	if edges == 0 {
+		t.Error("Expected Fsem for the related nodes")
+	}
+}
+
+func TestVF2PPOrder(t *testing.T) {
+	// a triangle with a rare node attached
+	query := newMock(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{1, 3}, [2]int{2, 3})
+
+	target := newMock(5)
+	for i := range target.edges {
+		for j := range target.edges[i] {
+			target.edges[i][j] = i != j
+		}
+	}
+
+	state := newVF2PPState(query, target, func(state State, fromQueryNode, fromTargetNode, toQueryNode, toTargetNode int) bool {
+		return toQueryNode != 0 || toTargetNode == 0
+	})
+	if state.order[0] != 0 || state.order[1] != 1 {
+		t.Error("Expected the rare node first, followed by its neighbour, got ", state.order)
+	}
+	for _, q := range state.order[1:] {
+		if state.parent[q] == NullNode {
+			t.Error("Expected all nodes next to matched nodes, got ", state.parent)
+		}
+	}
+}
+
+func TestVF2PPStopping(t *testing.T) {
+	subgraph := newMock(2, [2]int{0, 1})
+
+	visited := 0
+	FindVF2PPSubgraphIsomorphismFunc(subgraph, testgraph, nil, func(mapping map[int]int) bool {
+		visited += 1
+		return false
+	})
+
+	if visited != 1 {
+		t.Error("Search should stop after the first mapping, visited ", visited)
+	}
+}
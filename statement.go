@@ -0,0 +1,120 @@
+package goneo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	. "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/gcy"
+)
+
+// Statement is a parsed and validated query which can be evaluated many
+// times with different parameters. The query is planned on the first
+// evaluation and the plan is reused as long as the same database is used
+// and, for databases keeping Statistics, its nodes and relations did not
+// change in number by more than a factor of replanFactor.
+// A Statement is safe for concurrent use.
+//
+// Example:
+//
+//	stmt, err := Prepare("match (n:Tag {tag: $tag})<-[:IS_TAGGED]-(e) return e")
+//	if err != nil {
+//		return err
+//	}
+//	table, err := stmt.Evaluate(db, map[string]interface{}{"tag": "Drama"})
+type Statement struct {
+	query *gcy.Query
+
+	mu   sync.Mutex
+	db   DatabaseService
+	plan operator
+
+	// counts of the database when planning
+	nodes, relations int
+}
+
+// replanFactor is the factor by which the number of nodes or relations of a
+// database has to change for a statement to be planned anew.
+const replanFactor = 2
+
+// Prepare parses and validates a gcy query for later evaluation.
+func Prepare(qry string) (*Statement, error) {
+	q, err := gcy.Parse("goneo", qry)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuery(q); err != nil {
+		return nil, err
+	}
+
+	return &Statement{query: q}, nil
+}
+
+// Parameters returns the names of all parameters the statement needs.
+func (s *Statement) Parameters() []string {
+	return s.query.Parameters
+}
+
+// Evaluate the statement with the given parameters.
+func (s *Statement) Evaluate(db DatabaseService, params map[string]interface{}) (*TabularData, error) {
+	rows, err := s.EvaluateContext(context.Background(), db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.Table()
+}
+
+// EvaluateContext evaluates the statement lazily with the given parameters,
+// see EvaluateContext for details.
+func (s *Statement) EvaluateContext(ctx context.Context, db DatabaseService, params map[string]interface{}) (*Rows, error) {
+	for _, name := range s.query.Parameters {
+		if _, ok := params[name]; !ok {
+			return nil, &SemanticError{fmt.Sprintf("parameter `%s` missing", name)}
+		}
+	}
+
+	limits := limitsFrom(ctx)
+	ctx, cancel := limits.withTimeout(ctx)
+
+	eval := &evalContext{ctx: ctx, cancel: cancel, db: db, limits: limits, profile: s.query.Profile, params: params}
+	plan := s.planFor(db)
+
+	rows := run(eval, plan)
+	if s.query.Explain {
+		rows = func(yield func(row) bool) {}
+	}
+
+	return newRows(eval, plan, s.query.Explain || s.query.Profile, rows), nil
+}
+
+// planFor returns the plan for a database, planning only if necessary.
+func (s *Statement) planFor(db DatabaseService) operator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes, relations := counts(db)
+	if s.plan == nil || s.db != db || changed(s.nodes, nodes) || changed(s.relations, relations) {
+		s.db, s.plan = db, planQuery(s.query, db)
+		s.nodes, s.relations = nodes, relations
+	}
+
+	return s.plan
+}
+
+// counts of the nodes and relations of a database, zero if it does not keep
+// Statistics.
+func counts(db DatabaseService) (nodes, relations int) {
+	if stats, ok := db.(Statistics); ok {
+		return stats.CountNodes(), stats.CountRelations("")
+	}
+	return 0, 0
+}
+
+// changed checks whether a count changed by more than replanFactor since
+// planning.
+func changed(planned, current int) bool {
+	a, b := float64(max(planned, 1)), float64(max(current, 1))
+	return a*replanFactor < b || b*replanFactor < a
+}
@@ -0,0 +1,178 @@
+package goneo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestPrepareParameters(t *testing.T) {
+	db := setupTestDb(t)
+
+	stmt, err := Prepare("match (n:Tag {tag: $tag})<-[:IS_TAGGED]-(v) return count(v) as c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params := stmt.Parameters(); len(params) != 1 || params[0] != "tag" {
+		t.Error("Statement should need the tag parameter, got ", params)
+	}
+
+	for _, tag := range []string{"Drama", "Sci-Fi", "Unknown"} {
+		table, err := stmt.Evaluate(db, map[string]interface{}{"tag": tag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := Evaluate(db, "match (n:Tag {tag: \""+tag+"\"})<-[:IS_TAGGED]-(v) return count(v) as c")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if table.Get(0, "c") != expected.Get(0, "c") {
+			t.Errorf("%s: expected %v matches, got %v", tag, expected.Get(0, "c"), table.Get(0, "c"))
+		}
+	}
+}
+
+func TestPrepareIdParameter(t *testing.T) {
+	db := setupTestDb(t)
+
+	stmt, err := Prepare("start n=node($ids) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := stmt.Evaluate(db, map[string]interface{}{"ids": []interface{}{0.0, 1.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 2 {
+		t.Error("Should find both nodes, found ", table.Len())
+	}
+
+	table, err = stmt.Evaluate(db, map[string]interface{}{"ids": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 1 {
+		t.Error("Should find a single node, found ", table.Len())
+	}
+
+	_, err = stmt.Evaluate(db, map[string]interface{}{"ids": "zero"})
+	var semanticErr *SemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Error("Should reject ids which are no numbers, got ", err)
+	}
+}
+
+func TestPrepareMissingParameter(t *testing.T) {
+	db := setupTestDb(t)
+
+	stmt, err := Prepare("match (n:Tag {tag: $tag}) return n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = stmt.Evaluate(db, nil)
+	var semanticErr *SemanticError
+	if !errors.As(err, &semanticErr) {
+		t.Error("Should report the missing parameter, got ", err)
+	}
+}
+
+func TestPrepareSubgraphMatchParameter(t *testing.T) {
+	db := setupTestDb(t)
+
+	stmt, err := Prepare("match (e {episode: $nr})-[:LEADS_TO*]->(next) return count(next) as c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := stmt.Evaluate(db, map[string]interface{}{"nr": 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := Evaluate(db, "match (e {episode: \"12\"})-[:LEADS_TO*]->(next) return count(next) as c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Get(0, "c") == 0 || table.Get(0, "c") != expected.Get(0, "c") {
+		t.Errorf("Expected %v matches, got %v", expected.Get(0, "c"), table.Get(0, "c"))
+	}
+}
+
+func TestPrepareConcurrent(t *testing.T) {
+	db := setupTestDb(t)
+
+	stmt, err := Prepare("profile match (n:Tag {tag: $tag})<-[:IS_TAGGED]-(v) return v")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := stmt.Evaluate(db, map[string]interface{}{"tag": "Drama"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			table, err := stmt.Evaluate(db, map[string]interface{}{"tag": "Drama"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if table.Len() != expected.Len() {
+				t.Errorf("Expected %d rows, got %d", expected.Len(), table.Len())
+			}
+			if rows := table.Plan().Rows; rows != expected.Plan().Rows {
+				t.Errorf("Profile should only count its own execution, expected %d rows, got %d", expected.Plan().Rows, rows)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPrepareReplans(t *testing.T) {
+	db, _ := OpenDb("mem:replan")
+	a := db.NewNode("A")
+	for i := 0; i < 10; i++ {
+		a.RelateTo(db.NewNode("B"), "HAS")
+	}
+
+	stmt, err := Prepare("explain match (a:A)-->(b:B) return a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor := func() string {
+		table, err := stmt.Evaluate(db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan := table.Plan()
+		for plan.Operator != "NodeByLabelScan" && len(plan.Children) > 0 {
+			plan = plan.Children[0]
+		}
+		return plan.Details
+	}
+
+	if details := anchor(); details != "a:A" {
+		t.Fatal("Should start at the rare label, got ", details)
+	}
+	plan := stmt.plan
+
+	db.NewNode("A")
+	anchor()
+	if stmt.plan != plan {
+		t.Error("Should reuse the plan while the database barely changes")
+	}
+
+	for i := 0; i < 100; i++ {
+		db.NewNode("A").RelateTo(db.NewNode(), "HAS")
+	}
+	if details := anchor(); details != "b:B" {
+		t.Error("Should plan anew once the labels changed in number, got ", details)
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 // TabularData describes information in a tabular format.
 type TabularData struct {
-	line []map[string]interface{}
+	columns []string
+	line    []map[string]interface{}
+
+	plan *Plan
 }
 
 func (t *TabularData) String() string {
@@ -24,11 +27,7 @@ func (t *TabularData) String() string {
 	// Format in tab-separated columns with a tab stop of 8.
 	w.Init(b, 0, 8, 0, '\t', 0)
 
-	headers := make([]string, 0)
-
-	for header := range t.line[0] {
-		headers = append(headers, header)
-	}
+	headers := t.Columns()
 
 	for _, header := range headers {
 		fmt.Fprint(w, header+"\t")
@@ -48,6 +47,11 @@ func (t *TabularData) String() string {
 	return b.String()
 }
 
+// Plan returns the execution plan for explained or profiled queries.
+func (t *TabularData) Plan() *Plan {
+	return t.plan
+}
+
 // Len returns the count of lines
 func (t *TabularData) Len() int {
 	return len(t.line)
@@ -55,6 +59,10 @@ func (t *TabularData) Len() int {
 
 // Columns returns column names
 func (t *TabularData) Columns() []string {
+	if t.columns != nil {
+		return t.columns
+	}
+
 	cols := make([]string, 0)
 	for k := range t.line[0] {
 		cols = append(cols, k)
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/BuJo/goneo"
+)
+
+// defaultStatementCacheSize is the number of prepared statements kept by a
+// server.
+const defaultStatementCacheSize = 128
+
+// statementCache keeps the most recently used prepared statements by query
+// text.
+type statementCache struct {
+	mu   sync.Mutex
+	size int
+
+	lru   *list.List // of *cachedStatement, most recently used first
+	items map[string]*list.Element
+}
+
+type cachedStatement struct {
+	query string
+	stmt  *goneo.Statement
+}
+
+func newStatementCache(size int) *statementCache {
+	return &statementCache{size: size, lru: list.New(), items: make(map[string]*list.Element)}
+}
+
+// prepare returns the cached statement for a query, preparing it if
+// necessary. Queries failing to prepare are not cached.
+func (c *statementCache) prepare(query string) (*goneo.Statement, error) {
+	c.mu.Lock()
+	if e, ok := c.items[query]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cachedStatement).stmt, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := goneo.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[query]; ok {
+		// prepared concurrently, keep the first one
+		c.lru.MoveToFront(e)
+		return e.Value.(*cachedStatement).stmt, nil
+	}
+
+	c.items[query] = c.lru.PushFront(&cachedStatement{query, stmt})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedStatement).query)
+	}
+
+	return stmt, nil
+}
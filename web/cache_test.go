@@ -0,0 +1,43 @@
+package web
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BuJo/goneo/gcy"
+)
+
+func TestStatementCache(t *testing.T) {
+	c := newStatementCache(2)
+
+	first, err := c.prepare("match (a) return a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := c.prepare("match (a) return a"); again != first {
+		t.Error("Should reuse the prepared statement")
+	}
+
+	c.prepare("match (b) return b")
+	// using the first query again keeps it over the second one
+	c.prepare("match (a) return a")
+	c.prepare("match (c) return c")
+
+	if c.lru.Len() != 2 {
+		t.Fatal("Should keep at most 2 statements, got ", c.lru.Len())
+	}
+	if _, ok := c.items["match (b) return b"]; ok {
+		t.Error("Should evict the least recently used statement")
+	}
+	if again, _ := c.prepare("match (a) return a"); again != first {
+		t.Error("Should keep the recently used statement")
+	}
+
+	var syntaxErr *gcy.SyntaxError
+	if _, err := c.prepare("match (a return a"); !errors.As(err, &syntaxErr) {
+		t.Error("Should report syntax errors, got ", err)
+	}
+	if _, ok := c.items["match (a return a"]; ok {
+		t.Error("Should not cache failing queries")
+	}
+}
@@ -2,22 +2,30 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/BuJo/goneo"
 	goneodb "github.com/BuJo/goneo/db"
+	"github.com/BuJo/goneo/gcy"
 )
 
 type (
 	webHandler struct {
 		routes []route
 
-		db goneodb.DatabaseService
+		db         goneodb.DatabaseService
+		statements *statementCache
+		limits     goneo.Limits
 	}
 
+	// ServerOption configures a server created by NewGoneoServer.
+	ServerOption func(*webHandler)
+
 	// NodeResponse is a representation of a node
 	NodeResponse struct {
 		Self                  string
@@ -44,6 +52,11 @@ type (
 		Exception  string
 		FullName   string `json:"Fullname"`
 		Stacktrace []string
+
+		// Position of a syntax error in the query
+		Line   int    `json:",omitempty"`
+		Column int    `json:",omitempty"`
+		Token  string `json:",omitempty"`
 	}
 )
 
@@ -139,10 +152,10 @@ func (h *webHandler) graphvizHandler(w http.ResponseWriter, req *http.Request) {
 
 	db := h.db
 	if gocy != "" {
-		// Execute query
-		table, err := goneo.Evaluate(db, gocy)
+		// Execute query, aborting when the client goes away
+		table, err := h.evaluate(req, db, gocy)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, err)
 			return
 		}
 
@@ -195,13 +208,16 @@ func (h *webHandler) gocyTableHandler(w http.ResponseWriter, req *http.Request)
 
 	gocy := req.FormValue("gocy")
 	if gocy != "" {
-		table, err := goneo.Evaluate(h.db, gocy)
+		table, err := h.evaluate(req, h.db, gocy)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, err)
 			return
 		}
 
 		w.Write([]byte(table.String()))
+		if plan := table.Plan(); plan != nil {
+			w.Write([]byte("\n" + plan.String()))
+		}
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -209,9 +225,43 @@ func (h *webHandler) gocyTableHandler(w http.ResponseWriter, req *http.Request)
 	w.WriteHeader(http.StatusNotFound)
 }
 
-func NewGoneoServer(db goneodb.DatabaseService) http.Handler {
+// evaluate a query bound to the lifetime of the request. Parameters are
+// given as JSON object in the params form value.
+func (h *webHandler) evaluate(req *http.Request, db goneodb.DatabaseService, gocy string) (*goneo.TabularData, error) {
+	stmt, err := h.statements.prepare(gocy)
+	if err != nil {
+		return nil, err
+	}
+
+	var params map[string]interface{}
+	if p := req.FormValue("params"); p != "" {
+		if err := json.Unmarshal([]byte(p), &params); err != nil {
+			return nil, &goneo.SemanticError{Msg: "invalid params: " + err.Error()}
+		}
+	}
+
+	ctx := goneo.WithLimits(req.Context(), h.limits)
+
+	rows, err := stmt.EvaluateContext(ctx, db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.Table()
+}
+
+// WithLimits restricts the resources every query of the server may use.
+func WithLimits(limits goneo.Limits) ServerOption {
+	return func(h *webHandler) { h.limits = limits }
+}
+
+func NewGoneoServer(db goneodb.DatabaseService, options ...ServerOption) http.Handler {
 	s := &webHandler{
-		db: db,
+		db:         db,
+		statements: newStatementCache(defaultStatementCacheSize),
+	}
+	for _, option := range options {
+		option(s)
 	}
 
 	s.routes = []route{
@@ -227,3 +277,46 @@ func NewGoneoServer(db goneodb.DatabaseService) http.Handler {
 
 	return s
 }
+
+// writeError reports a failed query as ErrorResponse with a fitting status.
+func writeError(w http.ResponseWriter, err error) {
+	res := ErrorResponse{Message: err.Error(), Exception: "QueryExecutionError", FullName: fmt.Sprintf("%T", err)}
+	status := http.StatusInternalServerError
+
+	var (
+		syntaxErr   *gcy.SyntaxError
+		semanticErr *goneo.SemanticError
+		notFoundErr *goneo.EntityNotFoundError
+		limitErr    *goneo.LimitExceededError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		// the client went away, nobody is listening anymore
+		return
+	case errors.As(err, &limitErr):
+		res.Exception, status = "LimitExceededError", http.StatusUnprocessableEntity
+		if limitErr.Limit == "Timeout" {
+			status = http.StatusServiceUnavailable
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		res.Exception, status = "QueryTimeoutError", http.StatusServiceUnavailable
+	case errors.As(err, &syntaxErr):
+		res.Exception, status = "SyntaxError", http.StatusBadRequest
+		res.FullName = fmt.Sprintf("%T", syntaxErr)
+		res.Line, res.Column, res.Token = syntaxErr.Line, syntaxErr.Column, syntaxErr.Token
+	case errors.As(err, &semanticErr):
+		res.Exception, status = "SemanticError", http.StatusBadRequest
+	case errors.As(err, &notFoundErr):
+		res.Exception, status = "EntityNotFoundError", http.StatusNotFound
+	}
+
+	if list, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range list.Unwrap() {
+			res.Stacktrace = append(res.Stacktrace, e.Error())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
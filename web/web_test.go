@@ -0,0 +1,69 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/BuJo/goneo"
+	"github.com/BuJo/goneo/data"
+)
+
+func query(t *testing.T, server http.Handler, gocy string) (*httptest.ResponseRecorder, ErrorResponse) {
+	t.Helper()
+
+	form := url.Values{"gocy": {gocy}}
+	req := httptest.NewRequest("POST", "/table", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	var res ErrorResponse
+	if w.Code != http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatal("Errors should be reported as JSON: ", err)
+		}
+	}
+	return w, res
+}
+
+func TestErrorResponses(t *testing.T) {
+	db, _ := goneo.OpenDb("mem:web")
+	data.NewUniverseGenerator(db).Generate()
+	server := NewGoneoServer(db, WithLimits(goneo.Limits{MaxRows: 3}))
+
+	tests := []struct {
+		gocy      string
+		status    int
+		exception string
+	}{
+		{"match (n:Tag) return n.tag + n.tag", http.StatusBadRequest, "SyntaxError"},
+		{"match (n {tag: 5}) return n", http.StatusBadRequest, "SyntaxError"},
+		{"match (n {tag: }) return n", http.StatusBadRequest, "SyntaxError"},
+		{"match (n:Tag) return m", http.StatusBadRequest, "SemanticError"},
+		{"start n=node(123456) return n", http.StatusNotFound, "EntityNotFoundError"},
+		{"start n=node(*) return n", http.StatusUnprocessableEntity, "LimitExceededError"},
+	}
+
+	for _, test := range tests {
+		w, res := query(t, server, test.gocy)
+		if w.Code != test.status || res.Exception != test.exception {
+			t.Errorf("%s: expected %d %s, got %d %+v", test.gocy, test.status, test.exception, w.Code, res)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: expected a JSON response, got %s", test.gocy, ct)
+		}
+	}
+
+	if _, res := query(t, server, "match (n:Tag) return n.tag + n.tag"); res.Line != 1 || res.Column == 0 || res.Token == "" {
+		t.Error("Syntax errors should report their position, got ", res)
+	}
+
+	if w, _ := query(t, server, "start n=node(0, 1) return n"); w.Code != http.StatusOK {
+		t.Error("Queries within the limits should succeed, got ", w.Code, w.Body)
+	}
+}